@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"go-web3/internal/config"
+	"go-web3/internal/infra/eth/registry"
+	"go-web3/internal/infra/redis"
+	"log"
+)
+
+// 校验合约清单，加 -push 时写入 Redis，供 CONTRACTS_SOURCE=redis 的实例加载
+func main() {
+	file := flag.String("file", "configs/contracts.json", "合约清单文件")
+	push := flag.Bool("push", false, "校验通过后写入 Redis")
+	flag.Parse()
+
+	path := config.FindFile(*file)
+	if path == "" {
+		log.Fatalf("合约清单文件不存在: %s", *file)
+	}
+
+	ctx := context.Background()
+	m, err := registry.NewFileStore(path).Load(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := m.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("合约清单校验通过: revision=%s, contracts=%d, templates=%d", m.Revision, len(m.Contracts), len(m.Templates))
+
+	if !*push {
+		return
+	}
+
+	cfg := config.MustLoad()
+	rdb, err := redis.NewClient(ctx, cfg.RedisConfig())
+	if err != nil {
+		log.Fatalf("Redis 连接失败: %v", err)
+	}
+	defer rdb.Close()
+
+	key := cfg.ContractsConfig().RedisKey
+	if err := registry.NewRedisStore(rdb, key).Save(ctx, m); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("合约清单已写入 Redis: %s", key)
+}
@@ -1,41 +1,30 @@
 package main
 
 import (
+	"context"
+	"go-web3/internal/app"
 	"go-web3/internal/config"
-	"go-web3/internal/infra/eth"
-	"go-web3/internal/infra/redis"
-	"go-web3/internal/router"
-	ethevent "go-web3/internal/router/event"
 	"log"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
-	var err error
-	cfg := config.Get()
+	cfg := config.MustLoad()
 
-	// redis 初始化
-	redis.InitRedis()
+	// SIGINT/SIGTERM 触发优雅退出
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	// 初始化 ETH client
-	eth.InitEthClient()
-
-	eth.InitNonce(redis.Rdb)
-
-	// ETH 事件处理器
-	eventRouter := ethevent.SetupRouter()
-	// 异步执行，不要阻塞main导致gin无法启动
-	go eventRouter.Listen()
-	// --- 启动 Scanner
-	//scanner := ethevent.SetupScanner()
-	//go scanner.Start()
-
-	// 设置路由
-	r := router.SetupRouter()
-
-	log.Printf("Server listening on :%s", cfg.AppPort())
-
-	err = r.Run(":" + cfg.AppPort())
+	a, err := app.New(ctx, cfg)
 	if err != nil {
-		panic(err)
+		log.Fatalf("初始化失败: %v", err)
+	}
+
+	if err := a.Run(ctx); err != nil {
+		log.Printf("server error: %v", err)
 	}
+
+	a.Close()
+	log.Println("Server exited")
 }
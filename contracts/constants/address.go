@@ -1,5 +0,0 @@
-package constants
-
-const (
-	ADDRESS_NFT_AUCTION = "0xD1B8Dc552131A1e999af04Ef43d0453281f5f202"
-)
@@ -0,0 +1,6 @@
+package constants
+
+// 合约在清单（configs/contracts.json 或 Redis）中的名称，地址与 ABI 从清单加载
+const (
+	CONTRACT_NFT_AUCTION = "NftAuctionV1"
+)
@@ -0,0 +1,350 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"go-web3/internal/config"
+	"go-web3/internal/handlers"
+	"go-web3/internal/infra/eth"
+	"go-web3/internal/infra/eth/event"
+	"go-web3/internal/infra/eth/registry"
+	"go-web3/internal/infra/eth/signer"
+	ethtrans "go-web3/internal/infra/eth/trans"
+	"go-web3/internal/infra/eth/txtrack"
+	infraredis "go-web3/internal/infra/redis"
+	"go-web3/internal/router"
+	ethevent "go-web3/internal/router/event"
+	"go-web3/internal/services"
+	"go-web3/internal/services/deadletter"
+	"go-web3/internal/services/eventadmin"
+	"go-web3/internal/services/trans"
+	"go-web3/internal/utils"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// 收到退出信号后等待 HTTP 请求与事件处理完成的最长时间
+const shutdownTimeout = 30 * time.Second
+
+// App 应用容器：由配置创建，持有 Redis、链客户端与 nonce 管理器、合约清单、事件管道、服务与处理器。
+// 不依赖包级全局变量，测试可以并行创建互相隔离的实例
+type App struct {
+	Config    config.Config
+	Redis     *redis.Client
+	Senders   *ethtrans.SenderPool     // 发送交易的账户池
+	Gas       ethtrans.GasStrategy     // gas 费计算策略
+	GasLimits *ethtrans.GasLimitPolicy // gas limit 余量规则
+	Chains    *eth.Chains
+	Contracts *registry.Registry // 合约清单
+	Events    *event.Registry    // 事件 ABI 与路由表
+
+	Tracker           *txtrack.Tracker         // 发出交易的生命周期跟踪
+	Replacer          *ethtrans.Replacer       // 卡住交易的加速与取消
+	BalanceMonitor    *ethtrans.BalanceMonitor // 发送账户余额告警与补充，未配置 ETH_BALANCE_ALERT 时为 nil
+	TransService      *trans.Service
+	AuctionService    *services.AuctionService
+	DeadLetterService *deadletter.Service
+	EventAdminService *eventadmin.Service
+
+	Pipelines []*event.Pipeline
+	Handlers  *handlers.Handlers
+	Engine    *gin.Engine
+
+	closers []func()
+}
+
+// Option 替换 App 创建的依赖，如测试注入模拟链或内存清单
+type Option func(*options)
+
+type options struct {
+	redis          *redis.Client
+	signers        []signer.Signer
+	gas            ethtrans.GasStrategy
+	chains         *eth.Chains
+	contractsStore registry.Store
+}
+
+// WithRedis 使用已创建的 Redis 客户端，App 关闭时不会关闭它
+func WithRedis(rdb *redis.Client) Option {
+	return func(o *options) { o.redis = rdb }
+}
+
+// WithSigners 使用指定的发送账户签名器，替代配置中的私钥、keystore 或远程签名服务
+func WithSigners(signers ...signer.Signer) Option {
+	return func(o *options) { o.signers = signers }
+}
+
+// WithGasStrategy 替换默认的 eth_feeHistory gas 费策略
+func WithGasStrategy(gas ethtrans.GasStrategy) Option {
+	return func(o *options) { o.gas = gas }
+}
+
+// WithChains 使用已创建的链，App 关闭时不会关闭它们
+func WithChains(chains *eth.Chains) Option {
+	return func(o *options) { o.chains = chains }
+}
+
+// WithContractsStore 指定合约清单来源，替代配置中的文件或 Redis
+func WithContractsStore(store registry.Store) Option {
+	return func(o *options) { o.contractsStore = store }
+}
+
+// New 按依赖顺序创建 Redis、链、合约清单、事件管道、服务、处理器与 Gin 路由。失败时释放已创建的资源
+func New(ctx context.Context, cfg config.Config, opts ...Option) (*App, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	a := &App{Config: cfg}
+	ok := false
+	defer func() {
+		if !ok {
+			a.Close()
+		}
+	}()
+
+	// redis 初始化
+	a.Redis = o.redis
+	if a.Redis == nil {
+		rdb, err := infraredis.NewClient(ctx, cfg.RedisConfig())
+		if err != nil {
+			return nil, errors.New("Redis 连接失败: " + err.Error())
+		}
+		a.Redis = rdb
+		a.closers = append(a.closers, func() { _ = rdb.Close() })
+	}
+
+	// 发送账户池
+	signers := o.signers
+	if len(signers) == 0 {
+		list, err := signer.New(ctx, cfg.EthConfig())
+		if err != nil {
+			return nil, errors.New("创建签名器失败: " + err.Error())
+		}
+		for _, s := range list {
+			if remote, ok := s.(*signer.RemoteSigner); ok {
+				a.closers = append(a.closers, remote.Close)
+			}
+		}
+		signers = list
+	}
+	senders, err := ethtrans.NewSenderPool(a.Redis, signers)
+	if err != nil {
+		return nil, errors.New("创建发送账户池失败: " + err.Error())
+	}
+	a.Senders = senders
+	if minBalance := cfg.EthConfig().MinBalance; minBalance != "" {
+		a.Senders.MinBalance = ethToWei(minBalance)
+	}
+
+	// 余额补充的资金账户，不参与发送账户选择
+	treasury, err := signer.NewTreasury(ctx, cfg.EthConfig())
+	if err != nil {
+		return nil, errors.New("创建资金账户签名器失败: " + err.Error())
+	}
+	addresses := a.Senders.Addresses()
+	if treasury != nil {
+		if remote, ok := treasury.(*signer.RemoteSigner); ok {
+			a.closers = append(a.closers, remote.Close)
+		}
+		if _, ok := a.Senders.Signer(treasury.Address()); ok {
+			return nil, errors.New("资金账户不能同时是发送账户: " + treasury.Address().Hex())
+		}
+		addresses = append(addresses, treasury.Address())
+	}
+
+	a.Gas = o.gas
+	if a.Gas == nil {
+		a.Gas = ethtrans.DefaultGasStrategy
+	}
+	gasLimits, err := ethtrans.NewGasLimitPolicy(cfg.EthConfig().GasLimit)
+	if err != nil {
+		return nil, errors.New("ETH_GAS_LIMIT_METHODS 配置错误: " + err.Error())
+	}
+	a.GasLimits = gasLimits
+
+	// 接入的链：ETH client + nonce 管理器
+	a.Chains = o.chains
+	if a.Chains == nil {
+		chains, err := eth.DialChains(ctx, cfg.EthConfig(), a.Redis, addresses...)
+		if err != nil {
+			return nil, err
+		}
+		a.Chains = chains
+		a.closers = append(a.closers, chains.Close)
+	}
+
+	// 合约清单（地址、ABI、部署区块）
+	store := o.contractsStore
+	if store == nil {
+		s, err := registry.NewStore(cfg.ContractsConfig(), a.Redis)
+		if err != nil {
+			return nil, err
+		}
+		store = s
+	}
+	var names []string
+	for _, c := range a.Chains.All() {
+		names = append(names, c.Name)
+	}
+	a.Contracts = registry.New()
+	if err := a.Contracts.Load(ctx, store, names...); err != nil {
+		return nil, errors.New("加载合约清单失败: " + err.Error())
+	}
+
+	// 服务
+	a.Events = event.NewRegistry()
+	ethevent.RegisterContracts(a.Contracts, a.Events, a.Chains)
+	a.Tracker = txtrack.NewTracker(txtrack.NewRedisStore(a.Redis), a.Chains, txtrack.NewWebhookNotifier(),
+		log.New(os.Stdout, "[tx-tracker] ", log.LstdFlags))
+	a.Replacer = ethtrans.NewReplacer(a.Tracker, a.Chains, a.Senders, log.New(os.Stdout, "[tx-replacer] ", log.LstdFlags))
+	a.Replacer.Gas = a.Gas
+	if topUp := cfg.EthConfig().TopUp; topUp.AlertBalance != "" {
+		a.BalanceMonitor = ethtrans.NewBalanceMonitor(a.Chains, a.Senders, a.Tracker, a.Redis, ethToWei(topUp.AlertBalance),
+			log.New(os.Stdout, "[balance-monitor] ", log.LstdFlags))
+		a.BalanceMonitor.AlertURL = topUp.AlertURL
+		a.BalanceMonitor.Interval = topUp.Interval
+		a.BalanceMonitor.Gas = a.Gas
+		a.BalanceMonitor.GasLimits = a.GasLimits
+		if treasury != nil {
+			a.BalanceMonitor.Treasury = treasury
+			a.BalanceMonitor.Target = ethToWei(topUp.Target)
+			a.BalanceMonitor.DailyCap = ethToWei(topUp.DailyCap)
+		}
+	}
+	a.TransService = trans.NewService(a.Senders, a.Tracker, a.Replacer, a.Gas, a.GasLimits)
+	a.AuctionService = services.NewAuctionService(a.Contracts, a.Senders, a.Tracker, a.Gas, a.GasLimits)
+	deadLetters := event.NewRedisDeadLetterStore(a.Redis)
+	a.DeadLetterService = deadletter.NewService(deadLetters, a.Events)
+	a.EventAdminService = eventadmin.NewService(a.Events)
+
+	// ETH 事件管道：每条链实时订阅 + 区块补扫
+	a.Pipelines = ethevent.SetupPipelines(ethevent.Deps{
+		Redis:             a.Redis,
+		Contracts:         a.Contracts,
+		Events:            a.Events,
+		DeadLetters:       deadLetters,
+		DeadLetterService: a.DeadLetterService,
+		EventAdminService: a.EventAdminService,
+	}, a.Chains)
+
+	// 处理器与路由
+	a.Handlers = &handlers.Handlers{
+		TransService:      a.TransService,
+		AuctionService:    a.AuctionService,
+		DeadLetterService: a.DeadLetterService,
+		EventAdminService: a.EventAdminService,
+	}
+	a.Engine = router.SetupRouter(router.Deps{
+		Config:   cfg,
+		Redis:    a.Redis,
+		Senders:  a.Senders,
+		Chains:   a.Chains,
+		Handlers: a.Handlers,
+	})
+
+	ok = true
+	return a, nil
+}
+
+// StartBackground 异步启动全部事件管道、交易跟踪、卡住交易的自动加速与余额监控，返回的 channel 在全部停止后关闭
+func (a *App) StartBackground(ctx context.Context) <-chan struct{} {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		a.Tracker.Start(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		a.Replacer.Start(ctx)
+	}()
+	if a.BalanceMonitor != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.BalanceMonitor.Start(ctx)
+		}()
+	}
+	for _, pipeline := range a.Pipelines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pipeline.Start(ctx)
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return done
+}
+
+// Run 启动事件管道与 HTTP 服务，阻塞直到 ctx 取消，然后优雅退出
+func (a *App) Run(ctx context.Context) error {
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	// 异步执行，不要阻塞导致gin无法启动
+	backgroundDone := a.StartBackground(ctx)
+
+	srv := &http.Server{
+		Addr:    ":" + a.Config.AppPort(),
+		Handler: a.Engine,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("Server listening on :%s", a.Config.AppPort())
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+	}()
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-serveErr:
+		stop()
+	}
+	log.Println("Shutting down...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// 停止接收新请求，等待在途请求（如正在发送的交易）完成
+	if shutdownErr := srv.Shutdown(shutdownCtx); shutdownErr != nil {
+		log.Printf("HTTP server shutdown error: %v", shutdownErr)
+	}
+
+	// 等待事件订阅停止、在途处理器排空、checkpoint 落盘，以及在途的交易状态回调
+	select {
+	case <-backgroundDone:
+	case <-shutdownCtx.Done():
+		log.Println("background shutdown timeout")
+	}
+	return err
+}
+
+// Close 释放 App 创建的 Redis 与链客户端，通过 Option 注入的依赖由调用方关闭
+func (a *App) Close() {
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		a.closers[i]()
+	}
+	a.closers = nil
+}
+
+// ethToWei 已校验的 ETH 数量转换为 wei
+func ethToWei(amount string) *big.Int {
+	wei, _ := new(big.Int).SetString(utils.ParseEthToWei(amount), 10)
+	return wei
+}
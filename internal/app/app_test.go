@@ -0,0 +1,79 @@
+package app_test
+
+import (
+	"context"
+	"encoding/json"
+	"go-web3/internal/infra/eth/event"
+	"go-web3/internal/infra/eth/txtrack"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// TestAppsAreIsolated 同一进程内的两个 App 各自持有 Redis、链、发送账户与事件注册表，互不可见
+func TestAppsAreIsolated(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	key1, sender1 := newKey(t)
+	key2, sender2 := newKey(t)
+	env1 := newSimEnv(t, sender1)
+	env2 := newSimEnv(t, sender2)
+	_, a1 := env1.newApp(ctx, t, key1)
+	_, a2 := env2.newApp(ctx, t, key2)
+
+	if a1.Redis == a2.Redis || a1.Chains.Default() == a2.Chains.Default() || a1.Events == a2.Events {
+		t.Fatal("apps share redis, chain or event registry")
+	}
+
+	// 各自的 HTTP 路由只返回自己的发送账户
+	for _, tc := range []struct {
+		engine http.Handler
+		want   common.Address
+	}{{a1.Engine, sender1}, {a2.Engine, sender2}} {
+		w := httptest.NewRecorder()
+		tc.engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/eth", nil))
+		var resp struct {
+			Data struct {
+				Addresses []common.Address `json:"addresses"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode /health/eth: %v", err)
+		}
+		if len(resp.Data.Addresses) != 1 || resp.Data.Addresses[0] != tc.want {
+			t.Fatalf("/health/eth addresses = %v, want [%s]", resp.Data.Addresses, tc.want.Hex())
+		}
+	}
+
+	// 运行时添加的路由只进入自己的事件注册表
+	routes, own := len(a2.Events.AllRoutes()), len(a1.Events.AllRoutes())
+	a1.Pipelines[0].Router.AnyContract("AuctionCreated").Use(func(*event.Context) error { return nil })
+	if got := len(a1.Events.AllRoutes()); got != own+1 {
+		t.Fatalf("route not added to app 1: %d routes, want %d", got, own+1)
+	}
+	if got := len(a2.Events.AllRoutes()); got != routes {
+		t.Fatalf("route added to app 1 is visible in app 2: %d → %d routes", routes, got)
+	}
+
+	// 一个 App 发出的交易只在它自己的链与交易跟踪中
+	result, err := a1.TransService.Trans(a1.Chains.Default(), sender2.Hex(), "0.01", txtrack.Request{})
+	if err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	if _, err := a1.TransService.GetTx(result.TxHash); err != nil {
+		t.Fatalf("app 1 lost its own tx: %v", err)
+	}
+	if _, err := a2.TransService.GetTx(result.TxHash); err == nil {
+		t.Fatal("tx sent by app 1 is tracked by app 2")
+	}
+	pending, err := a2.Chains.Default().NonceMgr.InFlight(ctx, sender1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("app 2 sees nonces reserved by app 1: %v", pending)
+	}
+}
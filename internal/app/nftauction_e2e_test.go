@@ -0,0 +1,296 @@
+package app_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"go-web3/contracts/constants"
+	"go-web3/contracts/nftauction"
+	"go-web3/internal/app"
+	"go-web3/internal/config"
+	"go-web3/internal/infra/eth"
+	"go-web3/internal/infra/eth/chainclient/chaintest"
+	"go-web3/internal/infra/eth/event"
+	"go-web3/internal/infra/eth/nonce"
+	"go-web3/internal/infra/eth/registry"
+	"go-web3/internal/infra/eth/signer"
+	"go-web3/internal/infra/eth/txtrack"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	simChainID   = 1337
+	simChainName = "sim"
+	manifestKey  = "contracts:manifest"
+)
+
+// stubNFT 最小的 ERC-721 桩合约：ownerOf 返回 tx.origin，getApproved 返回调用方（拍卖合约），
+// 其他调用（isApprovedForAll、safeTransferFrom 等）返回 true 且不做任何事
+var stubNFT = common.FromHex("0x60003560e01c80636352211e146024578063081812fc14602e57600160005260206000f35b3260005260206000f35b3360005260206000f3")
+
+var (
+	nftAddress = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	oneEther   = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+)
+
+// simEnv 模拟链 + 内存 Redis
+type simEnv struct {
+	sim *chaintest.Simulated
+	rdb *redis.Client
+}
+
+func newSimEnv(t *testing.T, accounts ...common.Address) *simEnv {
+	t.Helper()
+	alloc := types.GenesisAlloc{nftAddress: {Code: stubNFT}}
+	for _, addr := range accounts {
+		alloc[addr] = types.Account{Balance: new(big.Int).Mul(oneEther, big.NewInt(100))}
+	}
+	sim := chaintest.NewSimulated(alloc)
+	t.Cleanup(func() { _ = sim.Close() })
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	return &simEnv{sim: sim, rdb: rdb}
+}
+
+// chains 模拟链对应的 Chains，nonce 记录在测试自己的 Redis 中
+func (e *simEnv) chains(t *testing.T) *eth.Chains {
+	t.Helper()
+	chain := eth.NewChain(simChainID, simChainName, e.sim, nonce.NewNonceManager(e.rdb, e.sim, simChainID))
+	chains := eth.NewChains()
+	if err := chains.Register(chain); err != nil {
+		t.Fatal(err)
+	}
+	return chains
+}
+
+// mine 打包交易并要求执行成功
+func (e *simEnv) mine(t *testing.T, tx *types.Transaction) *types.Receipt {
+	t.Helper()
+	e.sim.Commit()
+	receipt, err := e.sim.TransactionReceipt(context.Background(), tx.Hash())
+	if err != nil {
+		t.Fatalf("receipt of %s: %v", tx.Hash().Hex(), err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("tx %s reverted", tx.Hash().Hex())
+	}
+	return receipt
+}
+
+func newKey(t *testing.T) (*ecdsa.PrivateKey, common.Address) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, crypto.PubkeyToAddress(key.PublicKey)
+}
+
+func transactOpts(t *testing.T, key *ecdsa.PrivateKey) *bind.TransactOpts {
+	t.Helper()
+	auth, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(simChainID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return auth
+}
+
+func testConfig() config.Config {
+	return config.New("0", config.EthConfig{GasLimit: config.GasLimitConfig{Multiplier: 1.2}},
+		config.RedisConfig{}, config.ContractsConfig{})
+}
+
+// saveManifest 把已部署的拍卖合约写入 Redis 合约清单
+func saveManifest(t *testing.T, rdb *redis.Client, address common.Address, deployBlock uint64) registry.Store {
+	t.Helper()
+	store := registry.NewRedisStore(rdb, manifestKey)
+	m := &registry.Manifest{
+		Version: registry.ManifestVersion,
+		Contracts: []registry.ContractMeta{{
+			Name:        constants.CONTRACT_NFT_AUCTION,
+			Chain:       simChainName,
+			Address:     address.Hex(),
+			ABI:         json.RawMessage(nftauction.NftauctionMetaData.ABI),
+			DeployBlock: deployBlock,
+		}},
+	}
+	if err := store.Save(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// newApp 由 owner 部署并初始化拍卖合约，写入合约清单后创建以 owner 为发送账户的 App
+func (e *simEnv) newApp(ctx context.Context, t *testing.T, owner *ecdsa.PrivateKey) (*nftauction.Nftauction, *app.App) {
+	t.Helper()
+	auth := transactOpts(t, owner)
+	address, deployTx, auction, err := nftauction.DeployNftauction(auth, e.sim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployed := e.mine(t, deployTx)
+	tx, err := auction.Initialize(auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.mine(t, tx)
+
+	store := saveManifest(t, e.rdb, address, deployed.BlockNumber.Uint64())
+	a, err := app.New(ctx, testConfig(),
+		app.WithRedis(e.rdb),
+		app.WithChains(e.chains(t)),
+		app.WithSigners(signer.NewKeySigner(owner)),
+		app.WithContractsStore(store),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(a.Close)
+	return auction, a
+}
+
+// auctionEvents 记录路由收到的 AuctionCreated 事件
+type auctionEvents struct {
+	mu   sync.Mutex
+	seen map[string]int // auctionId → 投递次数
+}
+
+func (a *auctionEvents) handle(ctx *event.Context) error {
+	if ctx.EventName != "AuctionCreated" || ctx.Log.Removed {
+		return nil
+	}
+	evt := &nftauction.NftauctionAuctionCreated{}
+	if err := ctx.BindEvent(evt); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	a.seen[evt.AuctionId.String()]++
+	a.mu.Unlock()
+	return nil
+}
+
+func (a *auctionEvents) snapshot() map[string]int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := map[string]int{}
+	for k, v := range a.seen {
+		out[k] = v
+	}
+	return out
+}
+
+// TestNftAuctionEndToEnd 在模拟链上部署拍卖合约，创建、出价后通过 AuctionService 取消与结算，
+// 并确认 AuctionCreated 经事件管道（实时订阅 + 补扫）恰好投递一次
+func TestNftAuctionEndToEnd(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sellerKey, seller := newKey(t)
+	bidderKey, bidder := newKey(t)
+	env := newSimEnv(t, seller, bidder)
+
+	// 卖家同时是合约 owner 与服务的发送账户
+	auction, a := env.newApp(ctx, t, sellerKey)
+	sellerAuth := transactOpts(t, sellerKey)
+
+	events := &auctionEvents{seen: map[string]int{}}
+	a.Pipelines[0].Router.Contract(constants.CONTRACT_NFT_AUCTION).Use(events.handle)
+	done := a.StartBackground(ctx)
+
+	// 创建两场拍卖：0 号出价后结算，1 号取消
+	for tokenId := int64(1); tokenId <= 2; tokenId++ {
+		tx, err := auction.CreateAuction(sellerAuth, nftAddress, big.NewInt(tokenId), big.NewInt(100), 60)
+		if err != nil {
+			t.Fatalf("create auction: %v", err)
+		}
+		env.mine(t, tx)
+	}
+	bidAuth := transactOpts(t, bidderKey)
+	bidAuth.Value = big.NewInt(200)
+	tx, err := auction.Bid(bidAuth, big.NewInt(0))
+	if err != nil {
+		t.Fatalf("bid: %v", err)
+	}
+	env.mine(t, tx)
+
+	chain := a.Chains.Default()
+	cancelled, err := a.AuctionService.CancelAuction(chain, big.NewInt(1), txtrack.Request{})
+	if err != nil {
+		t.Fatalf("cancel auction: %v", err)
+	}
+	env.sim.Commit()
+	assertSucceeded(t, env, cancelled.TxHash)
+
+	// 拍卖结束后才能结算
+	if err := env.sim.AdjustTime(2 * time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	env.sim.Commit()
+	settled, err := a.AuctionService.SettleAuction(chain, big.NewInt(0), txtrack.Request{})
+	if err != nil {
+		t.Fatalf("settle auction: %v", err)
+	}
+	if settled.GasLimit == 0 || settled.MaxCost == "" {
+		t.Fatalf("send result missing gas limit or max cost: %+v", settled)
+	}
+	env.sim.Commit()
+	assertSucceeded(t, env, settled.TxHash)
+
+	// 取消与结算不影响拍卖编号
+	next, err := auction.GetNextAuctionId(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Int64() != 2 {
+		t.Fatalf("next auction id = %s, want 2", next)
+	}
+
+	// 实时订阅与补扫都会看到 AuctionCreated，去重后每场拍卖只投递一次
+	deadline := time.Now().Add(15 * time.Second)
+	for {
+		got := events.snapshot()
+		if got["0"] >= 1 && got["1"] >= 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("AuctionCreated not delivered, got %v", got)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	// 等补扫至少再跑一轮，确认没有重复投递
+	time.Sleep(3 * time.Second)
+	if got := events.snapshot(); got["0"] != 1 || got["1"] != 1 || len(got) != 2 {
+		t.Fatalf("AuctionCreated deliveries = %v, want exactly once per auction", got)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("background tasks did not stop")
+	}
+}
+
+func assertSucceeded(t *testing.T, env *simEnv, hash string) {
+	t.Helper()
+	receipt, err := env.sim.TransactionReceipt(context.Background(), common.HexToHash(hash))
+	if err != nil {
+		t.Fatalf("receipt of %s: %v", hash, err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("tx %s reverted", hash)
+	}
+}
@@ -1,11 +1,14 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -18,21 +21,72 @@ type RedisConfig struct {
 }
 
 type EthConfig struct {
-	RpcUrl      string
-	NetworkName string
-	Private     string
+	RpcUrl       string
+	NetworkName  string
+	Private      string // 发送账户私钥，多个账户逗号分隔
+	Signer       SignerConfig
+	MinBalance   string // 发送账户余额低于该值（ETH）时不参与选择，为空不检查
+	TopUp        TopUpConfig
+	GasLimit     GasLimitConfig
+	Chains       []ChainConfig // 接入的链，未配置 ETH_CHAINS 时为 ETH_RPC_URL 对应的单条链
+	DefaultChain string        // 接口未指定链时使用，默认第一条链
 }
 
-type Config struct {
-	appPort     string
-	ethConfig   *EthConfig
-	redisConfig *RedisConfig
+// SignerConfig 交易签名方式
+type SignerConfig struct {
+	Type                 string // key（默认，使用 ETH_PRIVATE）| keystore | remote
+	KeystoreFile         string // geth keystore JSON 文件，多个账户逗号分隔
+	KeystorePassword     string // keystore 口令，为空时读取 KeystorePasswordFile
+	KeystorePasswordFile string
+	RemoteURL            string // 远程签名服务 JSON-RPC 地址
+	RemoteAddress        string // 远程签名服务中用于签名的账户地址，多个账户逗号分隔
 }
 
-var (
-	cfg  *Config
-	once sync.Once
-)
+// GasLimitConfig gas limit 在估算值基础上的余量：limit = max(估算值 × 倍数, 下限)
+type GasLimitConfig struct {
+	Multiplier float64 // 默认倍数
+	Floor      uint64  // 默认下限
+	Methods    string  // 按方法配置，分号分隔，如 cancelAuction(uint256)=1.5:100000;transfer=1:21000，transfer 为无 calldata 的转账
+}
+
+// TopUpConfig 发送账户余额监控：低于告警值时告警，配置资金账户时从资金账户补充。金额单位均为 ETH
+type TopUpConfig struct {
+	AlertBalance string        // 告警与补充的余额阈值，为空不监控
+	AlertURL     string        // 告警 webhook，可选
+	Treasury     string        // 资金账户：私钥、keystore 文件或远程签名账户地址（与 ETH_SIGNER 一致），为空只告警
+	Target       string        // 补充到的目标余额
+	DailyCap     string        // 每条链每天从资金账户补充的总额上限
+	Interval     time.Duration // 检查间隔
+}
+
+// ChainConfig 单条链的配置，环境变量前缀为 ETH_<链名大写>_，如 ETH_SEPOLIA_RPC_URL
+type ChainConfig struct {
+	Name          string
+	ChainID       uint64        // 为 0 时从节点获取，否则启动时校验
+	RpcUrls       []string      // HTTP RPC 地址（含协议），多个节点逗号分隔，故障时自动切换
+	WsUrls        []string      // WebSocket RPC 地址（含协议），用于事件订阅
+	RateLimit     float64       // 每个节点每秒请求数，0 表示不限流
+	MaxLag        uint64        // 节点落后最高区块超过该值时降级使用
+	Confirmations uint64        // 事件扫描确认区块数
+	ReorgDepth    uint64        // 重组最大回溯深度
+	StartBlock    uint64        // 合约未配置部署区块时的默认扫描起点
+	MaxFeeGwei    float64       // 交易与替换交易的 gas 费上限（gwei），0 表示不限
+	StuckAfter    time.Duration // 交易未打包超过该时间自动加速，0（默认）表示不自动加速，开启时必须配置 MaxFeeGwei
+}
+
+// ContractsConfig 合约元数据来源
+type ContractsConfig struct {
+	Source   string // file | redis
+	File     string // 清单文件路径，相对路径从当前目录向上查找
+	RedisKey string
+}
+
+type Config struct {
+	appPort         string
+	ethConfig       *EthConfig
+	redisConfig     *RedisConfig
+	contractsConfig *ContractsConfig
+}
 
 func (c Config) AppPort() string {
 	return c.appPort
@@ -46,43 +100,150 @@ func (c Config) EthConfig() EthConfig {
 	return *c.ethConfig
 }
 
-// Get 获取配置数据，返回值类型
-func Get() Config {
-	if cfg == nil {
-		return MustLoad()
+func (c Config) ContractsConfig() ContractsConfig {
+	return *c.contractsConfig
+}
+
+// New 由各部分配置创建，测试可据此构造独立的配置
+func New(appPort string, eth EthConfig, redis RedisConfig, contracts ContractsConfig) Config {
+	return Config{
+		appPort:         appPort,
+		ethConfig:       &eth,
+		redisConfig:     &redis,
+		contractsConfig: &contracts,
+	}
+}
+
+// Load 从 .env 文件与环境变量读取配置，每次调用都重新读取
+func Load() (Config, error) {
+	loadEnvFiles()
+	c := Config{
+		appPort:   getEnv("APP_PORT", "8080"),
+		ethConfig: loadEthConfig(),
+
+		redisConfig: &RedisConfig{
+			Addr:     getEnv("REDIS_ADDR", "127.0.0.1:6379"),
+			Password: getEnv("REDIS_PASSWORD", ""),
+			DB:       getEnv("REDIS_DB", 0),
+		},
+
+		contractsConfig: &ContractsConfig{
+			Source:   getEnv("CONTRACTS_SOURCE", "file"),
+			File:     getEnv("CONTRACTS_FILE", "configs/contracts.json"),
+			RedisKey: getEnv("CONTRACTS_REDIS_KEY", "contracts:manifest"),
+		},
 	}
-	return *cfg
+
+	if err := c.Validate(); err != nil {
+		return Config{}, err
+	}
+	return c, nil
 }
 
+// MustLoad 读取配置，校验失败直接退出
 func MustLoad() Config {
-	once.Do(func() {
-		loadEnvFiles()
-		cfg = &Config{
-			appPort: getEnv("APP_PORT", "8080"),
-			ethConfig: &EthConfig{
-				RpcUrl:      getEnv("ETH_RPC_URL", ""),
-				NetworkName: getEnv("ETH_NETWORK_NAME", ""),
-				Private:     getEnv("ETH_PRIVATE", ""),
-			},
-
-			redisConfig: &RedisConfig{
-				Addr:     getEnv("REDIS_ADDR", "127.0.0.1:6379"),
-				Password: getEnv("REDIS_PASSWORD", ""),
-				DB:       getEnv("REDIS_DB", 0),
-			},
-		}
-
-		validateConfig(cfg)
-	})
-	return *cfg
-}
-
-// findEnvFile 从当前目录向上查找 .env 文件
-func findEnvFile() string {
+	c, err := Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return c
+}
+
+func loadEthConfig() *EthConfig {
+	c := &EthConfig{
+		RpcUrl:      getEnv("ETH_RPC_URL", ""),
+		NetworkName: getEnv("ETH_NETWORK_NAME", ""),
+		Private:     getEnv("ETH_PRIVATE", ""),
+		MinBalance:  getEnv("ETH_SENDER_MIN_BALANCE", ""),
+		GasLimit: GasLimitConfig{
+			Multiplier: getEnv("ETH_GAS_LIMIT_MULTIPLIER", 1.2),
+			Floor:      uint64(getEnv("ETH_GAS_LIMIT_FLOOR", uint(0))),
+			Methods:    getEnv("ETH_GAS_LIMIT_METHODS", ""),
+		},
+		TopUp: TopUpConfig{
+			AlertBalance: getEnv("ETH_BALANCE_ALERT", ""),
+			AlertURL:     getEnv("ETH_BALANCE_ALERT_URL", ""),
+			Treasury:     getEnv("ETH_TREASURY", ""),
+			Target:       getEnv("ETH_TOPUP_TARGET", ""),
+			DailyCap:     getEnv("ETH_TOPUP_DAILY_CAP", ""),
+			Interval:     getEnv("ETH_BALANCE_CHECK_INTERVAL", time.Minute),
+		},
+		Signer: SignerConfig{
+			Type:                 getEnv("ETH_SIGNER", "key"),
+			KeystoreFile:         getEnv("ETH_KEYSTORE_FILE", ""),
+			KeystorePassword:     getEnv("ETH_KEYSTORE_PASSWORD", ""),
+			KeystorePasswordFile: getEnv("ETH_KEYSTORE_PASSWORD_FILE", ""),
+			RemoteURL:            getEnv("ETH_REMOTE_SIGNER_URL", ""),
+			RemoteAddress:        getEnv("ETH_REMOTE_SIGNER_ADDRESS", ""),
+		},
+	}
+
+	for _, name := range splitList(getEnv("ETH_CHAINS", "")) {
+		prefix := "ETH_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		c.Chains = append(c.Chains, ChainConfig{
+			Name:          name,
+			ChainID:       uint64(getEnv(prefix+"CHAIN_ID", uint(0))),
+			RpcUrls:       splitList(getEnv(prefix+"RPC_URL", "")),
+			WsUrls:        splitList(getEnv(prefix+"WS_URL", "")),
+			RateLimit:     getEnv(prefix+"RATE_LIMIT", 0.0),
+			MaxLag:        uint64(getEnv(prefix+"MAX_LAG", uint(3))),
+			Confirmations: uint64(getEnv(prefix+"CONFIRMATIONS", uint(6))),
+			ReorgDepth:    uint64(getEnv(prefix+"REORG_DEPTH", uint(6))),
+			StartBlock:    uint64(getEnv(prefix+"START_BLOCK", uint(0))),
+			MaxFeeGwei:    getEnv(prefix+"MAX_FEE_GWEI", 0.0),
+			StuckAfter:    getEnv(prefix+"STUCK_AFTER", time.Duration(0)),
+		})
+	}
+
+	// 兼容单链配置：ETH_RPC_URL 不含协议，可逗号分隔多个节点
+	if len(c.Chains) == 0 && c.RpcUrl != "" {
+		var rpcUrls, wsUrls []string
+		for _, u := range splitList(c.RpcUrl) {
+			rpcUrls = append(rpcUrls, "https://"+u)
+			wsUrls = append(wsUrls, "wss://"+u)
+		}
+		c.Chains = append(c.Chains, ChainConfig{
+			Name:          c.NetworkName,
+			ChainID:       uint64(getEnv("ETH_CHAIN_ID", uint(0))),
+			RpcUrls:       rpcUrls,
+			WsUrls:        wsUrls,
+			RateLimit:     getEnv("ETH_RATE_LIMIT", 0.0),
+			MaxLag:        3,
+			Confirmations: 6,
+			ReorgDepth:    6,
+			StartBlock:    uint64(getEnv("ETH_START_BLOCK", uint(0))),
+			MaxFeeGwei:    getEnv("ETH_MAX_FEE_GWEI", 0.0),
+			StuckAfter:    getEnv("ETH_STUCK_AFTER", time.Duration(0)),
+		})
+	}
+
+	c.DefaultChain = getEnv("ETH_DEFAULT_CHAIN", "")
+	if c.DefaultChain == "" && len(c.Chains) > 0 {
+		c.DefaultChain = c.Chains[0].Name
+	}
+	return c
+}
+
+// splitList 解析逗号分隔的配置，忽略空项
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// FindFile 从当前目录向上查找文件，绝对路径原样返回，未找到返回空串
+func FindFile(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
 	dir, _ := os.Getwd()
 
 	for i := 0; i < 6; i++ {
-		encPath := filepath.Join(dir, ".env")
+		encPath := filepath.Join(dir, name)
 		if _, err := os.Stat(encPath); err == nil {
 			return encPath
 		}
@@ -93,7 +254,7 @@ func findEnvFile() string {
 }
 
 func loadEnvFiles() {
-	envPath := findEnvFile()
+	envPath := FindFile(".env")
 	if envPath != "" {
 		if err := godotenv.Load(envPath); err != nil {
 			log.Printf("加载 .env 文件失败: %v", err)
@@ -161,17 +322,104 @@ func getEnv[T any](key string, def T) T {
 
 }
 
-func validateConfig(c *Config) {
+// Validate 校验配置
+func (c Config) Validate() error {
 	ethCfg := c.EthConfig()
-	if ethCfg.RpcUrl == "" {
-		log.Fatal("配置错误：缺少 ETH_RPC_URL")
+	if len(ethCfg.Chains) == 0 {
+		return errors.New("配置错误：缺少 ETH_CHAINS 或 ETH_RPC_URL")
+	}
+
+	seen := map[string]bool{}
+	for _, chain := range ethCfg.Chains {
+		if chain.Name == "" {
+			return errors.New("配置错误：缺少 ETH_NETWORK_NAME")
+		}
+		if seen[chain.Name] {
+			return fmt.Errorf("配置错误：链 %s 重复配置", chain.Name)
+		}
+		seen[chain.Name] = true
+
+		if len(chain.RpcUrls) == 0 || len(chain.WsUrls) == 0 {
+			return fmt.Errorf("配置错误：链 %s 缺少 RPC_URL 或 WS_URL", chain.Name)
+		}
+		// 自动加速每次提高 12.5%，没有上限时卡住的交易会被无限加价
+		if chain.StuckAfter > 0 && chain.MaxFeeGwei <= 0 {
+			return fmt.Errorf("配置错误：链 %s 开启自动加速（STUCK_AFTER）时必须配置 MAX_FEE_GWEI", chain.Name)
+		}
+	}
+	if !seen[ethCfg.DefaultChain] {
+		return fmt.Errorf("配置错误：ETH_DEFAULT_CHAIN %s 未在 ETH_CHAINS 中配置", ethCfg.DefaultChain)
+	}
+
+	switch signer := ethCfg.Signer; signer.Type {
+	case "", "key":
+		if ethCfg.Private == "" {
+			return errors.New("配置错误：缺少 ETH_PRIVATE")
+		}
+	case "keystore":
+		if signer.KeystoreFile == "" {
+			return errors.New("配置错误：缺少 ETH_KEYSTORE_FILE")
+		}
+	case "remote":
+		if signer.RemoteURL == "" || signer.RemoteAddress == "" {
+			return errors.New("配置错误：缺少 ETH_REMOTE_SIGNER_URL 或 ETH_REMOTE_SIGNER_ADDRESS")
+		}
+	default:
+		return errors.New("配置错误：ETH_SIGNER 只支持 key、keystore 或 remote")
+	}
+	if ethCfg.MinBalance != "" && !validEth(ethCfg.MinBalance) {
+		return errors.New("配置错误：ETH_SENDER_MIN_BALANCE 必须是非负的 ETH 数量")
+	}
+	if ethCfg.GasLimit.Multiplier < 1 {
+		return errors.New("配置错误：ETH_GAS_LIMIT_MULTIPLIER 不能小于 1")
+	}
+	if err := validateTopUp(ethCfg.TopUp); err != nil {
+		return err
 	}
 
-	if ethCfg.NetworkName == "" {
-		log.Fatal("配置错误：缺少 ETH_NETWORK_NAME")
+	contractsCfg := c.ContractsConfig()
+	if contractsCfg.Source != "file" && contractsCfg.Source != "redis" {
+		return errors.New("配置错误：CONTRACTS_SOURCE 只支持 file 或 redis")
 	}
+	return nil
+}
 
-	if ethCfg.Private == "" {
-		log.Fatal("配置错误：缺少 ETH_PRIVATE")
+func validateTopUp(c TopUpConfig) error {
+	if c.AlertBalance == "" {
+		if c.Treasury != "" {
+			return errors.New("配置错误：配置 ETH_TREASURY 时需要配置 ETH_BALANCE_ALERT")
+		}
+		return nil
+	}
+	if !validEth(c.AlertBalance) {
+		return errors.New("配置错误：ETH_BALANCE_ALERT 必须是非负的 ETH 数量")
+	}
+	if c.AlertURL != "" && !strings.HasPrefix(c.AlertURL, "http://") && !strings.HasPrefix(c.AlertURL, "https://") {
+		return errors.New("配置错误：ETH_BALANCE_ALERT_URL 只支持 http/https")
 	}
+	if c.Interval <= 0 {
+		return errors.New("配置错误：ETH_BALANCE_CHECK_INTERVAL 必须大于 0")
+	}
+	if c.Treasury == "" {
+		return nil
+	}
+	if !validEth(c.Target) || !validEth(c.DailyCap) {
+		return errors.New("配置错误：配置 ETH_TREASURY 时 ETH_TOPUP_TARGET 与 ETH_TOPUP_DAILY_CAP 必须是非负的 ETH 数量")
+	}
+	target, _ := strconv.ParseFloat(c.Target, 64)
+	alert, _ := strconv.ParseFloat(c.AlertBalance, 64)
+	if target <= alert {
+		return errors.New("配置错误：ETH_TOPUP_TARGET 必须大于 ETH_BALANCE_ALERT")
+	}
+	if dailyCap, _ := strconv.ParseFloat(c.DailyCap, 64); dailyCap <= 0 {
+		return errors.New("配置错误：ETH_TOPUP_DAILY_CAP 必须大于 0")
+	}
+	return nil
+}
+
+// validEth 非负的十进制 ETH 数量，如 0.5
+func validEth(s string) bool {
+	return ethAmount.MatchString(s)
 }
+
+var ethAmount = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
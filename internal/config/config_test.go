@@ -0,0 +1,56 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// validConfig 通过校验的最小配置
+func validConfig() (EthConfig, ContractsConfig) {
+	eth := EthConfig{
+		Private:  "0x01",
+		GasLimit: GasLimitConfig{Multiplier: 1.2},
+		Chains: []ChainConfig{{
+			Name:    "sepolia",
+			RpcUrls: []string{"https://rpc.example"},
+			WsUrls:  []string{"wss://rpc.example"},
+		}},
+		DefaultChain: "sepolia",
+	}
+	return eth, ContractsConfig{Source: "file"}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*EthConfig)
+		wantErr string
+	}{
+		{"valid", func(*EthConfig) {}, ""},
+		{"stuck after without fee cap", func(c *EthConfig) { c.Chains[0].StuckAfter = 3 * time.Minute }, "MAX_FEE_GWEI"},
+		{"stuck after with fee cap", func(c *EthConfig) {
+			c.Chains[0].StuckAfter = 3 * time.Minute
+			c.Chains[0].MaxFeeGwei = 100
+		}, ""},
+		{"gas limit multiplier below 1", func(c *EthConfig) { c.GasLimit.Multiplier = 0.9 }, "ETH_GAS_LIMIT_MULTIPLIER"},
+		{"gas limit multiplier unset", func(c *EthConfig) { c.GasLimit.Multiplier = 0 }, "ETH_GAS_LIMIT_MULTIPLIER"},
+		{"gas limit multiplier 1", func(c *EthConfig) { c.GasLimit.Multiplier = 1 }, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eth, contracts := validConfig()
+			tt.modify(&eth)
+			err := New("8080", eth, RedisConfig{}, contracts).Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
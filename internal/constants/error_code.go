@@ -22,6 +22,12 @@ const (
 	ContractError = "E40001"
 
 	TransError = "E50001"
+	// InsufficientFundsError 余额不足以支付 gas limit × maxFeePerGas + value，data 中返回差额
+	InsufficientFundsError = "E50002"
+	// SendUnknownError 广播结果未知，交易可能已经上链，按返回的交易 hash 查询状态后再决定是否重试
+	SendUnknownError = "E50003"
+
+	EventError = "E60001"
 
 	// InternalServerError 系统内部错误，非代码逻辑错误。
 	InternalServerError = "E90000"
@@ -2,8 +2,8 @@ package handlers
 
 import (
 	"go-web3/internal/constants"
+	"go-web3/internal/middleware"
 	"go-web3/internal/services/account"
-	"go-web3/internal/services/trans"
 	"go-web3/internal/utils"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -21,7 +21,7 @@ func GetBalance(c *gin.Context) {
 		utils.FailMsg(c, constants.ParamError, "address is required")
 		return
 	}
-	result, err := account.GetEthBalance(address)
+	result, err := account.GetEthBalance(middleware.CurrentChain(c), address)
 	if err != nil {
 		utils.FailMsg(c, constants.AccountError, err.Error())
 		return
@@ -30,7 +30,7 @@ func GetBalance(c *gin.Context) {
 	utils.OkData(c, result)
 }
 
-func Trans(c *gin.Context) {
+func (h *Handlers) Trans(c *gin.Context) {
 	var req TransInfoReq
 	if err := c.ShouldBindJSON(&req); err != nil {
 		if err.Error() == "EOF" {
@@ -45,23 +45,28 @@ func Trans(c *gin.Context) {
 		utils.FailMsg(c, constants.ParamError, "无效的账户地址！")
 		return
 	}
-	result, err := trans.Trans(req.To, req.Amount)
+	txReq, err := txRequest(c)
 	if err != nil {
-		utils.FailMsg(c, constants.AccountError, err.Error())
+		utils.FailMsg(c, constants.ParamError, err.Error())
+		return
+	}
+	result, err := h.TransService.Trans(middleware.CurrentChain(c), req.To, req.Amount, txReq)
+	if err != nil {
+		failTx(c, constants.AccountError, err)
 		return
 	}
 
 	utils.OkData(c, result)
 }
 
-func GetTxReceipt(c *gin.Context) {
+func (h *Handlers) GetTxReceipt(c *gin.Context) {
 	txHash := c.Param("txHash")
 	if txHash == "" {
 		utils.FailMsg(c, constants.ParamError, "txHash is required")
 		return
 	}
 
-	result, err := trans.GetTxReceipt(txHash)
+	result, err := h.TransService.GetTxReceipt(middleware.CurrentChain(c), txHash)
 	if err != nil {
 		utils.FailMsg(c, constants.TransError, err.Error())
 		return
@@ -2,7 +2,7 @@ package handlers
 
 import (
 	"go-web3/internal/constants"
-	"go-web3/internal/services"
+	"go-web3/internal/middleware"
 	"go-web3/internal/utils"
 	"math/big"
 
@@ -10,7 +10,7 @@ import (
 )
 
 // SettleAuction 拍卖结算
-func SettleAuction(c *gin.Context) {
+func (h *Handlers) SettleAuction(c *gin.Context) {
 	auctionIdStr := c.Param("auctionId")
 	if auctionIdStr == "" {
 		utils.FailMsg(c, constants.ParamError, "auctionId is required")
@@ -22,16 +22,21 @@ func SettleAuction(c *gin.Context) {
 		utils.FailMsg(c, constants.ParamError, "invalid auctionId")
 		return
 	}
-	err := services.SettleAuction(auctionId)
+	txReq, err := txRequest(c)
 	if err != nil {
-		utils.FailMsg(c, constants.ContractError, err.Error())
+		utils.FailMsg(c, constants.ParamError, err.Error())
+		return
+	}
+	result, err := h.AuctionService.SettleAuction(middleware.CurrentChain(c), auctionId, txReq)
+	if err != nil {
+		failTx(c, constants.ContractError, err)
 		return
 	}
 
-	utils.Ok(c)
+	utils.OkData(c, result)
 }
 
-func CancelAuction(c *gin.Context) {
+func (h *Handlers) CancelAuction(c *gin.Context) {
 	auctionIdStr := c.Param("auctionId")
 	if auctionIdStr == "" {
 		utils.FailMsg(c, constants.ParamError, "auctionId is required")
@@ -43,11 +48,16 @@ func CancelAuction(c *gin.Context) {
 		utils.FailMsg(c, constants.ParamError, "invalid auctionId")
 		return
 	}
-	err := services.CancelAuction(auctionId)
+	txReq, err := txRequest(c)
+	if err != nil {
+		utils.FailMsg(c, constants.ParamError, err.Error())
+		return
+	}
+	result, err := h.AuctionService.CancelAuction(middleware.CurrentChain(c), auctionId, txReq)
 	if err != nil {
-		utils.FailMsg(c, constants.ContractError, err.Error())
+		failTx(c, constants.ContractError, err)
 		return
 	}
 
-	utils.Ok(c)
+	utils.OkData(c, result)
 }
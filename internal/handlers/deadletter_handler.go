@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"go-web3/internal/constants"
+	"go-web3/internal/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListDeadLetters 分页查询死信事件
+func (h *Handlers) ListDeadLetters(c *gin.Context) {
+	offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+	if err != nil || offset < 0 {
+		utils.FailMsg(c, constants.ParamError, "invalid offset")
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+	if err != nil || limit <= 0 || limit > 100 {
+		utils.FailMsg(c, constants.ParamError, "invalid limit")
+		return
+	}
+
+	result, err := h.DeadLetterService.List(offset, limit)
+	if err != nil {
+		utils.FailMsg(c, constants.EventError, err.Error())
+		return
+	}
+
+	utils.OkData(c, result)
+}
+
+// GetDeadLetter 查询死信事件详情
+func (h *Handlers) GetDeadLetter(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		utils.FailMsg(c, constants.ParamError, "id is required")
+		return
+	}
+
+	result, err := h.DeadLetterService.Get(id)
+	if err != nil {
+		utils.FailMsg(c, constants.EventError, err.Error())
+		return
+	}
+
+	utils.OkData(c, result)
+}
+
+// ReplayDeadLetter 重放死信事件
+func (h *Handlers) ReplayDeadLetter(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		utils.FailMsg(c, constants.ParamError, "id is required")
+		return
+	}
+
+	if err := h.DeadLetterService.Replay(id); err != nil {
+		utils.FailMsg(c, constants.EventError, err.Error())
+		return
+	}
+
+	utils.Ok(c)
+}
+
+// DiscardDeadLetter 丢弃死信事件
+func (h *Handlers) DiscardDeadLetter(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		utils.FailMsg(c, constants.ParamError, "id is required")
+		return
+	}
+
+	if err := h.DeadLetterService.Discard(id); err != nil {
+		utils.FailMsg(c, constants.EventError, err.Error())
+		return
+	}
+
+	utils.Ok(c)
+}
@@ -16,6 +16,12 @@ func ListenerAuctionCreated(ctx *event.Context) error {
 		return err
 	}
 
+	// 链重组导致事件被回滚，业务需做补偿
+	if ctx.Log.Removed {
+		ctx.Logger.Printf("AuctionCreated removed by reorg: auctionId=%s tx=%s", evt.AuctionId.String(), ctx.Log.TxHash.Hex())
+		return nil
+	}
+
 	fmt.Println("---- AuctionCreated ----")
 	fmt.Println("AuctionId:", evt.AuctionId.String())
 	fmt.Println("Seller:", evt.Seller.Hex())
@@ -2,6 +2,7 @@ package eth_block
 
 import (
 	"go-web3/internal/constants"
+	"go-web3/internal/middleware"
 	"go-web3/internal/services"
 	"go-web3/internal/utils"
 	"strconv"
@@ -22,7 +23,7 @@ func GetBlockInfo(c *gin.Context) {
 		return
 	}
 
-	result, err := services.GetBlockInfo(blockNumber)
+	result, err := services.GetBlockInfo(middleware.CurrentChain(c), blockNumber)
 	if err != nil {
 		utils.FailMsg(c, constants.AccountError, err.Error())
 		return
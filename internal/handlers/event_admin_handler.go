@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"go-web3/internal/constants"
+	"go-web3/internal/middleware"
+	"go-web3/internal/services/eventadmin"
+	"go-web3/internal/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// ListContracts 查询已注册的监听合约
+func (h *Handlers) ListContracts(c *gin.Context) {
+	utils.OkData(c, h.EventAdminService.ListContracts(middleware.CurrentChain(c).Name))
+}
+
+// AddContract 运行时注册监听合约
+func (h *Handlers) AddContract(c *gin.Context) {
+	var req eventadmin.AddContractReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		if err.Error() == "EOF" {
+			utils.FailMsg(c, constants.ParamError, "请填写参数！")
+			return
+		}
+		utils.FailMsg(c, constants.ParamError, err.Error())
+		return
+	}
+	if !common.IsHexAddress(req.Address) {
+		utils.FailMsg(c, constants.ParamError, "无效的合约地址！")
+		return
+	}
+
+	if err := h.EventAdminService.AddContract(middleware.CurrentChain(c).Name, req); err != nil {
+		utils.FailMsg(c, constants.EventError, err.Error())
+		return
+	}
+
+	utils.Ok(c)
+}
+
+// RemoveContract 移除监听合约
+func (h *Handlers) RemoveContract(c *gin.Context) {
+	name := c.Param("name")
+	if name == "" {
+		utils.FailMsg(c, constants.ParamError, "name is required")
+		return
+	}
+
+	if err := h.EventAdminService.RemoveContract(middleware.CurrentChain(c).Name, name); err != nil {
+		utils.FailMsg(c, constants.EventError, err.Error())
+		return
+	}
+
+	utils.Ok(c)
+}
+
+// ListEventRoutes 查询已注册的事件路由
+func (h *Handlers) ListEventRoutes(c *gin.Context) {
+	utils.OkData(c, h.EventAdminService.ListRoutes(middleware.CurrentChain(c).Name))
+}
+
+// AddEventRoute 运行时注册事件路由
+func (h *Handlers) AddEventRoute(c *gin.Context) {
+	var req eventadmin.AddRouteReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		if err.Error() == "EOF" {
+			utils.FailMsg(c, constants.ParamError, "请填写参数！")
+			return
+		}
+		utils.FailMsg(c, constants.ParamError, err.Error())
+		return
+	}
+
+	id, err := h.EventAdminService.AddRoute(middleware.CurrentChain(c).Name, req)
+	if err != nil {
+		utils.FailMsg(c, constants.EventError, err.Error())
+		return
+	}
+
+	utils.OkData(c, gin.H{"id": id})
+}
+
+// RemoveEventRoute 移除事件路由
+func (h *Handlers) RemoveEventRoute(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		utils.FailMsg(c, constants.ParamError, "id is required")
+		return
+	}
+
+	if err := h.EventAdminService.RemoveRoute(middleware.CurrentChain(c).Name, id); err != nil {
+		utils.FailMsg(c, constants.EventError, err.Error())
+		return
+	}
+
+	utils.Ok(c)
+}
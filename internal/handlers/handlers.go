@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"go-web3/internal/services"
+	"go-web3/internal/services/deadletter"
+	"go-web3/internal/services/eventadmin"
+	"go-web3/internal/services/trans"
+)
+
+// Handlers HTTP 处理器，依赖的服务由 App 注入
+type Handlers struct {
+	TransService      *trans.Service
+	AuctionService    *services.AuctionService
+	DeadLetterService *deadletter.Service
+	EventAdminService *eventadmin.Service
+}
@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"go-web3/internal/constants"
+	"go-web3/internal/middleware"
+	"go-web3/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListSenders 账户池中各发送账户在链上的状态：在途交易、余额、连续失败与暂停情况
+func (h *Handlers) ListSenders(c *gin.Context) {
+	result, err := h.TransService.SenderStates(middleware.CurrentChain(c))
+	if err != nil {
+		utils.FailMsg(c, constants.TransError, err.Error())
+		return
+	}
+
+	utils.OkData(c, result)
+}
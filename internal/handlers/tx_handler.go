@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"go-web3/internal/constants"
+	"go-web3/internal/infra/eth"
+	ethtrans "go-web3/internal/infra/eth/trans"
+	"go-web3/internal/infra/eth/txtrack"
+	"go-web3/internal/middleware"
+	"go-web3/internal/utils"
+	"math/big"
+	"regexp"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// txRequest 发起交易的请求信息：幂等 key 作为交易记录 ID，X-Callback-Url 为可选的状态回调地址，
+// X-Sender-Pin 为可选的发送账户固定 key。gas 费可选：X-Gas-Speed 档位（slow/standard/fast），
+// X-Max-Fee-Per-Gas、X-Max-Priority-Fee-Per-Gas 覆盖计算值（gwei）
+func txRequest(c *gin.Context) (txtrack.Request, error) {
+	header := c.Request.Header
+	req := txtrack.Request{
+		ID:          header.Get("X-Idempotency-Key"),
+		CallbackURL: header.Get("X-Callback-Url"),
+		Pin:         header.Get("X-Sender-Pin"),
+		Speed:       header.Get("X-Gas-Speed"),
+	}
+	if req.CallbackURL != "" {
+		if err := txtrack.ValidateCallbackURL(req.CallbackURL); err != nil {
+			return req, err
+		}
+	}
+	if _, err := ethtrans.ParseSpeed(req.Speed); err != nil {
+		return req, err
+	}
+	var err error
+	if req.MaxFeePerGas, err = parseGwei(header.Get("X-Max-Fee-Per-Gas")); err != nil {
+		return req, err
+	}
+	if req.MaxTipPerGas, err = parseGwei(header.Get("X-Max-Priority-Fee-Per-Gas")); err != nil {
+		return req, err
+	}
+	return req, nil
+}
+
+// failTx 发送交易失败：余额不足时返回 InsufficientFundsError 与差额，广播结果未知时返回 SendUnknownError，其他错误使用 code
+func failTx(c *gin.Context, code string, err error) {
+	var funds *ethtrans.InsufficientFundsError
+	if errors.As(err, &funds) {
+		utils.FailData(c, constants.InsufficientFundsError, err.Error(), funds)
+		return
+	}
+	if errors.Is(err, ethtrans.ErrSendUnknown) {
+		utils.FailMsg(c, constants.SendUnknownError, err.Error())
+		return
+	}
+	utils.FailMsg(c, code, err.Error())
+}
+
+// parseGwei 解析十进制 gwei 为 wei，空字符串返回 nil
+func parseGwei(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if !gweiAmount.MatchString(s) {
+		return nil, errors.New("invalid gwei amount: " + s)
+	}
+	// ETH → wei 的换算结果除以 1e9 即 gwei → wei
+	wei, _ := new(big.Int).SetString(utils.ParseEthToWei(s), 10)
+	return wei.Div(wei, big.NewInt(1e9)), nil
+}
+
+var gweiAmount = regexp.MustCompile(`^[0-9]+(\.[0-9]{1,9})?$`)
+
+// GasFees 链上 slow/standard/fast 各档位当前的 gas 费
+func (h *Handlers) GasFees(c *gin.Context) {
+	result, err := h.TransService.GasFees(middleware.CurrentChain(c))
+	if err != nil {
+		utils.FailMsg(c, constants.TransError, err.Error())
+		return
+	}
+
+	utils.OkData(c, result)
+}
+
+// GetTx 按请求 ID 或交易 hash 查询交易状态
+func (h *Handlers) GetTx(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		utils.FailMsg(c, constants.ParamError, "id is required")
+		return
+	}
+
+	result, err := h.TransService.GetTx(id)
+	if errors.Is(err, txtrack.ErrTxNotFound) {
+		utils.FailMsg(c, constants.TransError, "交易不存在！")
+		return
+	}
+	if err != nil {
+		utils.FailMsg(c, constants.TransError, err.Error())
+		return
+	}
+
+	utils.OkData(c, result)
+}
+
+// SpeedUpTx 提高 gas 费重发发送账户（from 参数，默认为第一个账户）在该 nonce 上未打包的交易
+func (h *Handlers) SpeedUpTx(c *gin.Context) {
+	h.replaceTx(c, h.TransService.SpeedUp)
+}
+
+// CancelTx 用 0 ETH 转给自己的交易占用发送账户的该 nonce，取消未打包的交易
+func (h *Handlers) CancelTx(c *gin.Context) {
+	h.replaceTx(c, h.TransService.Cancel)
+}
+
+func (h *Handlers) replaceTx(c *gin.Context, replace func(*eth.Chain, common.Address, uint64, txtrack.Request) (*txtrack.Tx, error)) {
+	nonce, err := strconv.ParseUint(c.Param("nonce"), 10, 64)
+	if err != nil {
+		utils.FailMsg(c, constants.ParamError, "invalid nonce")
+		return
+	}
+	from := h.TransService.PrimarySender()
+	if v := c.Query("from"); v != "" {
+		if !common.IsHexAddress(v) {
+			utils.FailMsg(c, constants.ParamError, "invalid from address")
+			return
+		}
+		from = common.HexToAddress(v)
+	}
+	txReq, err := txRequest(c)
+	if err != nil {
+		utils.FailMsg(c, constants.ParamError, err.Error())
+		return
+	}
+
+	result, err := replace(middleware.CurrentChain(c), from, nonce, txReq)
+	if err != nil {
+		utils.FailMsg(c, constants.TransError, err.Error())
+		return
+	}
+
+	utils.OkData(c, result)
+}
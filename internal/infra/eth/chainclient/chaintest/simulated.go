@@ -0,0 +1,54 @@
+// Package chaintest 基于 go-ethereum 模拟链的 chainclient.Client 实现，仅供测试使用。
+// 模拟链依赖完整的 geth 节点，单独成包避免被编译进服务程序
+package chaintest
+
+import (
+	"go-web3/internal/infra/eth/chainclient"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/node"
+)
+
+var _ chainclient.Client = simulated.Client(nil)
+
+// Simulated 基于 go-ethereum 模拟链的客户端，不依赖任何节点。
+// 交易进入交易池后需要调用 Commit 打包出块，chain ID 固定为 1337
+type Simulated struct {
+	simulated.Client
+	Backend *simulated.Backend
+}
+
+// NewSimulated 创建模拟链，alloc 为创世账户余额
+func NewSimulated(alloc types.GenesisAlloc, options ...func(nodeConf *node.Config, ethConf *ethconfig.Config)) *Simulated {
+	backend := simulated.NewBackend(alloc, options...)
+	return &Simulated{
+		Client:  backend.Client(),
+		Backend: backend,
+	}
+}
+
+// Commit 打包交易池中的交易并出块，返回新区块 hash
+func (s *Simulated) Commit() common.Hash {
+	return s.Backend.Commit()
+}
+
+// AdjustTime 出一个时间戳前移 adjustment 的空块。
+// 出块后交易池在后台重置，刚打包的交易短时间内仍被视为待打包，重置完成前重试
+func (s *Simulated) AdjustTime(adjustment time.Duration) error {
+	deadline := time.Now().Add(time.Second)
+	for {
+		err := s.Backend.AdjustTime(adjustment)
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (s *Simulated) Close() error {
+	return s.Backend.Close()
+}
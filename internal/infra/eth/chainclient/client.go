@@ -0,0 +1,42 @@
+package chainclient
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Client 链客户端：余额、nonce、gas 费、交易发送、回执、日志查询与订阅。
+// 服务层、nonce 管理、交易发送器与事件监听只依赖该接口：
+// 生产环境由 rpcpool.Pool 实现，离线测试由 chaintest.Simulated（go-ethereum 模拟链）实现
+type Client interface {
+	ethereum.ChainIDReader
+	ethereum.BlockNumberReader
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+
+	// 账户状态
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+	PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error)
+
+	// gas 费
+	ethereum.GasPricer
+	ethereum.GasPricer1559
+	ethereum.FeeHistoryReader
+	ethereum.GasEstimator
+
+	// 调用与交易
+	ethereum.ContractCaller
+	ethereum.TransactionSender
+	TransactionByHash(ctx context.Context, txHash common.Hash) (tx *types.Transaction, isPending bool, err error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+
+	// 日志查询与订阅
+	ethereum.LogFilterer
+}
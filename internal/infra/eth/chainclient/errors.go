@@ -0,0 +1,24 @@
+package chainclient
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// IsSendUnknown 广播结果未知：超时、连接中断、网关 5xx 等，节点可能已经收到交易。
+// 节点返回 JSON-RPC 错误或 4xx 时交易确定未被接收
+func IsSendUnknown(err error) bool {
+	if err == nil {
+		return false
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return false
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500
+	}
+	return true
+}
@@ -2,45 +2,248 @@ package eth
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"go-web3/internal/config"
+	"go-web3/internal/infra/eth/chainclient"
 	"go-web3/internal/infra/eth/nonce"
+	"go-web3/internal/infra/eth/rpcpool"
 	"log"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/redis/go-redis/v9"
 )
 
-var EthClient *ethclient.Client
-var EthWssClient *ethclient.Client
-var NonceMgr *nonce.NonceManager
+// Chain 一条接入的链：独立的 HTTP/WS 客户端、nonce 命名空间与事件扫描参数
+type Chain struct {
+	ID            uint64
+	Name          string // 事件 checkpoint、合约清单等按链名区分
+	Client        chainclient.Client
+	WssClient     chainclient.Client
+	NonceMgr      *nonce.NonceManager
+	Confirmations uint64
+	ReorgDepth    uint64
+	StartBlock    uint64
+	MaxFeeCap     *big.Int      // 交易与替换交易的 gas 费上限（wei），nil 表示不限
+	StuckAfter    time.Duration // 交易未打包超过该时间自动加速，0 表示不自动加速
 
-func InitEthClient() {
-	cfg := config.Get().EthConfig()
-	client, err := ethclient.Dial("https://" + cfg.RpcUrl)
-	if err != nil {
-		log.Fatalf("Failed to connect to Ethereum RPC(Https): %v", err)
+	pools []*rpcpool.Pool // 节点池，用于健康状态查询与关闭
+}
+
+// NewChain 用指定客户端创建链，如模拟链；HTTP 与订阅共用同一客户端
+func NewChain(id uint64, name string, client chainclient.Client, nonceMgr *nonce.NonceManager) *Chain {
+	return &Chain{
+		ID:         id,
+		Name:       name,
+		Client:     client,
+		WssClient:  client,
+		NonceMgr:   nonceMgr,
+		ReorgDepth: 6,
 	}
+}
 
-	EthClient = client
+// EndpointStats 链上各节点池的健康状态
+func (c *Chain) EndpointStats() map[string][]rpcpool.EndpointStats {
+	stats := map[string][]rpcpool.EndpointStats{}
+	for _, p := range c.pools {
+		stats[p.Name()] = p.Stats()
+	}
+	return stats
+}
 
-	wssClient, err := ethclient.Dial("wss://" + cfg.RpcUrl)
+func (c *Chain) Close() {
+	for _, p := range c.pools {
+		p.Close()
+	}
+}
+
+// gweiToWei 0 表示不限，返回 nil
+func gweiToWei(gwei float64) *big.Int {
+	if gwei <= 0 {
+		return nil
+	}
+	wei, _ := new(big.Float).Mul(big.NewFloat(gwei), big.NewFloat(1e9)).Int(nil)
+	return wei
+}
+
+// Chains 接入的链，以 chain ID 为 key
+type Chains struct {
+	mu           sync.RWMutex
+	chains       map[uint64]*Chain
+	defaultChain *Chain
+}
+
+func NewChains() *Chains {
+	return &Chains{chains: map[uint64]*Chain{}}
+}
+
+// DialChains 连接配置的全部链，校验 chain ID，并同步发送账户在每条链上的 nonce
+func DialChains(ctx context.Context, cfg config.EthConfig, rdb *redis.Client, senders ...common.Address) (*Chains, error) {
+	chains := NewChains()
+	for _, cc := range cfg.Chains {
+		chain, err := dialChain(cc, rdb)
+		if err != nil {
+			chains.Close()
+			return nil, fmt.Errorf("connect to chain %s: %w", cc.Name, err)
+		}
+		if err := chains.Register(chain); err != nil {
+			chain.Close()
+			chains.Close()
+			return nil, fmt.Errorf("register chain %s: %w", cc.Name, err)
+		}
+
+		// 程序启动自动强制同步链上 nonce
+		for _, addr := range senders {
+			_ = chain.NonceMgr.ForceSyncNonce(ctx, addr)
+		}
+		log.Printf("chain %s(%d) connected", chain.Name, chain.ID)
+
+		if cc.Name == cfg.DefaultChain {
+			chains.SetDefault(chain)
+		}
+	}
+	return chains, nil
+}
+
+// dialChain 创建链的节点池。节点暂时不可用时不会退出，后台持续重连；
+// 配置了 chain ID 时节点全部不可用也能启动，否则需要至少一个节点返回 chain ID
+func dialChain(cc config.ChainConfig, rdb *redis.Client) (*Chain, error) {
+	opts := rpcpool.Options{
+		RateLimit: cc.RateLimit,
+		MaxLag:    cc.MaxLag,
+	}
+	client, err := rpcpool.New(cc.Name, cc.RpcUrls, opts)
 	if err != nil {
-		log.Fatalf("Failed to connect to Ethereum RPC(WebSocket): %v", err)
+		return nil, err
+	}
+	wssClient, err := rpcpool.New(cc.Name+"-ws", cc.WsUrls, opts)
+	if err != nil {
+		client.Close()
+		return nil, err
 	}
 
-	EthWssClient = wssClient
+	chainID := cc.ChainID
+	id, err := client.ChainID(context.Background())
+	switch {
+	case err != nil && chainID == 0:
+		client.Close()
+		wssClient.Close()
+		return nil, err
+	case err != nil:
+		log.Printf("chain %s: no endpoint available, use configured chain id %d: %v", cc.Name, chainID, err)
+	case chainID != 0 && id.Uint64() != chainID:
+		// 防止 RPC 地址配错链
+		client.Close()
+		wssClient.Close()
+		return nil, errors.New("chain id mismatch: configured " + strconv.FormatUint(chainID, 10) + ", node " + id.String())
+	default:
+		chainID = id.Uint64()
+	}
+
+	return &Chain{
+		ID:            chainID,
+		Name:          cc.Name,
+		Client:        client,
+		WssClient:     wssClient,
+		NonceMgr:      nonce.NewNonceManager(rdb, client, chainID),
+		pools:         []*rpcpool.Pool{client, wssClient},
+		Confirmations: cc.Confirmations,
+		ReorgDepth:    cc.ReorgDepth,
+		StartBlock:    cc.StartBlock,
+		MaxFeeCap:     gweiToWei(cc.MaxFeeGwei),
+		StuckAfter:    cc.StuckAfter,
+	}, nil
+}
+
+// Register 注册链，chain ID 或链名重复时报错。第一条注册的链为默认链
+func (cs *Chains) Register(chain *Chain) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if _, ok := cs.chains[chain.ID]; ok {
+		return errors.New("chain id already registered: " + strconv.FormatUint(chain.ID, 10))
+	}
+	for _, c := range cs.chains {
+		if c.Name == chain.Name {
+			return errors.New("chain name already registered: " + chain.Name)
+		}
+	}
+	cs.chains[chain.ID] = chain
+	if cs.defaultChain == nil {
+		cs.defaultChain = chain
+	}
+	return nil
+}
+
+// SetDefault 设置接口未指定链时使用的链
+func (cs *Chains) SetDefault(chain *Chain) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.defaultChain = chain
+}
+
+func (cs *Chains) Get(id uint64) (*Chain, error) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	c, ok := cs.chains[id]
+	if !ok {
+		return nil, errors.New("chain not configured: " + strconv.FormatUint(id, 10))
+	}
+	return c, nil
+}
+
+// Resolve 按 chain ID 或链名查找链，为空时返回默认链
+func (cs *Chains) Resolve(s string) (*Chain, error) {
+	if s == "" {
+		if c := cs.Default(); c != nil {
+			return c, nil
+		}
+		return nil, errors.New("no default chain configured")
+	}
+	if id, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return cs.Get(id)
+	}
+
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	for _, c := range cs.chains {
+		if strings.EqualFold(c.Name, s) {
+			return c, nil
+		}
+	}
+	return nil, errors.New("chain not configured: " + s)
 }
 
-func InitNonce(redis *redis.Client) {
-	NonceMgr = nonce.NewNonceManager(redis, EthClient)
+func (cs *Chains) Default() *Chain {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.defaultChain
+}
 
-	ctx := context.Background()
-	cfg := config.Get().EthConfig()
+// All 按 chain ID 排序返回全部链
+func (cs *Chains) All() []*Chain {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
 
-	privateKey, _ := crypto.HexToECDSA(cfg.Private)
-	addr := crypto.PubkeyToAddress(privateKey.PublicKey)
+	list := make([]*Chain, 0, len(cs.chains))
+	for _, c := range cs.chains {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
 
-	// 程序启动自动强制同步链上 nonce
-	_ = NonceMgr.ForceSyncNonce(ctx, addr)
+// Close 关闭全部链的客户端
+func (cs *Chains) Close() {
+	for _, c := range cs.All() {
+		c.Close()
+	}
 }
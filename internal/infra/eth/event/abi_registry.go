@@ -2,34 +2,149 @@ package event
 
 import (
 	"errors"
+	"sort"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 )
 
 // ABI 元数据管理中心
 
 // ABIInfo 合约的完整描述元数据
 type ABIInfo struct {
+	Chain        string // 所在链，同名合约可部署在多条链上
 	ContractName string
 	ABI          abi.ABI
 	Address      common.Address
+	Template     string // 由工厂合约创建的子合约所用的模板名，普通合约为空
+	StartBlock   uint64 // 部署（创建）区块，扫描器从该区块开始补扫
 }
 
-var ABIRegistry = map[string]*ABIInfo{}
+// Registry 事件元数据：已注册合约 ABI、子合约模板与路由表。
+// 运行时可增删合约与路由，读写都需加锁。合约以 chain/合约名 为 key，同一 Registry 可供多条链的 Router、Scanner 共享
+type Registry struct {
+	mu        sync.RWMutex
+	contracts map[string]*ABIInfo
+	templates map[string]abi.ABI
 
-func RegisterABI(name string, a abi.ABI, addr string) {
-	ABIRegistry[name] = &ABIInfo{
+	// 路由表，让扫描器能够找到。同一个事件可以命中多个路由（扇出投递）
+	routeMu sync.RWMutex
+	routes  []*Route
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		contracts: map[string]*ABIInfo{},
+		templates: map[string]abi.ABI{},
+	}
+}
+
+func registryKey(chain, name string) string {
+	return chain + "/" + name
+}
+
+func (r *Registry) RegisterABI(chain string, name string, a abi.ABI, addr string) {
+	r.RegisterContract(&ABIInfo{
+		Chain:        chain,
 		ContractName: name,
 		ABI:          a,
 		Address:      common.HexToAddress(addr),
+	})
+}
+
+// RegisterContract 注册合约，同名合约会被覆盖
+func (r *Registry) RegisterContract(info *ABIInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.contracts[registryKey(info.Chain, info.ContractName)] = info
+}
+
+// UnregisterContract 移除合约
+func (r *Registry) UnregisterContract(chain string, name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := registryKey(chain, name)
+	if _, ok := r.contracts[key]; !ok {
+		return false
 	}
+	delete(r.contracts, key)
+	return true
 }
 
-func GetABIByContract(name string) (*ABIInfo, error) {
-	v, ok := ABIRegistry[name]
+// RegisterTemplate 注册子合约模板 ABI（如工厂合约创建的拍卖合约、NFT 合集合约）
+func (r *Registry) RegisterTemplate(name string, a abi.ABI) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.templates[name] = a
+}
+
+func (r *Registry) GetTemplate(name string) (abi.ABI, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	a, ok := r.templates[name]
 	if !ok {
-		return nil, errors.New("ABI not registered: " + name)
+		return abi.ABI{}, errors.New("ABI template not registered: " + name)
+	}
+	return a, nil
+}
+
+func (r *Registry) GetABIByContract(chain string, name string) (*ABIInfo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	v, ok := r.contracts[registryKey(chain, name)]
+	if !ok {
+		return nil, errors.New("ABI not registered: " + chain + "/" + name)
 	}
 	return v, nil
 }
+
+// GetABIByAddress 按链与合约地址查找 ABI
+func (r *Registry) GetABIByAddress(chain string, addr common.Address) (*ABIInfo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, v := range r.contracts {
+		if v.Chain == chain && v.Address == addr {
+			return v, nil
+		}
+	}
+	return nil, errors.New("ABI not registered for address: " + chain + "/" + addr.Hex())
+}
+
+// ResolveLog 根据日志地址与 topic0 找到所属合约与事件
+func (r *Registry) ResolveLog(chain string, lg types.Log) (*ABIInfo, *abi.Event, error) {
+	if len(lg.Topics) == 0 {
+		return nil, nil, errors.New("log has no topics")
+	}
+	abiInfo, err := r.GetABIByAddress(chain, lg.Address)
+	if err != nil {
+		return nil, nil, err
+	}
+	ev, err := abiInfo.ABI.EventByID(lg.Topics[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	return abiInfo, ev, nil
+}
+
+// AllABIs 按合约名排序返回链上全部已注册 ABI
+func (r *Registry) AllABIs(chain string) []*ABIInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]*ABIInfo, 0, len(r.contracts))
+	for _, v := range r.contracts {
+		if v.Chain == chain {
+			list = append(list, v)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ContractName < list[j].ContractName })
+	return list
+}
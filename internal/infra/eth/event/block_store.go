@@ -2,9 +2,14 @@ package event
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"log"
 	"strconv"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -13,7 +18,30 @@ type BlockStore interface {
 	SetLastBlock(ctx context.Context, chain string, contract string, v uint64) error
 }
 
-const BlockKeyPrefix = "event:lastBlock1:"
+// BlockHashStore 记录已处理区块的 hash 及其中已处理的日志，用于检测链重组并回滚
+type BlockHashStore interface {
+	// GetBlockHash 未记录时返回零值 hash
+	GetBlockHash(ctx context.Context, chain string, number uint64) (common.Hash, error)
+	SetBlockHash(ctx context.Context, chain string, number uint64, hash common.Hash) error
+	// HeadBlock 已记录的最高区块号，未记录时返回 0
+	HeadBlock(ctx context.Context, chain string) (uint64, error)
+	AddBlockLog(ctx context.Context, chain string, number uint64, lg types.Log) error
+	BlockLogs(ctx context.Context, chain string, number uint64) ([]types.Log, error)
+	// DeleteBlock 删除区块记录（hash + 日志），并把 head 回退到 number-1
+	DeleteBlock(ctx context.Context, chain string, number uint64) error
+	// PruneBefore 清理 number 之前的区块记录
+	PruneBefore(ctx context.Context, chain string, number uint64) error
+}
+
+const (
+	BlockKeyPrefix     = "event:lastBlock1:"
+	BlockHashKeyPrefix = "event:blockHash:"
+	BlockHeadKeyPrefix = "event:blockHead:"
+	BlockLogsKeyPrefix = "event:blockLogs:"
+)
+
+// 区块日志只在重组窗口内有意义，过期自动清理
+const blockLogsTTL = 7 * 24 * time.Hour
 
 //存 lastProcessedBlock
 
@@ -50,3 +78,104 @@ func (rs *RedisBlockStore) GetLastBlock(ctx context.Context, chain string, contr
 func (rs *RedisBlockStore) SetLastBlock(ctx context.Context, chain string, contract string, v uint64) error {
 	return rs.Client.Set(ctx, key(chain, contract), strconv.FormatUint(v, 10), 0).Err()
 }
+
+func hashKey(chain string) string {
+	return BlockHashKeyPrefix + chain
+}
+
+func headKey(chain string) string {
+	return BlockHeadKeyPrefix + chain
+}
+
+func blockLogsKey(chain string, number uint64) string {
+	return BlockLogsKeyPrefix + chain + ":" + strconv.FormatUint(number, 10)
+}
+
+func (rs *RedisBlockStore) GetBlockHash(ctx context.Context, chain string, number uint64) (common.Hash, error) {
+	str, err := rs.Client.HGet(ctx, hashKey(chain), strconv.FormatUint(number, 10)).Result()
+	if errors.Is(err, redis.Nil) {
+		return common.Hash{}, nil
+	}
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.HexToHash(str), nil
+}
+
+func (rs *RedisBlockStore) SetBlockHash(ctx context.Context, chain string, number uint64, hash common.Hash) error {
+	pipe := rs.Client.TxPipeline()
+	pipe.HSet(ctx, hashKey(chain), strconv.FormatUint(number, 10), hash.Hex())
+	pipe.Set(ctx, headKey(chain), strconv.FormatUint(number, 10), 0)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (rs *RedisBlockStore) HeadBlock(ctx context.Context, chain string) (uint64, error) {
+	str, err := rs.Client.Get(ctx, headKey(chain)).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(str, 10, 64)
+}
+
+func (rs *RedisBlockStore) AddBlockLog(ctx context.Context, chain string, number uint64, lg types.Log) error {
+	buf, err := json.Marshal(lg)
+	if err != nil {
+		return err
+	}
+	k := blockLogsKey(chain, number)
+	pipe := rs.Client.TxPipeline()
+	pipe.RPush(ctx, k, buf)
+	pipe.Expire(ctx, k, blockLogsTTL)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (rs *RedisBlockStore) BlockLogs(ctx context.Context, chain string, number uint64) ([]types.Log, error) {
+	raws, err := rs.Client.LRange(ctx, blockLogsKey(chain, number), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	logs := make([]types.Log, 0, len(raws))
+	for _, raw := range raws {
+		var lg types.Log
+		if err := json.Unmarshal([]byte(raw), &lg); err != nil {
+			return nil, err
+		}
+		logs = append(logs, lg)
+	}
+	return logs, nil
+}
+
+func (rs *RedisBlockStore) DeleteBlock(ctx context.Context, chain string, number uint64) error {
+	pipe := rs.Client.TxPipeline()
+	pipe.HDel(ctx, hashKey(chain), strconv.FormatUint(number, 10))
+	pipe.Del(ctx, blockLogsKey(chain, number))
+	if number > 0 {
+		pipe.Set(ctx, headKey(chain), strconv.FormatUint(number-1, 10), 0)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (rs *RedisBlockStore) PruneBefore(ctx context.Context, chain string, number uint64) error {
+	fields, err := rs.Client.HKeys(ctx, hashKey(chain)).Result()
+	if err != nil {
+		return err
+	}
+
+	var stale []string
+	for _, f := range fields {
+		n, err := strconv.ParseUint(f, 10, 64)
+		if err != nil || n < number {
+			stale = append(stale, f)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	return rs.Client.HDel(ctx, hashKey(chain), stale...).Err()
+}
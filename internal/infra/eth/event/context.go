@@ -2,22 +2,26 @@ package event
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"go-web3/internal/infra/eth/chainclient"
 	"log"
 	"math/big"
 	"reflect"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
 )
 
 // Context 事件上下文。为事件处理器提供“事件处理所需全部信息”
 type Context struct {
 	Ctx    context.Context // 用于取消事件处理、超时控制等。
 	Log    types.Log       // go-ethereum 返回的链上原始日志
-	Client *ethclient.Client
+	Client chainclient.Client
 
+	RouteID      string // 命中的路由
+	Chain        string // 所在链
 	ContractName string
 	EventName    string
 
@@ -27,25 +31,44 @@ type Context struct {
 	Logger *log.Logger
 }
 
-// BindEvent 自动解析事件结构体
+// BindEvent 自动解析事件结构体：非 indexed 参数来自 Data，indexed 参数来自 Topics。
+// 字段按 `abi:"参数名"` 标签或参数名（驼峰）匹配，与结构体字段顺序无关
 func (c *Context) BindEvent(out interface{}) error {
-	if err := c.ABIEventUnpack(out, c.Log); err != nil {
-		return err
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return errors.New("BindEvent: out must be a pointer to struct")
 	}
 
-	// 动解析 indexed 参数（topics 部分）
 	eventABI, ok := c.ABIInfo.ABI.Events[c.EventName]
 	if !ok {
 		return fmt.Errorf("event %s not found in ABI", c.EventName)
 	}
+	elem := v.Elem()
 
+	// 解析非 indexed 参数（data 部分）
+	values, err := eventABI.Inputs.UnpackValues(c.Log.Data)
+	if err != nil {
+		return err
+	}
+	for i, arg := range eventABI.Inputs.NonIndexed() {
+		field, ok := structField(elem, arg.Name)
+		if !ok {
+			continue // 结构体不关心该参数
+		}
+		if err := copyValue(arg, field, values[i]); err != nil {
+			return fmt.Errorf("bind %s: %w", arg.Name, err)
+		}
+	}
+
+	// 动解析 indexed 参数（topics 部分）
 	topics := c.Log.Topics
+	// 匿名事件没有 topic0（事件签名）
 	topicIndex := 1
+	if eventABI.Anonymous {
+		topicIndex = 0
+	}
 
-	v := reflect.ValueOf(out)
-	elem := v.Elem()
-
-	for i, arg := range eventABI.Inputs {
+	for _, arg := range eventABI.Inputs {
 		if !arg.Indexed {
 			continue // 跳过非 indexed
 		}
@@ -54,26 +77,131 @@ func (c *Context) BindEvent(out interface{}) error {
 			return fmt.Errorf("missing topic for %s", arg.Name)
 		}
 
-		field := elem.Field(i)
-		//Solidity ABI，indexed 参数只可能是 static types
-		switch arg.Type.String() {
+		value, err := decodeTopic(arg, topics[topicIndex])
+		if err != nil {
+			return fmt.Errorf("decode indexed %s: %w", arg.Name, err)
+		}
+		topicIndex++
+
+		field, ok := structField(elem, arg.Name)
+		if !ok {
+			continue // 结构体不关心该参数
+		}
+		if err := setField(field, value); err != nil {
+			return fmt.Errorf("bind indexed %s: %w", arg.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// decodeTopic 还原 indexed 参数。
+// 静态类型（bool、intN/uintN、address、bytesN、enum）按 32 字节 ABI 编码解析；
+// 动态类型（string、bytes、数组、tuple）在 topic 中只保存其编码的 keccak256，返回该 hash
+func decodeTopic(arg abi.Argument, topic common.Hash) (interface{}, error) {
+	switch arg.Type.T {
+	case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+		return topic, nil
+
+	case abi.FunctionTy:
+		var fn [24]byte
+		copy(fn[:], topic[:24])
+		return fn, nil
+	}
 
-		case "uint256":
-			field.Set(reflect.ValueOf(new(big.Int).SetBytes(topics[topicIndex].Bytes())))
+	values, err := abi.Arguments{{Type: arg.Type}}.Unpack(topic.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return values[0], nil
+}
+
+// structField 先按 abi 标签查找字段，再按参数名的驼峰形式查找
+func structField(elem reflect.Value, name string) (reflect.Value, bool) {
+	t := elem.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if tag, ok := t.Field(i).Tag.Lookup("abi"); ok && tag == name {
+			return elem.Field(i), true
+		}
+	}
+
+	field := elem.FieldByName(abi.ToCamelCase(name))
+	return field, field.IsValid()
+}
 
-		case "address":
-			field.Set(reflect.ValueOf(common.BytesToAddress(topics[topicIndex].Bytes())))
+// copyValue 借助 go-ethereum 的 Arguments.Copy 赋值，支持 tuple、数组等复杂类型到结构体的转换
+func copyValue(arg abi.Argument, field reflect.Value, value interface{}) error {
+	if !field.CanSet() {
+		return errors.New("field is not settable")
+	}
+	holder := reflect.New(reflect.StructOf([]reflect.StructField{{Name: "V", Type: field.Type()}}))
+	if err := (abi.Arguments{arg}).Copy(holder.Interface(), []interface{}{value}); err != nil {
+		return err
+	}
+	field.Set(holder.Elem().Field(0))
+	return nil
+}
 
-		//case "uint64":
-		//	bi := new(big.Int).SetBytes(topics[topicIndex].Bytes())
-		//	field.SetUint(bi.Uint64())
+// setField 赋值，类型不同但可转换时（如自定义 enum 类型、common.Hash 与 [32]byte）做类型转换
+func setField(field reflect.Value, value interface{}) error {
+	if !field.CanSet() {
+		return errors.New("field is not settable")
+	}
 
+	src := reflect.ValueOf(value)
+	dst := field.Type()
+	switch {
+	case src.Type().AssignableTo(dst):
+		field.Set(src)
+
+	// 数字到 string 的转换不是期望的语义
+	case src.Type().ConvertibleTo(dst) && (dst.Kind() != reflect.String || src.Kind() == reflect.String):
+		field.Set(src.Convert(dst))
+
+	// *big.Int 绑定到定长整数字段
+	case src.Type() == reflect.TypeOf(&big.Int{}):
+		n := value.(*big.Int)
+		switch dst.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if !n.IsUint64() || field.OverflowUint(n.Uint64()) {
+				return fmt.Errorf("value %s overflows %s", n, dst)
+			}
+			field.SetUint(n.Uint64())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if !n.IsInt64() || field.OverflowInt(n.Int64()) {
+				return fmt.Errorf("value %s overflows %s", n, dst)
+			}
+			field.SetInt(n.Int64())
 		default:
-			return fmt.Errorf("unsupported indexed type %s", arg.Type.String())
+			return fmt.Errorf("cannot assign %s to %s", src.Type(), dst)
 		}
 
-		topicIndex++
+	default:
+		return fmt.Errorf("cannot assign %s to %s", src.Type(), dst)
 	}
-
 	return nil
 }
+
+func newContext(ctx context.Context, client chainclient.Client, logger *log.Logger, rt *Route, lg types.Log) (*Context, error) {
+	// 通配路由下合约与事件由日志本身决定
+	abiInfo, ev, err := rt.registry.ResolveLog(rt.Chain, lg)
+	if err != nil {
+		return nil, err
+	}
+	eventName := ev.Name
+
+	return &Context{
+		Ctx:          ctx,
+		Client:       client,
+		Log:          lg,
+		RouteID:      rt.ID,
+		Chain:        rt.Chain,
+		ContractName: abiInfo.ContractName,
+		EventName:    eventName,
+		ABIInfo:      abiInfo,
+		ABIEventUnpack: func(out interface{}, log types.Log) error {
+			return abiInfo.ABI.UnpackIntoInterface(out, eventName, log.Data)
+		},
+		Logger: logger,
+	}, nil
+}
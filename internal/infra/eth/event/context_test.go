@@ -0,0 +1,125 @@
+package event
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func mustType(t *testing.T, typ string, components ...abi.ArgumentMarshaling) abi.Type {
+	t.Helper()
+	ty, err := abi.NewType(typ, "", components)
+	if err != nil {
+		t.Fatalf("new type %s: %v", typ, err)
+	}
+	return ty
+}
+
+// leftPad 右对齐到 32 字节，静态数值类型的 topic 编码
+func leftPad(b []byte) common.Hash {
+	return common.BytesToHash(b)
+}
+
+// rightPad 左对齐到 32 字节，bytesN 与 function 的 topic 编码
+func rightPad(b []byte) common.Hash {
+	var h common.Hash
+	copy(h[:], b)
+	return h
+}
+
+func TestDecodeTopic(t *testing.T) {
+	addr := common.HexToAddress("0x00000000000000000000000000000000deadbeef")
+	hash := crypto.Keccak256Hash([]byte("dynamic"))
+
+	var fn [24]byte
+	copy(fn[:20], addr.Bytes())
+	copy(fn[20:], []byte{0xa9, 0x05, 0x9c, 0xbb})
+
+	negOne := common.Hash{}
+	for i := range negOne {
+		negOne[i] = 0xff
+	}
+
+	tests := []struct {
+		name  string
+		typ   abi.Type
+		topic common.Hash
+		want  interface{}
+	}{
+		{"bool", mustType(t, "bool"), leftPad([]byte{1}), true},
+		{"uint8", mustType(t, "uint8"), leftPad([]byte{7}), uint8(7)},
+		{"uint64", mustType(t, "uint64"), leftPad([]byte{1, 0}), uint64(256)},
+		{"uint256", mustType(t, "uint256"), leftPad([]byte{1, 0, 0}), big.NewInt(65536)},
+		{"int8 negative", mustType(t, "int8"), negOne, int8(-1)},
+		{"int256 negative", mustType(t, "int256"), negOne, big.NewInt(-1)},
+		{"address", mustType(t, "address"), leftPad(addr.Bytes()), addr},
+		{"bytes32", mustType(t, "bytes32"), hash, [32]byte(hash)},
+		{"bytes4", mustType(t, "bytes4"), rightPad([]byte{0xde, 0xad, 0xbe, 0xef}), [4]byte{0xde, 0xad, 0xbe, 0xef}},
+		{"function", mustType(t, "function"), rightPad(fn[:]), fn},
+		{"string", mustType(t, "string"), hash, hash},
+		{"bytes", mustType(t, "bytes"), hash, hash},
+		{"uint256[]", mustType(t, "uint256[]"), hash, hash},
+		{"address[2]", mustType(t, "address[2]"), hash, hash},
+		{"tuple", mustType(t, "tuple", abi.ArgumentMarshaling{Name: "a", Type: "uint256"}), hash, hash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeTopic(abi.Argument{Name: "v", Type: tt.typ, Indexed: true}, tt.topic)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v (%T), want %#v (%T)", got, got, tt.want, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindEvent(t *testing.T) {
+	const def = `[{"type":"event","name":"Bid","anonymous":false,"inputs":[
+		{"name":"auctionId","type":"uint256","indexed":true},
+		{"name":"bidder","type":"address","indexed":true},
+		{"name":"memo","type":"string","indexed":true},
+		{"name":"amount","type":"uint256","indexed":false}]}]`
+	parsed, err := abi.JSON(strings.NewReader(def))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := parsed.Events["Bid"]
+	bidder := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	memo := crypto.Keccak256Hash([]byte("hello"))
+	data, err := ev.Inputs.NonIndexed().Pack(big.NewInt(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Context{
+		Log: types.Log{
+			Topics: []common.Hash{ev.ID, leftPad([]byte{9}), leftPad(bidder.Bytes()), memo},
+			Data:   data,
+		},
+		EventName: "Bid",
+		ABIInfo:   &ABIInfo{ABI: parsed},
+	}
+
+	// 字段顺序与事件参数不同，Id 通过 abi 标签匹配，定长整数由 *big.Int 转换
+	var out struct {
+		Amount *big.Int
+		Memo   common.Hash
+		Bidder common.Address
+		Id     uint64 `abi:"auctionId"`
+	}
+	if err := c.BindEvent(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Id != 9 || out.Bidder != bidder || out.Memo != memo || out.Amount.Int64() != 500 {
+		t.Fatalf("unexpected binding: %+v", out)
+	}
+}
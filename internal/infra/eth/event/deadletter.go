@@ -0,0 +1,152 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/redis/go-redis/v9"
+)
+
+// DeadLetter 重试耗尽仍处理失败的事件
+type DeadLetter struct {
+	ID        string    `json:"id"`
+	Route     string    `json:"route"` // 失败的路由，重放时只投递给该路由
+	Contract  string    `json:"contract"`
+	Event     string    `json:"event"`
+	Log       types.Log `json:"log"` // 原始日志，用于重放
+	Error     string    `json:"error"`
+	Attempts  int       `json:"attempts"` // 累计尝试次数（含重放）
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func NewDeadLetter(ctx *Context, err error, attempts int) *DeadLetter {
+	now := time.Now()
+	return &DeadLetter{
+		ID:        deadLetterID(ctx.Log, ctx.RouteID),
+		Route:     ctx.RouteID,
+		Contract:  ctx.ContractName,
+		Event:     ctx.EventName,
+		Log:       ctx.Log,
+		Error:     err.Error(),
+		Attempts:  attempts,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+func deadLetterID(lg types.Log, route string) string {
+	id := fmt.Sprintf("%s-%d", lg.TxHash.Hex(), lg.Index)
+	if lg.Removed {
+		id += "-removed"
+	}
+	return id + "-" + route
+}
+
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+type DeadLetterStore interface {
+	// Save 保存死信。同一事件重复失败时累加尝试次数
+	Save(ctx context.Context, dl *DeadLetter) error
+	// List 按进入死信队列的时间倒序分页
+	List(ctx context.Context, offset, limit int64) ([]*DeadLetter, int64, error)
+	Get(ctx context.Context, id string) (*DeadLetter, error)
+	Delete(ctx context.Context, id string) error
+}
+
+const (
+	DeadLetterKey      = "event:deadletter"
+	DeadLetterIndexKey = "event:deadletter:index"
+)
+
+type RedisDeadLetterStore struct {
+	Client *redis.Client
+}
+
+func NewRedisDeadLetterStore(client *redis.Client) *RedisDeadLetterStore {
+	return &RedisDeadLetterStore{client}
+}
+
+func (rs *RedisDeadLetterStore) Save(ctx context.Context, dl *DeadLetter) error {
+	old, err := rs.Get(ctx, dl.ID)
+	if err != nil && !errors.Is(err, ErrDeadLetterNotFound) {
+		return err
+	}
+	if old != nil {
+		dl.Attempts += old.Attempts
+		dl.CreatedAt = old.CreatedAt
+	}
+
+	buf, err := json.Marshal(dl)
+	if err != nil {
+		return err
+	}
+
+	pipe := rs.Client.TxPipeline()
+	pipe.HSet(ctx, DeadLetterKey, dl.ID, buf)
+	pipe.ZAdd(ctx, DeadLetterIndexKey, redis.Z{Score: float64(dl.CreatedAt.UnixMilli()), Member: dl.ID})
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (rs *RedisDeadLetterStore) List(ctx context.Context, offset, limit int64) ([]*DeadLetter, int64, error) {
+	total, err := rs.Client.ZCard(ctx, DeadLetterIndexKey).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids, err := rs.Client.ZRevRange(ctx, DeadLetterIndexKey, offset, offset+limit-1).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(ids) == 0 {
+		return []*DeadLetter{}, total, nil
+	}
+
+	raws, err := rs.Client.HMGet(ctx, DeadLetterKey, ids...).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	list := make([]*DeadLetter, 0, len(raws))
+	for _, raw := range raws {
+		str, ok := raw.(string)
+		if !ok {
+			continue
+		}
+		var dl DeadLetter
+		if err := json.Unmarshal([]byte(str), &dl); err != nil {
+			return nil, 0, err
+		}
+		list = append(list, &dl)
+	}
+	return list, total, nil
+}
+
+func (rs *RedisDeadLetterStore) Get(ctx context.Context, id string) (*DeadLetter, error) {
+	raw, err := rs.Client.HGet(ctx, DeadLetterKey, id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrDeadLetterNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var dl DeadLetter
+	if err := json.Unmarshal(raw, &dl); err != nil {
+		return nil, err
+	}
+	return &dl, nil
+}
+
+func (rs *RedisDeadLetterStore) Delete(ctx context.Context, id string) error {
+	pipe := rs.Client.TxPipeline()
+	pipe.HDel(ctx, DeadLetterKey, id)
+	pipe.ZRem(ctx, DeadLetterIndexKey, id)
+	_, err := pipe.Exec(ctx)
+	return err
+}
@@ -0,0 +1,194 @@
+package event
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// 通用事件解析：只依赖 ABIInfo，不需要生成的绑定结构体，供 webhook、存储、日志等通用消费者使用
+// 渲染规则：大整数（> 32 位）为十进制字符串，地址为 EIP-55 校验和格式，bytes/hash 为 0x 十六进制，
+// tuple 为保持参数顺序的对象，indexed 的动态类型为其 keccak256 hash
+
+// EventField 单个事件参数
+type EventField struct {
+	Name    string
+	Type    string
+	Indexed bool
+	Value   any
+}
+
+// EventData 按 ABI 参数顺序排列的事件参数，JSON 序列化为保持顺序的对象
+type EventData []EventField
+
+func (d EventData) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range d {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(f.Name)
+		if err != nil {
+			return nil, err
+		}
+		val, err := json.Marshal(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// EventDocument 带日志元数据的通用事件文档
+type EventDocument struct {
+	Contract    string    `json:"contract"`
+	Event       string    `json:"event"`
+	Address     string    `json:"address"`
+	BlockNumber uint64    `json:"blockNumber"`
+	BlockHash   string    `json:"blockHash"`
+	TxHash      string    `json:"txHash"`
+	LogIndex    uint      `json:"logIndex"`
+	Removed     bool      `json:"removed"`
+	Args        EventData `json:"args"`
+}
+
+// DecodeEvent 按 ABI 解析事件的全部参数（indexed + 非 indexed）
+func (c *Context) DecodeEvent() (EventData, error) {
+	eventABI, ok := c.ABIInfo.ABI.Events[c.EventName]
+	if !ok {
+		return nil, fmt.Errorf("event %s not found in ABI", c.EventName)
+	}
+
+	values, err := eventABI.Inputs.UnpackValues(c.Log.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	topics := c.Log.Topics
+	topicIndex := 1
+	if eventABI.Anonymous {
+		topicIndex = 0
+	}
+
+	data := make(EventData, 0, len(eventABI.Inputs))
+	valueIndex := 0
+	for i, arg := range eventABI.Inputs {
+		var value any
+		if arg.Indexed {
+			if topicIndex >= len(topics) {
+				return nil, fmt.Errorf("missing topic for %s", arg.Name)
+			}
+			raw, err := decodeTopic(arg, topics[topicIndex])
+			if err != nil {
+				return nil, fmt.Errorf("decode indexed %s: %w", arg.Name, err)
+			}
+			topicIndex++
+
+			if hash, ok := raw.(common.Hash); ok {
+				value = hash.Hex() // 动态类型只有 hash
+			} else {
+				value = renderValue(arg.Type, raw)
+			}
+		} else {
+			value = renderValue(arg.Type, values[valueIndex])
+			valueIndex++
+		}
+
+		name := arg.Name
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		data = append(data, EventField{
+			Name:    name,
+			Type:    arg.Type.String(),
+			Indexed: arg.Indexed,
+			Value:   value,
+		})
+	}
+	return data, nil
+}
+
+// DecodeEventJSON 解析事件并序列化为带元数据的 JSON 文档
+func (c *Context) DecodeEventJSON() ([]byte, error) {
+	args, err := c.DecodeEvent()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(EventDocument{
+		Contract:    c.ContractName,
+		Event:       c.EventName,
+		Address:     c.Log.Address.Hex(),
+		BlockNumber: c.Log.BlockNumber,
+		BlockHash:   c.Log.BlockHash.Hex(),
+		TxHash:      c.Log.TxHash.Hex(),
+		LogIndex:    c.Log.Index,
+		Removed:     c.Log.Removed,
+		Args:        args,
+	})
+}
+
+// renderValue 把 go-ethereum 解析出的 Go 值转换为适合 JSON 的表示
+func renderValue(t abi.Type, v any) any {
+	rv := reflect.ValueOf(v)
+
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		if t.Size > 32 {
+			return fmt.Sprint(v) // *big.Int / int64 / uint64
+		}
+		return v
+
+	case abi.AddressTy:
+		return v.(common.Address).Hex()
+
+	case abi.HashTy:
+		return v.(common.Hash).Hex()
+
+	case abi.BytesTy:
+		return hexutil.Encode(v.([]byte))
+
+	case abi.FixedBytesTy, abi.FunctionTy:
+		b := make([]byte, rv.Len())
+		reflect.Copy(reflect.ValueOf(b), rv)
+		return hexutil.Encode(b)
+
+	case abi.SliceTy, abi.ArrayTy:
+		out := make([]any, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			out[i] = renderValue(*t.Elem, rv.Index(i).Interface())
+		}
+		return out
+
+	case abi.TupleTy:
+		if rv.Kind() == reflect.Ptr {
+			rv = rv.Elem()
+		}
+		out := make(EventData, 0, len(t.TupleElems))
+		for i, elem := range t.TupleElems {
+			name := t.TupleRawNames[i]
+			if name == "" {
+				name = fmt.Sprintf("arg%d", i)
+			}
+			out = append(out, EventField{
+				Name:  name,
+				Type:  elem.String(),
+				Value: renderValue(*elem, rv.Field(i).Interface()),
+			})
+		}
+		return out
+
+	default:
+		return v // bool、string
+	}
+}
@@ -0,0 +1,142 @@
+package event
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// listedABI 带嵌套 tuple 与 tuple 数组的事件，tuple 字段名不按字母序，用于检查顺序
+const listedABI = `[{"type":"event","name":"Listed","anonymous":false,"inputs":[
+	{"name":"id","type":"uint256","indexed":true},
+	{"name":"seller","type":"address","indexed":true},
+	{"name":"tag","type":"string","indexed":true},
+	{"name":"order","type":"tuple","indexed":false,"components":[
+		{"name":"token","type":"address"},
+		{"name":"amount","type":"uint256"},
+		{"name":"window","type":"tuple","components":[
+			{"name":"start","type":"uint64"},
+			{"name":"end","type":"uint64"}]}]},
+	{"name":"items","type":"tuple[]","indexed":false,"components":[
+		{"name":"kind","type":"uint8"},
+		{"name":"sel","type":"bytes4"}]},
+	{"name":"","type":"bytes","indexed":false},
+	{"name":"ok","type":"bool","indexed":false}]}]`
+
+type listedWindow struct {
+	Start uint64
+	End   uint64
+}
+
+type listedOrder struct {
+	Token  common.Address
+	Amount *big.Int
+	Window listedWindow
+}
+
+type listedItem struct {
+	Kind uint8
+	Sel  [4]byte
+}
+
+var (
+	listedSeller = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	listedToken  = common.HexToAddress("0x00000000000000000000000000000000deadbeef")
+	listedTag    = crypto.Keccak256Hash([]byte("rare"))
+)
+
+// newListedContext 按 listedABI 编码一条 Listed 日志
+func newListedContext(t *testing.T) *Context {
+	t.Helper()
+	parsed, err := abi.JSON(strings.NewReader(listedABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := parsed.Events["Listed"]
+	data, err := ev.Inputs.NonIndexed().Pack(
+		listedOrder{Token: listedToken, Amount: big.NewInt(1e18), Window: listedWindow{Start: 100, End: 200}},
+		[]listedItem{{Kind: 1, Sel: [4]byte{0xa9, 0x05, 0x9c, 0xbb}}, {Kind: 2, Sel: [4]byte{0x23, 0xb8, 0x72, 0xdd}}},
+		[]byte{0xde, 0xad},
+		true,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Context{
+		Log: types.Log{
+			Address:     common.HexToAddress("0x2222222222222222222222222222222222222222"),
+			Topics:      []common.Hash{ev.ID, leftPad([]byte{9}), leftPad(listedSeller.Bytes()), listedTag},
+			Data:        data,
+			BlockNumber: 42,
+			BlockHash:   common.HexToHash("0xb1"),
+			TxHash:      common.HexToHash("0xa1"),
+			Index:       3,
+		},
+		ContractName: "Market",
+		EventName:    "Listed",
+		ABIInfo:      &ABIInfo{ABI: parsed},
+	}
+}
+
+// TestDecodeEventNestedTuple 嵌套 tuple 与 tuple 数组解析为按参数顺序排列的 EventData
+func TestDecodeEventNestedTuple(t *testing.T) {
+	got, err := newListedContext(t).DecodeEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := EventData{
+		{Name: "id", Type: "uint256", Indexed: true, Value: "9"},
+		{Name: "seller", Type: "address", Indexed: true, Value: listedSeller.Hex()},
+		{Name: "tag", Type: "string", Indexed: true, Value: listedTag.Hex()},
+		{Name: "order", Type: "(address,uint256,(uint64,uint64))", Value: EventData{
+			{Name: "token", Type: "address", Value: listedToken.Hex()},
+			{Name: "amount", Type: "uint256", Value: "1000000000000000000"},
+			{Name: "window", Type: "(uint64,uint64)", Value: EventData{
+				{Name: "start", Type: "uint64", Value: "100"},
+				{Name: "end", Type: "uint64", Value: "200"},
+			}},
+		}},
+		{Name: "items", Type: "(uint8,bytes4)[]", Value: []any{
+			EventData{
+				{Name: "kind", Type: "uint8", Value: uint8(1)},
+				{Name: "sel", Type: "bytes4", Value: "0xa9059cbb"},
+			},
+			EventData{
+				{Name: "kind", Type: "uint8", Value: uint8(2)},
+				{Name: "sel", Type: "bytes4", Value: "0x23b872dd"},
+			},
+		}},
+		{Name: "arg5", Type: "bytes", Value: "0xdead"},
+		{Name: "ok", Type: "bool", Value: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("decoded:\n%#v\nwant:\n%#v", got, want)
+	}
+}
+
+// TestDecodeEventJSONOrder JSON 文档中的参数（含嵌套 tuple）保持 ABI 顺序
+func TestDecodeEventJSONOrder(t *testing.T) {
+	buf, err := newListedContext(t).DecodeEventJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"contract":"Market","event":"Listed",` +
+		`"address":"0x2222222222222222222222222222222222222222","blockNumber":42,` +
+		`"blockHash":"` + common.HexToHash("0xb1").Hex() + `","txHash":"` + common.HexToHash("0xa1").Hex() + `",` +
+		`"logIndex":3,"removed":false,"args":{` +
+		`"id":"9","seller":"` + listedSeller.Hex() + `","tag":"` + listedTag.Hex() + `",` +
+		`"order":{"token":"` + listedToken.Hex() + `","amount":"1000000000000000000","window":{"start":"100","end":"200"}},` +
+		`"items":[{"kind":1,"sel":"0xa9059cbb"},{"kind":2,"sel":"0x23b872dd"}],` +
+		`"arg5":"0xdead","ok":true}}`
+	if string(buf) != want {
+		t.Fatalf("json:\n%s\nwant:\n%s", buf, want)
+	}
+}
@@ -9,37 +9,67 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// DedupeStore 按 (日志, 路由) 去重，同一日志扇出到多个路由时各自独立。
+// 投递前先原子地占用，实时订阅与补扫同时投递同一日志时只有一方执行处理器
 type DedupeStore interface {
-	AlreadyHandled(lg types.Log) bool
-	MarkHandled(lg types.Log)
+	// Claim 占用 (日志, 路由)，返回 false 表示已处理或正由另一条路径处理
+	Claim(lg types.Log, route string) (bool, error)
+	// MarkHandled 处理成功后把占用转为已处理
+	MarkHandled(lg types.Log, route string)
+	// Unclaim 处理失败（含转入死信队列）后释放占用，事件可被再次投递
+	Unclaim(lg types.Log, route string)
 }
 
+const (
+	claimProcessing = "processing"
+	claimDone       = "done"
+)
+
+// unclaimScript 只删除仍处于处理中的占用，不删除已处理标记
+var unclaimScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
 type RedisDedupeStore struct {
 	Client *redis.Client
 	Ctx    context.Context
+
+	// ClaimTTL 处理中占用的有效期，需大于处理器含重试的最长耗时。进程在处理中退出时，过期后事件可被再次投递
+	ClaimTTL time.Duration
 }
 
 func NewRedisDedupeStore(ctx context.Context, client *redis.Client) *RedisDedupeStore {
-	return &RedisDedupeStore{client, ctx}
+	return &RedisDedupeStore{Client: client, Ctx: ctx, ClaimTTL: 10 * time.Minute}
 }
 
-func logKey(lg types.Log) string {
-	return fmt.Sprintf("event:handled:%s:%s:%d",
+func logKey(lg types.Log, route string) string {
+	key := fmt.Sprintf("event:handled:%s:%s:%d:%s",
 		lg.BlockHash.Hex(),
 		lg.TxHash.Hex(),
 		lg.Index,
+		route,
 	)
+	// 重组回滚事件与原事件分开去重
+	if lg.Removed {
+		key += ":removed"
+	}
+	return key
 }
 
-func (rs *RedisDedupeStore) AlreadyHandled(lg types.Log) bool {
-	key := logKey(lg)
-	_, err := rs.Client.Get(rs.Ctx, key).Result()
-	return err == nil // key 存在 = 已处理
+func (rs *RedisDedupeStore) Claim(lg types.Log, route string) (bool, error) {
+	return rs.Client.SetNX(rs.Ctx, logKey(lg, route), claimProcessing, rs.ClaimTTL).Result()
 }
 
-func (rs *RedisDedupeStore) MarkHandled(lg types.Log) {
-	key := logKey(lg)
+func (rs *RedisDedupeStore) MarkHandled(lg types.Log, route string) {
+	key := logKey(lg, route)
 
 	// 存 30 天即可，不要永久占存储
-	rs.Client.Set(rs.Ctx, key, 1, 30*24*time.Hour)
+	rs.Client.Set(rs.Ctx, key, claimDone, 30*24*time.Hour)
+}
+
+func (rs *RedisDedupeStore) Unclaim(lg types.Log, route string) {
+	unclaimScript.Run(rs.Ctx, rs.Client, []string{logKey(lg, route)}, claimProcessing)
 }
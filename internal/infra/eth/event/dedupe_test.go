@@ -0,0 +1,160 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const pingABI = `[{"type":"event","name":"Ping","anonymous":false,"inputs":[{"name":"id","type":"uint256","indexed":true}]}]`
+
+var pingAddress = common.HexToAddress("0x0000000000000000000000000000000000000abc")
+
+// newDedupeFixture 同一条链上共享 Registry 与 DedupeStore 的 Router 和独立 Scanner，
+// 返回路由及命中该路由的一条日志
+func newDedupeFixture(t *testing.T, handler func(*Context) error) (*Router, *Scanner, *Route, types.Log) {
+	t.Helper()
+	parsed, err := abi.JSON(strings.NewReader(pingABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := NewRegistry()
+	registry.RegisterABI("test", "Pinger", parsed, pingAddress.Hex())
+
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	store := NewRedisDedupeStore(context.Background(), rdb)
+	logger := log.New(io.Discard, "", 0)
+
+	router := NewRouter("test", nil, registry, logger)
+	router.DedupeStore = store
+	rt := router.Event("Pinger", "Ping").Use(handler)
+	scanner := &Scanner{Registry: registry, Chain: "test", DedupeStore: store, Logger: logger}
+
+	lg := types.Log{
+		Address:     pingAddress,
+		Topics:      []common.Hash{parsed.Events["Ping"].ID, common.BigToHash(common.Big1)},
+		BlockNumber: 10,
+		BlockHash:   common.HexToHash("0x01"),
+		TxHash:      common.HexToHash("0x02"),
+		Index:       3,
+	}
+	return router, scanner, rt, lg
+}
+
+// TestDispatchConcurrentPaths 实时订阅与补扫同时投递同一日志，处理器只执行一次
+func TestDispatchConcurrentPaths(t *testing.T) {
+	var calls atomic.Int32
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	router, scanner, rt, lg := newDedupeFixture(t, func(*Context) error {
+		if calls.Add(1) == 1 {
+			close(entered)
+		}
+		<-release
+		return nil
+	})
+	ctx := context.Background()
+
+	// 实时路径进入处理器后，补扫路径（独立 Scanner 与经 Router 分发两种方式）都不应再次执行
+	liveDone := make(chan error, 1)
+	go func() { liveDone <- router.Dispatch(ctx, rt, lg) }()
+	<-entered
+	if err := scanner.handleLog(ctx, lg, rt); err != nil {
+		t.Fatalf("scanner path: %v", err)
+	}
+	if err := router.Dispatch(ctx, rt, lg); err != nil {
+		t.Fatalf("scanner path via router: %v", err)
+	}
+	close(release)
+	if err := <-liveDone; err != nil {
+		t.Fatalf("live path: %v", err)
+	}
+
+	// 处理完成后再次投递同样被去重
+	if err := scanner.handleLog(ctx, lg, rt); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("handler called %d times, want 1", n)
+	}
+}
+
+// TestDispatchRace 两条路径在同一时刻大量并发投递，处理器只执行一次
+func TestDispatchRace(t *testing.T) {
+	var calls atomic.Int32
+	router, scanner, rt, lg := newDedupeFixture(t, func(*Context) error {
+		calls.Add(1)
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	})
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(live bool) {
+			defer wg.Done()
+			<-start
+			var err error
+			if live {
+				err = router.Dispatch(ctx, rt, lg)
+			} else {
+				err = scanner.handleLog(ctx, lg, rt)
+			}
+			if err != nil {
+				t.Errorf("dispatch: %v", err)
+			}
+		}(i%2 == 0)
+	}
+	close(start)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("handler called %d times, want 1", n)
+	}
+}
+
+// TestDispatchReleasesClaimOnFailure 处理失败或转入死信队列后释放占用，事件可再次投递；成功后不再投递
+func TestDispatchReleasesClaimOnFailure(t *testing.T) {
+	results := []error{
+		errors.New("temporary failure"),
+		fmt.Errorf("%w: retries exhausted", ErrDeadLettered),
+		nil,
+	}
+	var calls atomic.Int32
+	router, scanner, rt, lg := newDedupeFixture(t, func(*Context) error {
+		return results[calls.Add(1)-1]
+	})
+	ctx := context.Background()
+
+	if err := router.Dispatch(ctx, rt, lg); err == nil || errors.Is(err, ErrDeadLettered) {
+		t.Fatalf("first dispatch = %v, want handler error", err)
+	}
+	if err := scanner.handleLog(ctx, lg, rt); !errors.Is(err, ErrDeadLettered) {
+		t.Fatalf("second dispatch = %v, want ErrDeadLettered", err)
+	}
+	if err := router.Dispatch(ctx, rt, lg); err != nil {
+		t.Fatalf("third dispatch: %v", err)
+	}
+	if err := scanner.handleLog(ctx, lg, rt); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("handler called %d times, want 3", n)
+	}
+}
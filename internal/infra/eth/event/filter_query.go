@@ -1,15 +1,202 @@
 package event
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 )
 
-func buildFilterQuery(contract common.Address, event string, a abi.ABI) ethereum.FilterQuery {
-	id := a.Events[event].ID
-	return ethereum.FilterQuery{
-		Addresses: []common.Address{contract},
-		Topics:    [][]common.Hash{{id}},
+// ErrNoContractMatched 路由没有命中任何已注册合约（如模板路由在子合约创建之前）
+var ErrNoContractMatched = errors.New("no registered contract matches route")
+
+// topicFilter indexed 参数过滤条件，Values 为编码后的 topic，多个值之间为“或”
+type topicFilter struct {
+	Name   string
+	Values []common.Hash
+}
+
+// buildFilterQuery 根据路由匹配规则生成订阅/扫描条件
+func buildFilterQuery(rt *Route) (ethereum.FilterQuery, error) {
+	return routeQuery(rt, "")
+}
+
+// routeQuery 生成路由在指定合约（为空表示全部已注册合约）上的查询条件：
+// Addresses 为命中的合约，Topics[0] 为命中的事件签名（监听合约全部事件时不限制），
+// 之后为 indexed 参数过滤。同一参数在命中的事件中 topic 位置不一致时无法编码，只在进程内过滤
+func routeQuery(rt *Route, contract string) (ethereum.FilterQuery, error) {
+	var (
+		addrs  []common.Address
+		ids    []common.Hash
+		events []*abi.Event
+		seen   = map[common.Hash]struct{}{}
+	)
+
+	for _, abiInfo := range rt.registry.AllABIs(rt.Chain) {
+		if rt.Contract != "" && abiInfo.ContractName != rt.Contract {
+			continue
+		}
+		if contract != "" && abiInfo.ContractName != contract {
+			continue
+		}
+
+		matched := false
+		for name, ev := range abiInfo.ABI.Events {
+			if !rt.Match(abiInfo, name) {
+				continue
+			}
+			matched = true
+			if _, ok := seen[ev.ID]; !ok {
+				seen[ev.ID] = struct{}{}
+				ids = append(ids, ev.ID)
+				events = append(events, &ev)
+			}
+		}
+		if matched {
+			addrs = append(addrs, abiInfo.Address)
+		}
+	}
+
+	if len(addrs) == 0 {
+		return ethereum.FilterQuery{}, fmt.Errorf("%w: %s", ErrNoContractMatched, routeID(rt.Chain, rt.Contract, rt.Template, rt.Events))
+	}
+
+	query := ethereum.FilterQuery{Addresses: addrs}
+	topics := [][]common.Hash{nil}
+	if len(rt.Events) > 0 {
+		sort.Slice(ids, func(i, j int) bool { return ids[i].Cmp(ids[j]) < 0 })
+		topics[0] = ids
+	}
+
+	for _, f := range rt.filters {
+		pos, ok := uniformTopicPosition(events, f.Name)
+		if !ok {
+			continue
+		}
+		for len(topics) <= pos {
+			topics = append(topics, nil)
+		}
+		topics[pos] = f.Values
+	}
+	query.Topics = trimTopics(topics)
+	return query, nil
+}
+
+// mergeQueries 合并多个路由的查询条件（同一合约共享一次扫描）。
+// 某个位置只要有一个查询不限制，合并后该位置就不限制，结果可能比单个路由宽松，进程内再按路由精确过滤
+func mergeQueries(queries []ethereum.FilterQuery) ethereum.FilterQuery {
+	var merged ethereum.FilterQuery
+	if len(queries) == 0 {
+		return merged
+	}
+
+	seenAddr := map[common.Address]struct{}{}
+	width := 0
+	for _, q := range queries {
+		for _, addr := range q.Addresses {
+			if _, ok := seenAddr[addr]; !ok {
+				seenAddr[addr] = struct{}{}
+				merged.Addresses = append(merged.Addresses, addr)
+			}
+		}
+		if len(q.Topics) > width {
+			width = len(q.Topics)
+		}
+	}
+
+	topics := make([][]common.Hash, width)
+	for pos := 0; pos < width; pos++ {
+		seen := map[common.Hash]struct{}{}
+		var union []common.Hash
+		for _, q := range queries {
+			if pos >= len(q.Topics) || len(q.Topics[pos]) == 0 {
+				union = nil
+				break
+			}
+			for _, h := range q.Topics[pos] {
+				if _, ok := seen[h]; !ok {
+					seen[h] = struct{}{}
+					union = append(union, h)
+				}
+			}
+		}
+		topics[pos] = union
+	}
+	merged.Topics = trimTopics(topics)
+	return merged
+}
+
+func trimTopics(topics [][]common.Hash) [][]common.Hash {
+	for len(topics) > 0 && len(topics[len(topics)-1]) == 0 {
+		topics = topics[:len(topics)-1]
+	}
+	if len(topics) == 0 {
+		return nil
+	}
+	return topics
+}
+
+// indexedPosition indexed 参数在 topics 中的下标
+func indexedPosition(ev *abi.Event, name string) (int, bool) {
+	pos := 1
+	if ev.Anonymous {
+		pos = 0
+	}
+	for _, arg := range ev.Inputs {
+		if !arg.Indexed {
+			continue
+		}
+		if arg.Name == name {
+			return pos, true
+		}
+		pos++
+	}
+	return 0, false
+}
+
+func uniformTopicPosition(events []*abi.Event, name string) (int, bool) {
+	pos := -1
+	for _, ev := range events {
+		p, ok := indexedPosition(ev, name)
+		if !ok || (pos >= 0 && p != pos) {
+			return 0, false
+		}
+		pos = p
+	}
+	return pos, pos >= 0
+}
+
+// matchTopics 进程内校验日志是否满足路由的 indexed 参数过滤
+func (rt *Route) matchTopics(ev *abi.Event, lg types.Log) bool {
+	for _, f := range rt.filters {
+		pos, ok := indexedPosition(ev, f.Name)
+		if !ok || pos >= len(lg.Topics) {
+			return false
+		}
+
+		hit := false
+		for _, v := range f.Values {
+			if v == lg.Topics[pos] {
+				hit = true
+				break
+			}
+		}
+		if !hit {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchLog 判断日志是否命中路由（合约、事件与 indexed 参数过滤）
+func (rt *Route) MatchLog(lg types.Log) bool {
+	abiInfo, ev, err := rt.registry.ResolveLog(rt.Chain, lg)
+	if err != nil {
+		return false
 	}
+	return rt.Match(abiInfo, ev.Name) && rt.matchTopics(ev, lg)
 }
@@ -12,3 +12,15 @@ type EventHandlerFunc func(ctx *Context) error
 func (f EventHandlerFunc) OnEvent(ctx *Context) error {
 	return f(ctx)
 }
+
+// JSONLogHandler 以通用 JSON 文档打印事件，不依赖生成的绑定结构体，适用于任意已注册合约
+func JSONLogHandler() EventHandler {
+	return EventHandlerFunc(func(ctx *Context) error {
+		doc, err := ctx.DecodeEventJSON()
+		if err != nil {
+			return err
+		}
+		ctx.Logger.Printf("[%s] %s", ctx.RouteID, doc)
+		return nil
+	})
+}
@@ -0,0 +1,105 @@
+package event
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	defaultMinRange       = uint64(1)
+	defaultMaxRange       = uint64(2000)
+	defaultInitRange      = uint64(10)
+	defaultRequestTimeout = 15 * time.Second
+)
+
+func (s *Scanner) minRange() uint64 {
+	if s.MinRange == 0 {
+		return defaultMinRange
+	}
+	return s.MinRange
+}
+
+func (s *Scanner) maxRange() uint64 {
+	if s.MaxRange == 0 {
+		return defaultMaxRange
+	}
+	if s.MaxRange < s.minRange() {
+		return s.minRange()
+	}
+	return s.MaxRange
+}
+
+func (s *Scanner) requestTimeout() time.Duration {
+	if s.RequestTimeout <= 0 {
+		return defaultRequestTimeout
+	}
+	return s.RequestTimeout
+}
+
+// rangeSize 当前批次区块数，限制在 [MinRange, MaxRange]
+func (s *Scanner) rangeSize() uint64 {
+	if s.batch == 0 {
+		s.batch = defaultInitRange
+	}
+	if s.batch < s.minRange() {
+		s.batch = s.minRange()
+	}
+	if s.batch > s.maxRange() {
+		s.batch = s.maxRange()
+	}
+	return s.batch
+}
+
+// growRange 成功后区间翻倍
+func (s *Scanner) growRange() {
+	s.batch = s.rangeSize() * 2
+	if s.batch > s.maxRange() {
+		s.batch = s.maxRange()
+	}
+}
+
+// shrinkRange 失败的区间二分
+func (s *Scanner) shrinkRange(failed uint64) {
+	s.batch = failed / 2
+	if s.batch < s.minRange() {
+		s.batch = s.minRange()
+	}
+}
+
+// logRangeMessages 节点服务商对 eth_getLogs 区间/结果数量限制的错误信息（小写）。
+// 不包含 timeout、rate limit 等通用错误，这类错误缩小区间无济于事，交给扫描器退避重试
+var logRangeMessages = []string{
+	"query returned more than",              // geth、Infura
+	"response size exceeded",                // Alchemy
+	"response size should not greater than", // 部分 BSC 节点
+	"exceed maximum block range",            // BSC
+	"block range is too wide",               // Ankr
+	"block range too large",
+	"block range limit exceeded",
+	"range is too large",
+	"eth_getlogs is limited to", // QuickNode
+}
+
+// IsLogRangeError 判断是否为节点服务商对 eth_getLogs 区间/结果数量的限制错误，可缩小区间重试
+func IsLogRangeError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, m := range logRangeMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+
+	// -32005 为 limit exceeded，Infura 的限流也使用该错误码，需排除
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32005 {
+		return !strings.Contains(msg, "rate") && !strings.Contains(msg, "request count")
+	}
+	return false
+}
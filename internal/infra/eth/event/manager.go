@@ -0,0 +1,245 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Manager 运行时合约与路由管理：线程安全地增删合约和路由，并同步到实时订阅与扫描器。每条链一个 Manager
+type Manager struct {
+	Chain    string
+	Router   *Router
+	Scanner  *Scanner
+	Registry *Registry
+
+	mu       sync.RWMutex
+	handlers map[string]EventHandler // 具名处理器，运行时注册路由时按名称引用
+}
+
+// ContractSpec 运行时注册的合约。ABI 与 Template 二选一
+type ContractSpec struct {
+	Name       string
+	Address    common.Address
+	ABI        *abi.ABI
+	Template   string
+	StartBlock uint64 // 部署（创建）区块，扫描器从该区块开始补扫
+}
+
+// RouteSpec 运行时注册的路由。Contract、Template 都为空时匹配任意已注册合约的同名事件
+type RouteSpec struct {
+	Contract string
+	Template string
+	Events   []string
+	Handler  string // 具名处理器
+}
+
+func NewManager(router *Router, scanner *Scanner) *Manager {
+	m := &Manager{
+		Chain:    router.Chain,
+		Router:   router,
+		Registry: router.Registry,
+		Scanner:  scanner,
+		handlers: map[string]EventHandler{},
+	}
+	m.RegisterHandler("log", JSONLogHandler())
+	return m
+}
+
+// RegisterHandler 注册具名处理器
+func (m *Manager) RegisterHandler(name string, h EventHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers[name] = h
+}
+
+// HandlerNames 返回全部具名处理器
+func (m *Manager) HandlerNames() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make([]string, 0, len(m.handlers))
+	for name := range m.handlers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// AddContract 注册合约并开始监听，扫描器从 StartBlock 开始补扫。同名同地址重复注册直接返回
+func (m *Manager) AddContract(ctx context.Context, spec ContractSpec) error {
+	if spec.Name == "" {
+		return errors.New("contract name is required")
+	}
+	if spec.Address == (common.Address{}) {
+		return errors.New("contract address is required")
+	}
+
+	var contractABI abi.ABI
+	switch {
+	case spec.Template != "":
+		a, err := m.Registry.GetTemplate(spec.Template)
+		if err != nil {
+			return err
+		}
+		contractABI = a
+	case spec.ABI != nil:
+		contractABI = *spec.ABI
+	default:
+		return errors.New("contract ABI or template is required")
+	}
+
+	if existing, err := m.Registry.GetABIByContract(m.Chain, spec.Name); err == nil {
+		if existing.Address == spec.Address {
+			return nil
+		}
+		return fmt.Errorf("contract %s already registered at %s", spec.Name, existing.Address.Hex())
+	}
+	if existing, err := m.Registry.GetABIByAddress(m.Chain, spec.Address); err == nil {
+		return fmt.Errorf("address %s already registered as %s", spec.Address.Hex(), existing.ContractName)
+	}
+
+	m.Registry.RegisterContract(&ABIInfo{
+		Chain:        m.Chain,
+		ContractName: spec.Name,
+		ABI:          contractABI,
+		Address:      spec.Address,
+		Template:     spec.Template,
+		StartBlock:   spec.StartBlock,
+	})
+
+	if m.Scanner != nil {
+		if err := m.Scanner.AddContract(ctx, spec.Name, spec.StartBlock); err != nil {
+			m.Registry.UnregisterContract(m.Chain, spec.Name)
+			return err
+		}
+	}
+	if m.Router != nil {
+		m.Router.Reload()
+	}
+	return nil
+}
+
+// RemoveContract 移除合约及只绑定该合约的路由，停止其订阅与扫描
+func (m *Manager) RemoveContract(name string) error {
+	if _, err := m.Registry.GetABIByContract(m.Chain, name); err != nil {
+		return err
+	}
+
+	if m.Router != nil {
+		for _, rt := range m.Registry.AllRoutes() {
+			if rt.Chain == m.Chain && rt.Contract == name {
+				if err := m.Router.RemoveRoute(rt.ID); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	m.Registry.UnregisterContract(m.Chain, name)
+
+	if m.Scanner != nil {
+		m.Scanner.RemoveContract(name)
+	}
+	if m.Router != nil {
+		m.Router.Reload()
+	}
+	return nil
+}
+
+// AddRoute 运行时注册路由
+func (m *Manager) AddRoute(spec RouteSpec) (*Route, error) {
+	m.mu.RLock()
+	handler, ok := m.handlers[spec.Handler]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("handler %s not registered", spec.Handler)
+	}
+
+	var events map[string]abi.Event
+	switch {
+	case spec.Contract != "":
+		info, err := m.Registry.GetABIByContract(m.Chain, spec.Contract)
+		if err != nil {
+			return nil, err
+		}
+		events = info.ABI.Events
+	case spec.Template != "":
+		a, err := m.Registry.GetTemplate(spec.Template)
+		if err != nil {
+			return nil, err
+		}
+		events = a.Events
+	case len(spec.Events) == 0:
+		return nil, errors.New("events are required when no contract or template is given")
+	}
+	for _, name := range spec.Events {
+		if _, ok := events[name]; events != nil && !ok {
+			return nil, fmt.Errorf("event %s not found in ABI", name)
+		}
+	}
+
+	rt := &Route{
+		Contract: spec.Contract,
+		Template: spec.Template,
+		Events:   spec.Events,
+	}
+	rt.Use(handler)
+	return m.Router.AddRoute(rt)
+}
+
+// RemoveRoute 运行时移除路由
+func (m *Manager) RemoveRoute(id string) error {
+	return m.Router.RemoveRoute(id)
+}
+
+// ChildContractName 工厂子合约的注册名
+func ChildContractName(template string, addr common.Address) string {
+	return template + "@" + addr.Hex()
+}
+
+// FactoryHandler 工厂合约创建事件的处理器：从事件参数 addrArg 中取出子合约地址，按模板 ABI 注册子合约，
+// 扫描器从创建区块开始补扫。创建事件被重组回滚（Removed）时移除该子合约
+func (m *Manager) FactoryHandler(template string, addrArg string) EventHandler {
+	return EventHandlerFunc(func(ctx *Context) error {
+		args, err := ctx.DecodeEvent()
+		if err != nil {
+			return err
+		}
+
+		var child common.Address
+		for _, f := range args {
+			if f.Name != addrArg {
+				continue
+			}
+			hex, ok := f.Value.(string)
+			if !ok || f.Type != "address" {
+				return fmt.Errorf("factory argument %s is not an address", addrArg)
+			}
+			child = common.HexToAddress(hex)
+		}
+		if child == (common.Address{}) {
+			return fmt.Errorf("factory argument %s not found in %s", addrArg, ctx.EventName)
+		}
+
+		name := ChildContractName(template, child)
+		if ctx.Log.Removed {
+			if _, err := m.Registry.GetABIByContract(m.Chain, name); err != nil {
+				return nil
+			}
+			ctx.Logger.Printf("factory %s: child %s removed by reorg", ctx.ContractName, name)
+			return m.RemoveContract(name)
+		}
+
+		ctx.Logger.Printf("factory %s: spawned %s at block %d", ctx.ContractName, name, ctx.Log.BlockNumber)
+		return m.AddContract(ctx.Ctx, ContractSpec{
+			Name:       name,
+			Address:    child,
+			Template:   template,
+			StartBlock: ctx.Log.BlockNumber,
+		})
+	})
+}
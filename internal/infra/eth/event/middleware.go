@@ -1,15 +1,18 @@
 package event
 
+import "fmt"
+
 // Middleware 事件监听中间件
 type Middleware func(EventHandler) EventHandler
 
-// Recover 捕获 panic
+// Recover 捕获 panic，并转换为 error 交给外层中间件（如 Retry）处理
 func Recover() Middleware {
 	return func(next EventHandler) EventHandler {
-		return EventHandlerFunc(func(ctx *Context) error {
+		return EventHandlerFunc(func(ctx *Context) (err error) {
 			defer func() {
 				if r := recover(); r != nil {
 					ctx.Logger.Printf("panic recovered: %v", r)
+					err = fmt.Errorf("panic: %v", r)
 				}
 			}()
 			return next.OnEvent(ctx)
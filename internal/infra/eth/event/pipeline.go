@@ -0,0 +1,43 @@
+package event
+
+import "context"
+
+// Pipeline 统一的链上事件管道
+// 1. Router 的 WebSocket 订阅提供低延迟的实时事件，断线重连后从最后收到事件的区块补扫
+// 2. Scanner 按 BlockStore checkpoint 周期性补扫已确认区块，覆盖重启、长时间断线等缺口
+// 3. 两条路径共享同一个 DedupeStore、中间件链和 BlockHashStore，每条日志只投递一次
+// checkpoint 只由 Scanner 推进到已确认区块，实时事件不会移动 checkpoint。
+// 每条链一个 Pipeline，Router 与 Scanner 必须属于同一条链并共享同一个 Registry
+type Pipeline struct {
+	Router  *Router
+	Scanner *Scanner
+}
+
+func NewPipeline(router *Router, scanner *Scanner) *Pipeline {
+	if router.Chain != scanner.Chain {
+		panic("pipeline chain mismatch: router " + router.Chain + ", scanner " + scanner.Chain)
+	}
+	if router.Registry != scanner.Registry {
+		panic("pipeline registry mismatch on chain " + router.Chain)
+	}
+	router.DedupeStore = scanner.DedupeStore
+	router.HashStore = scanner.HashStore
+	scanner.Router = router
+
+	return &Pipeline{
+		Router:  router,
+		Scanner: scanner,
+	}
+}
+
+// Start 启动补扫与实时订阅，阻塞直到 ctx 取消且两者都已停止
+func (p *Pipeline) Start(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.Scanner.Start(ctx)
+	}()
+
+	p.Router.Listen(ctx)
+	<-done
+}
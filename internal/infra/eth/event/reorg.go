@@ -0,0 +1,193 @@
+package event
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// 链重组处理
+// 1. 每轮扫描后记录重组窗口（ReorgDepth）内每个区块的 hash，新区块的 parentHash 与已记录 hash 不一致即视为重组
+// 2. 下一轮扫描前从已记录的最高区块向下回溯，直到链上 hash 与记录一致（共同祖先）
+// 3. 孤块中已处理过的日志以 Removed=true 重新投递给路由，由业务处理器做补偿
+// 4. 各合约 lastBlock 回退到共同祖先，之后正常扫描新链上的区块
+
+// handleReorg 检测并回滚链重组
+func (s *Scanner) handleReorg(ctx context.Context) error {
+	head, err := s.HashStore.HeadBlock(ctx, s.Chain)
+	if err != nil || head == 0 {
+		return err
+	}
+
+	ancestor := head
+	reorged := false
+	for n := head; n > 0 && head-n <= s.ReorgDepth; n-- {
+		stored, err := s.HashStore.GetBlockHash(ctx, s.Chain, n)
+		if err != nil {
+			return err
+		}
+		// 超出记录范围，视为已最终确认
+		if stored == (common.Hash{}) {
+			ancestor = n
+			break
+		}
+
+		header, err := s.Client.HeaderByNumber(ctx, new(big.Int).SetUint64(n))
+		if err != nil {
+			return err
+		}
+		if header.Hash() == stored {
+			ancestor = n
+			break
+		}
+
+		s.Logger.Printf("[reorg] block %d orphaned: stored=%s canonical=%s", n, stored.Hex(), header.Hash().Hex())
+		if err := s.revertBlock(ctx, n); err != nil {
+			return err
+		}
+		ancestor = n - 1
+		reorged = true
+	}
+
+	if !reorged {
+		return nil
+	}
+
+	s.Logger.Printf("[reorg] common ancestor %d, rewind checkpoints", ancestor)
+	for _, contract := range s.contracts() {
+		last, err := s.BlockStore.GetLastBlock(ctx, s.Chain, contract)
+		if err != nil {
+			return err
+		}
+		if last > ancestor {
+			if err := s.BlockStore.SetLastBlock(ctx, s.Chain, contract, ancestor); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// revertBlock 回滚孤块中已处理的日志，然后删除该区块记录
+func (s *Scanner) revertBlock(ctx context.Context, number uint64) error {
+	logs, err := s.HashStore.BlockLogs(ctx, s.Chain, number)
+	if err != nil {
+		return err
+	}
+	s.revertLogs(ctx, logs)
+
+	return s.HashStore.DeleteBlock(ctx, s.Chain, number)
+}
+
+// revertLogs 以倒序投递日志的 Removed 事件，同样经过去重，避免与实时订阅的 Removed 事件重复
+func (s *Scanner) revertLogs(ctx context.Context, logs []types.Log) {
+	// 同一日志扇出到多个路由时会被记录多次
+	seen := map[string]struct{}{}
+	for i := len(logs) - 1; i >= 0; i-- {
+		lg := logs[i]
+		lg.Removed = true
+
+		k := logKey(lg, "")
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+
+		_, routes := s.Registry.FindRoutes(s.Chain, lg)
+		for _, route := range routes {
+			if err := s.handleLog(ctx, lg, route); err != nil {
+				s.Logger.Printf("[%s] removed handler error: %v", route.ID, err)
+			}
+		}
+	}
+}
+
+// trackBlocks 记录 (head, targetEnd] 中位于重组窗口内的区块 hash
+func (s *Scanner) trackBlocks(ctx context.Context, targetEnd uint64) error {
+	head, err := s.HashStore.HeadBlock(ctx, s.Chain)
+	if err != nil {
+		return err
+	}
+
+	from := head + 1
+	if targetEnd > s.ReorgDepth && from < targetEnd-s.ReorgDepth+1 {
+		from = targetEnd - s.ReorgDepth + 1
+	}
+
+	for n := from; n <= targetEnd; n++ {
+		header, err := s.Client.HeaderByNumber(ctx, new(big.Int).SetUint64(n))
+		if err != nil {
+			return err
+		}
+
+		// parentHash 不一致：已记录的区块被重组，停止记录，下一轮回溯处理
+		parent, err := s.HashStore.GetBlockHash(ctx, s.Chain, n-1)
+		if err != nil {
+			return err
+		}
+		if parent != (common.Hash{}) && parent != header.ParentHash {
+			s.Logger.Printf("[reorg] parent hash mismatch at block %d", n)
+			return nil
+		}
+
+		if err := s.checkBlockLogs(ctx, n, header.Hash()); err != nil {
+			return err
+		}
+		if err := s.HashStore.SetBlockHash(ctx, s.Chain, n, header.Hash()); err != nil {
+			return err
+		}
+	}
+
+	if targetEnd > 2*s.ReorgDepth {
+		return s.HashStore.PruneBefore(ctx, s.Chain, targetEnd-2*s.ReorgDepth)
+	}
+	return nil
+}
+
+// checkBlockLogs 扫描与记录 hash 之间发生重组时，日志所属区块与链上区块不一致，需立即回滚
+func (s *Scanner) checkBlockLogs(ctx context.Context, number uint64, canonical common.Hash) error {
+	logs, err := s.HashStore.BlockLogs(ctx, s.Chain, number)
+	if err != nil {
+		return err
+	}
+
+	var orphaned, kept []types.Log
+	for _, lg := range logs {
+		if lg.BlockHash == canonical {
+			kept = append(kept, lg)
+		} else {
+			orphaned = append(orphaned, lg)
+		}
+	}
+	if len(orphaned) == 0 {
+		return nil
+	}
+
+	s.Logger.Printf("[reorg] %d logs of block %d belong to an orphaned block", len(orphaned), number)
+	s.revertLogs(ctx, orphaned)
+
+	if err := s.HashStore.DeleteBlock(ctx, s.Chain, number); err != nil {
+		return err
+	}
+	for _, lg := range kept {
+		if err := s.HashStore.AddBlockLog(ctx, s.Chain, number, lg); err != nil {
+			return err
+		}
+	}
+
+	// 回退 lastBlock，下一轮重扫该区块
+	for _, contract := range s.contracts() {
+		last, err := s.BlockStore.GetLastBlock(ctx, s.Chain, contract)
+		if err != nil {
+			return err
+		}
+		if last >= number {
+			if err := s.BlockStore.SetLastBlock(ctx, s.Chain, contract, number-1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrDeadLettered 重试耗尽后事件已转入死信队列。分发器释放占用，由死信重放或再次投递处理
+var ErrDeadLettered = errors.New("event dead-lettered")
+
+// RetryPolicy 事件处理失败的重试策略（指数退避）
+type RetryPolicy struct {
+	Attempts       int           // 最大尝试次数（含首次）
+	InitialBackoff time.Duration // 首次重试前等待时间
+	MaxBackoff     time.Duration // 最大等待时间
+	Multiplier     float64       // 退避倍数
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts:       3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+	}
+}
+
+// backoff 第 attempt 次失败后的等待时间
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := float64(p.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		d *= p.Multiplier
+	}
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		return p.MaxBackoff
+	}
+	return time.Duration(d)
+}
+
+// Retry 按策略重试失败的事件处理器。重试耗尽后若配置了死信存储，
+// 则保存原始日志、路由、错误和尝试次数，并返回包装了 ErrDeadLettered 的错误
+func Retry(policy RetryPolicy, dlq DeadLetterStore) Middleware {
+	if policy.Attempts <= 0 {
+		policy.Attempts = 1
+	}
+	if policy.Multiplier < 1 {
+		policy.Multiplier = 1
+	}
+
+	return func(next EventHandler) EventHandler {
+		return EventHandlerFunc(func(ctx *Context) error {
+			var err error
+			for attempt := 1; attempt <= policy.Attempts; attempt++ {
+				if err = next.OnEvent(ctx); err == nil {
+					return nil
+				}
+				if attempt == policy.Attempts {
+					break
+				}
+
+				wait := policy.backoff(attempt)
+				ctx.Logger.Printf("handler failed (attempt %d/%d), retry in %s: %v", attempt, policy.Attempts, wait, err)
+				select {
+				case <-ctx.Ctx.Done():
+					return ctx.Ctx.Err()
+				case <-time.After(wait):
+				}
+			}
+
+			if dlq == nil {
+				return err
+			}
+
+			dl := NewDeadLetter(ctx, err, policy.Attempts)
+			if saveErr := dlq.Save(ctx.Ctx, dl); saveErr != nil {
+				return fmt.Errorf("save dead letter failed: %v (handler error: %w)", saveErr, err)
+			}
+			ctx.Logger.Printf("event dead-lettered: id=%s error=%v", dl.ID, err)
+			return fmt.Errorf("%w: %v", ErrDeadLettered, err)
+		})
+	}
+}
@@ -1,12 +1,29 @@
 package event
 
+import (
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
 // Route 事件路由
+// 匹配规则：Contract 为空表示任意已注册合约；Template 不为空时只匹配由该模板创建的子合约；Events 为空表示合约的全部事件
 type Route struct {
+	ID           string         // 路由标识，由链与匹配规则生成，用于去重与死信重放
+	Chain        string         // 所在链，由 Router 设置
 	Contract     string         // 合约
-	Event        string         // 事件
+	Template     string         // 子合约模板
+	Events       []string       // 事件
 	handlers     []EventHandler // 最终执行的 handler 链
 	middlewares  []Middleware   // 中间件列表
+	filters      []topicFilter  // indexed 参数过滤
 	finalHandler EventHandler
+	lastBlock    atomic.Uint64 // 实时订阅最后收到事件的区块，用于断线补扫
+	registry     *Registry     // 由 Router 设置
+	router       *Router       // 由 Router 的构建方法设置，添加第一个处理器时向其注册
+	registered   bool          // 已注册，此后不能再修改过滤条件
 }
 
 func (r *Route) Use(handler interface{}) *Route {
@@ -19,9 +36,11 @@ func (r *Route) Use(handler interface{}) *Route {
 	case EventHandler:
 		// 注册业务处理器
 		r.handlers = append(r.handlers, v)
+		r.register()
 
 	case func(ctx *Context) error:
 		r.handlers = append(r.handlers, EventHandlerFunc(v))
+		r.register()
 
 	default:
 		panic("invalid route Use(): must be Middleware, EventHandler or func(*Context) error")
@@ -29,6 +48,60 @@ func (r *Route) Use(handler interface{}) *Route {
 	return r
 }
 
+// register Router 构建方法创建的路由在添加第一个处理器时注册并启动订阅，过滤条件在注册前已全部确定
+func (r *Route) register() {
+	if r.router == nil || r.registered {
+		return
+	}
+	r.router.addRoute(r)
+}
+
+// Where 按 indexed 参数过滤事件，如 Where("seller", addr)、Where("nft", a, b)。
+// 同一参数的多个值为“或”，多个 Where 之间为“且”。路由命中的每个事件都必须有该 indexed 参数。
+// 必须在 Use 添加处理器（注册路由）之前调用，订阅从一开始就带有过滤条件
+func (rt *Route) Where(arg string, values ...interface{}) *Route {
+	if rt.registered {
+		panic("Where(" + arg + "): must be called before the route handler is added")
+	}
+	if len(values) == 0 {
+		panic("Where(" + arg + "): at least one value required")
+	}
+
+	// 模板路由在子合约创建之前注册，按模板 ABI 校验
+	if rt.Template != "" {
+		tmpl, err := rt.registry.GetTemplate(rt.Template)
+		if err != nil {
+			panic(err.Error())
+		}
+		for name, ev := range tmpl.Events {
+			if !rt.matchEvent(name) {
+				continue
+			}
+			if _, ok := indexedPosition(&ev, arg); !ok {
+				panic("indexed argument " + arg + " not found in event @" + rt.Template + "." + name)
+			}
+		}
+	}
+	for _, abiInfo := range rt.registry.AllABIs(rt.Chain) {
+		for name, ev := range abiInfo.ABI.Events {
+			if !rt.Match(abiInfo, name) {
+				continue
+			}
+			if _, ok := indexedPosition(&ev, arg); !ok {
+				panic("indexed argument " + arg + " not found in event " + abiInfo.ContractName + "." + name)
+			}
+		}
+	}
+
+	topics, err := abi.MakeTopics(values)
+	if err != nil {
+		panic("Where(" + arg + "): " + err.Error())
+	}
+
+	rt.filters = append(rt.filters, topicFilter{Name: arg, Values: topics[0]})
+	return rt
+}
+
 // Scanner 调用
 func (rt *Route) Handler() EventHandler {
 	if rt.finalHandler == nil {
@@ -36,3 +109,50 @@ func (rt *Route) Handler() EventHandler {
 	}
 	return rt.finalHandler
 }
+
+// Match 判断合约的某个事件是否命中该路由
+func (rt *Route) Match(abiInfo *ABIInfo, event string) bool {
+	if rt.Chain != abiInfo.Chain {
+		return false
+	}
+	if rt.Contract != "" && rt.Contract != abiInfo.ContractName {
+		return false
+	}
+	if rt.Template != "" && rt.Template != abiInfo.Template {
+		return false
+	}
+	return rt.matchEvent(event)
+}
+
+// matchEvent 判断事件名是否命中路由，Events 为空时命中全部事件
+func (rt *Route) matchEvent(event string) bool {
+	if len(rt.Events) == 0 {
+		return true
+	}
+	for _, e := range rt.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// routeID 由链与匹配规则生成路由标识，如 sepolia:NftAuctionV1.AuctionCreated、sepolia:NftAuctionV1.*、
+// sepolia:*.Transfer、sepolia:@Auction.Bid
+func routeID(chain string, contract string, template string, events []string) string {
+	switch {
+	case template != "":
+		contract = "@" + template
+	case contract == "":
+		contract = "*"
+	}
+	if chain != "" {
+		contract = chain + ":" + contract
+	}
+	if len(events) == 0 {
+		return contract + ".*"
+	}
+	sorted := append([]string(nil), events...)
+	sort.Strings(sorted)
+	return contract + "." + strings.Join(sorted, ",")
+}
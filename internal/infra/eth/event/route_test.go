@@ -0,0 +1,158 @@
+package event
+
+import (
+	"context"
+	"go-web3/internal/infra/eth/chainclient"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	gethevent "github.com/ethereum/go-ethereum/event"
+)
+
+const marketABI = `[
+	{"type":"event","name":"Sold","anonymous":false,"inputs":[
+		{"name":"seller","type":"address","indexed":true},
+		{"name":"price","type":"uint256","indexed":false}]},
+	{"type":"event","name":"Listed","anonymous":false,"inputs":[
+		{"name":"seller","type":"address","indexed":true},
+		{"name":"nft","type":"address","indexed":true}]}
+]`
+
+var (
+	marketAddress = common.HexToAddress("0x0000000000000000000000000000000000000def")
+	sellerAddress = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+)
+
+// subscribeClient 记录订阅条件，订阅保持到取消
+type subscribeClient struct {
+	chainclient.Client
+	mu      sync.Mutex
+	queries []ethereum.FilterQuery
+}
+
+func (c *subscribeClient) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, _ chan<- types.Log) (ethereum.Subscription, error) {
+	c.mu.Lock()
+	c.queries = append(c.queries, q)
+	c.mu.Unlock()
+	return gethevent.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	}), nil
+}
+
+func (c *subscribeClient) subscriptions() []ethereum.FilterQuery {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]ethereum.FilterQuery(nil), c.queries...)
+}
+
+func newMarketRegistry(t *testing.T) (*Registry, abi.ABI) {
+	t.Helper()
+	parsed, err := abi.JSON(strings.NewReader(marketABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := NewRegistry()
+	registry.RegisterABI("test", "Market", parsed, marketAddress.Hex())
+	registry.RegisterTemplate("Shop", parsed)
+	return registry, parsed
+}
+
+func noopHandler(*Context) error { return nil }
+
+// TestWhereBeforeRegistration 路由在添加处理器时才注册，监听中的 Router 第一次订阅就带有 indexed 过滤
+func TestWhereBeforeRegistration(t *testing.T) {
+	registry, parsed := newMarketRegistry(t)
+	client := &subscribeClient{}
+	router := NewRouter("test", client, registry, log.New(io.Discard, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		router.Listen(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	// 等待 Listen 就绪，之后新增的路由立即订阅
+	for deadline := time.Now().Add(time.Second); ; {
+		router.routesMu.Lock()
+		ready := router.listenCtx != nil
+		router.routesMu.Unlock()
+		if ready {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("router not listening")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	rt := router.Event("Market", "Sold").Where("seller", sellerAddress)
+	if len(registry.AllRoutes()) != 0 {
+		t.Fatal("route registered before a handler was added")
+	}
+	rt.Use(noopHandler)
+	if len(registry.AllRoutes()) != 1 {
+		t.Fatal("route not registered after Use")
+	}
+
+	var subs []ethereum.FilterQuery
+	for deadline := time.Now().Add(time.Second); len(subs) == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("route not subscribed")
+		}
+		subs = client.subscriptions()
+	}
+	want := [][]common.Hash{{parsed.Events["Sold"].ID}, {common.BytesToHash(sellerAddress.Bytes())}}
+	for _, q := range subs {
+		if len(q.Topics) != 2 || q.Topics[0][0] != want[0][0] || len(q.Topics[1]) != 1 || q.Topics[1][0] != want[1][0] {
+			t.Fatalf("subscription topics = %v, want %v", q.Topics, want)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Where after Use did not panic")
+		}
+	}()
+	rt.Where("seller", common.Address{})
+}
+
+func TestWhereValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		route     func(r *Router) *Route
+		arg       string
+		wantPanic bool
+	}{
+		{"contract indexed arg", func(r *Router) *Route { return r.Event("Market", "Sold") }, "seller", false},
+		{"contract non-indexed arg", func(r *Router) *Route { return r.Event("Market", "Sold") }, "price", true},
+		{"contract arg missing in one event", func(r *Router) *Route { return r.Contract("Market") }, "nft", true},
+		{"template indexed arg", func(r *Router) *Route { return r.Template("Shop", "Listed") }, "nft", false},
+		{"template non-indexed arg", func(r *Router) *Route { return r.Template("Shop", "Sold") }, "price", true},
+		{"template unknown arg", func(r *Router) *Route { return r.Template("Shop") }, "buyer", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, _ := newMarketRegistry(t)
+			rt := tt.route(NewRouter("test", nil, registry, log.New(io.Discard, "", 0)))
+			defer func() {
+				if got := recover() != nil; got != tt.wantPanic {
+					t.Fatalf("panic = %v, want %v", got, tt.wantPanic)
+				}
+			}()
+			rt.Where(tt.arg, sellerAddress)
+		})
+	}
+}
@@ -2,38 +2,65 @@ package event
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"go-web3/internal/infra/eth"
+	"go-web3/internal/infra/eth/chainclient"
 	"log"
+	"math/big"
 	"sync"
 	"time"
 
-	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
-)
-
-var (
-	routeTable = map[common.Address]map[common.Hash]*Route{}
-	routeMu    sync.RWMutex
 )
 
 type Router struct {
-	Client      *ethclient.Client
+	Client      chainclient.Client
 	Middlewares []Middleware
 	Routes      []*Route
 	Logger      *log.Logger
+
+	Chain    string    // 所在链，注册的路由与合约都限定在该链上
+	Registry *Registry // 合约 ABI 与路由表，与同一链的 Scanner 共享
+
+	// 以下由 Pipeline 设置，与 Scanner 共享，保证实时事件与补扫事件只投递一次
+	DedupeStore DedupeStore
+	HashStore   BlockHashStore
+
+	// 实时事件的 worker 池：同一分区 key 的事件按顺序处理，不同 key 并行，队列满时阻塞订阅形成背压
+	Workers   int           // worker 数量，默认 8
+	QueueSize int           // 每个 worker 的队列长度，默认 256
+	Partition PartitionFunc // 分区函数，默认 PartitionByContractTopic
+
+	DrainTimeout time.Duration // 停止时等待在途事件处理完毕的最长时间，默认 10s
+
+	chainMu sync.Mutex
+	chains  map[*Route]EventHandler // route 局部中间件 + 全局中间件构建后的 handler 链
+
+	pool       *WorkerPool
+	handlerCtx context.Context // 事件处理器的 ctx，只在排空超时后取消
+
+	// 运行时增删路由
+	routesMu  sync.Mutex
+	listenCtx context.Context
+	listeners map[*Route]context.CancelFunc
+	listenWg  sync.WaitGroup
 }
 
-func NewRouter(client *ethclient.Client, logger *log.Logger) *Router {
+func NewRouter(chain string, client chainclient.Client, registry *Registry, logger *log.Logger) *Router {
 	return &Router{
-		Client: client,
-		Logger: logger,
+		Chain:    chain,
+		Client:   client,
+		Registry: registry,
+		Logger:   logger,
 	}
 }
 
 func (r *Route) BuildHandler() EventHandler {
 	// Step1: 至少要有一个 handler
 	if len(r.handlers) == 0 {
-		panic("no handler registered for route " + r.Event)
+		panic("no handler registered for route " + r.ID)
 	}
 
 	// 如果多个 handler，合并成一个顺序执行的 handler
@@ -60,111 +87,481 @@ func (r *Router) Use(m ...Middleware) {
 	r.Middlewares = append(r.Middlewares, m...)
 }
 
+// Event 监听合约的单个事件。
+// 以下构建方法返回的路由在 Use 添加第一个处理器时注册并启动订阅，Where 过滤条件需在此之前设置
 func (r *Router) Event(contract string, event string) *Route {
-	abiInfo, err := GetABIByContract(contract)
+	return r.Events(contract, event)
+}
+
+// Events 监听合约的一组事件
+func (r *Router) Events(contract string, events ...string) *Route {
+	abiInfo, err := r.Registry.GetABIByContract(r.Chain, contract)
 	if err != nil {
-		panic("ABI not registered: " + contract)
+		panic(err.Error())
 	}
-	AddWatchedAddress(abiInfo.Address)
-	rt := &Route{
-		Contract: contract,
-		Event:    event,
+	for _, event := range events {
+		if _, ok := abiInfo.ABI.Events[event]; !ok {
+			panic("event " + event + " not found in ABI")
+		}
 	}
-	r.Routes = append(r.Routes, rt)
+	return r.newRoute(&Route{Contract: contract, Events: events})
+}
 
-	// 找到事件签名 topic0（Keccak256）
-	ev, ok := abiInfo.ABI.Events[event]
-	if !ok {
-		panic("event " + event + " not found in ABI")
+// Contract 监听合约的全部事件
+func (r *Router) Contract(contract string) *Route {
+	if _, err := r.Registry.GetABIByContract(r.Chain, contract); err != nil {
+		panic(err.Error())
 	}
+	return r.newRoute(&Route{Contract: contract})
+}
 
-	// 注册到全局路由表，让扫描器能够找到
-	registerRoute(abiInfo.Address, ev.ID, rt)
+// AnyContract 监听所有已注册合约中的同名事件（如所有 ERC-721 合约的 Transfer）
+func (r *Router) AnyContract(events ...string) *Route {
+	if len(events) == 0 {
+		panic("AnyContract requires at least one event")
+	}
+	return r.newRoute(&Route{Events: events})
+}
 
+// Template 监听由模板创建的全部子合约的事件，子合约在运行时注册后自动生效
+func (r *Router) Template(template string, events ...string) *Route {
+	a, err := r.Registry.GetTemplate(template)
+	if err != nil {
+		panic(err.Error())
+	}
+	for _, event := range events {
+		if _, ok := a.Events[event]; !ok {
+			panic("event " + event + " not found in template " + template)
+		}
+	}
+	return r.newRoute(&Route{Template: template, Events: events})
+}
+
+// newRoute 创建属于该 Router 的路由，Use 添加第一个处理器时注册
+func (r *Router) newRoute(rt *Route) *Route {
+	rt.Chain = r.Chain
+	rt.registry = r.Registry
+	rt.router = r
+	return rt
+}
+
+func (r *Router) addRoute(rt *Route) *Route {
+	if _, err := r.AddRoute(rt); err != nil {
+		panic(err)
+	}
 	return rt
 }
 
-func (r *Router) Listen() {
-	for _, rt := range r.Routes {
-		go r.listenRoute(rt)
+// AddRoute 注册路由，可在运行时调用：监听中的 Router 会立即为其启动订阅
+func (r *Router) AddRoute(rt *Route) (*Route, error) {
+	rt.Chain = r.Chain
+	rt.registry = r.Registry
+	_, err := buildFilterQuery(rt)
+	// 模板路由允许在子合约创建之前注册
+	if err != nil && !(rt.Template != "" && errors.Is(err, ErrNoContractMatched)) {
+		return nil, err
+	}
+	rt.registered = true
+	r.routesMu.Lock()
+	r.Routes = append(r.Routes, rt)
+	r.routesMu.Unlock()
+
+	// 注册到路由表，让扫描器能够找到
+	r.Registry.registerRoute(rt)
+
+	r.startListener(rt)
+	return rt, nil
+}
+
+// RemoveRoute 移除路由并停止其订阅，已入队的事件仍会处理完
+func (r *Router) RemoveRoute(id string) error {
+	r.routesMu.Lock()
+	defer r.routesMu.Unlock()
+
+	for i, rt := range r.Routes {
+		if rt.ID != id {
+			continue
+		}
+		if cancel, ok := r.listeners[rt]; ok {
+			cancel()
+			delete(r.listeners, rt)
+		}
+		r.Routes = append(r.Routes[:i:i], r.Routes[i+1:]...)
+		r.Registry.unregisterRoute(rt)
+
+		r.chainMu.Lock()
+		delete(r.chains, rt)
+		r.chainMu.Unlock()
+		return nil
+	}
+	return fmt.Errorf("route %s not found", id)
+}
+
+// Reload 重建全部订阅。合约增删后调用，使订阅条件中的合约地址生效
+func (r *Router) Reload() {
+	r.routesMu.Lock()
+	routes := append([]*Route(nil), r.Routes...)
+	r.routesMu.Unlock()
+
+	for _, rt := range routes {
+		r.startListener(rt)
+	}
+}
+
+// startListener 启动（或重启）路由的订阅。Router 尚未 Listen 或已停止时不做任何事
+func (r *Router) startListener(rt *Route) {
+	r.routesMu.Lock()
+	defer r.routesMu.Unlock()
+
+	if r.listenCtx == nil || r.listenCtx.Err() != nil {
+		return
+	}
+	if cancel, ok := r.listeners[rt]; ok {
+		cancel()
 	}
 
-	select {} // 阻塞主线程
+	ctx, cancel := context.WithCancel(r.listenCtx)
+	r.listeners[rt] = cancel
+	r.listenWg.Add(1)
+	go func() {
+		defer r.listenWg.Done()
+		r.listenRoute(ctx, rt)
+	}()
 }
 
-func (r *Router) listenRoute(rt *Route) {
+// Listen 为每个路由启动实时订阅，阻塞直到 ctx 取消。
+// 退出时先停止订阅，再在 DrainTimeout 内等待已入队的事件处理完毕，超时则取消处理器的 ctx
+func (r *Router) Listen(ctx context.Context) {
+	handlerCtx, cancelHandlers := context.WithCancel(context.Background())
+	defer cancelHandlers()
+
+	r.handlerCtx = handlerCtx
+	r.pool = NewWorkerPool(r.Workers, r.QueueSize)
+	if r.Partition == nil {
+		r.Partition = PartitionByContractTopic
+	}
+
+	r.routesMu.Lock()
+	r.listenCtx = ctx
+	r.listeners = map[*Route]context.CancelFunc{}
+	routes := append([]*Route(nil), r.Routes...)
+	r.routesMu.Unlock()
+
+	for _, rt := range routes {
+		r.startListener(rt)
+	}
+
+	<-ctx.Done()
+	// 持锁等待，避免与运行时新增的订阅竞争
+	r.routesMu.Lock()
+	r.listenWg.Wait()
+	r.routesMu.Unlock()
+
+	r.Logger.Println("subscriptions stopped, draining in-flight handlers...")
+	if !r.pool.Shutdown(r.drainTimeout()) {
+		cancelHandlers()
+		r.Logger.Println("drain timeout, in-flight handlers cancelled")
+		return
+	}
+	r.Logger.Println("event router stopped")
+}
+
+func (r *Router) drainTimeout() time.Duration {
+	if r.DrainTimeout <= 0 {
+		return 10 * time.Second
+	}
+	return r.DrainTimeout
+}
+
+// listenRoute 订阅单个路由的事件，订阅出错后自动重连，直到 ctx 取消
+func (r *Router) listenRoute(ctx context.Context, rt *Route) {
+	for {
+		err := r.subscribeRoute(ctx, rt)
+		if ctx.Err() != nil {
+			return
+		}
+
+		r.Logger.Println("订阅错误:", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+func (r *Router) subscribeRoute(ctx context.Context, rt *Route) (err error) {
 	defer func() {
 		if rec := recover(); rec != nil {
 			r.Logger.Printf("[panic recovered in listenRoute] %v", rec)
-			// 自动重启事件监听
-			go r.listenRoute(rt)
+			err = fmt.Errorf("panic: %v", rec)
 		}
 	}()
 
-	abiInfo, err := GetABIByContract(rt.Contract)
+	query, err := buildFilterQuery(rt)
+	if errors.Is(err, ErrNoContractMatched) {
+		// 暂无命中的合约（如模板路由尚无子合约），等待 Reload
+		<-ctx.Done()
+		return ctx.Err()
+	}
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	query := buildFilterQuery(abiInfo.Address, rt.Event, abiInfo.ABI)
-
 	logs := make(chan types.Log)
-	sub, err := r.Client.SubscribeFilterLogs(context.Background(), query, logs)
+	sub, err := r.Client.SubscribeFilterLogs(ctx, query, logs)
 	if err != nil {
-		panic(err)
+		return err
 	}
+	defer sub.Unsubscribe()
 
-	// 构建 handlerChain (route 中间件 + 全局中间件)
-	handler := rt.BuildHandler()
-	for i := len(r.Middlewares) - 1; i >= 0; i-- {
-		handler = r.Middlewares[i](handler)
+	// 断线重连后补齐断线期间的事件
+	if err := r.backfill(ctx, rt, query); err != nil {
+		r.Logger.Printf("backfill error: %v", err)
 	}
 
 	for {
 		select {
 		case logData := <-logs:
-			ctx := &Context{
-				Ctx:          context.Background(),
-				Log:          logData,
-				Client:       r.Client,
-				ContractName: rt.Contract,
-				EventName:    rt.Event,
-				Logger:       r.Logger,
-				ABIInfo:      abiInfo,
-				ABIEventUnpack: func(out interface{}, log types.Log) error {
-					return abiInfo.ABI.UnpackIntoInterface(out, rt.Event, log.Data)
-				},
+			rt.lastBlock.Store(logData.BlockNumber)
+			// 无法编码进订阅条件的过滤在进程内完成
+			if !rt.MatchLog(logData) {
+				continue
+			}
+			if err := r.submit(ctx, rt, logData); err != nil {
+				return err
 			}
-
-			go handler.OnEvent(ctx)
 
 		case err := <-sub.Err():
-			r.Logger.Println("订阅错误:", err)
-			time.Sleep(2 * time.Second)
-			go r.listenRoute(rt)
-			return
+			return err
+
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
 }
 
-func registerRoute(addr common.Address, topic common.Hash, rt *Route) {
-	routeMu.Lock()
-	defer routeMu.Unlock()
+// backfill 从该路由最后收到事件的区块补扫到最新区块。
+// 只有开启去重时才补扫，重复的事件由 DedupeStore 过滤；更早的缺口（如重启）由 Scanner 从 checkpoint 补齐
+func (r *Router) backfill(ctx context.Context, rt *Route, query ethereum.FilterQuery) error {
+	from := rt.lastBlock.Load()
+	if from == 0 || r.DedupeStore == nil {
+		return nil
+	}
+
+	latest, err := r.Client.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	if latest < from {
+		return nil
+	}
+
+	query.FromBlock = new(big.Int).SetUint64(from)
+	query.ToBlock = new(big.Int).SetUint64(latest)
+	logs, err := r.Client.FilterLogs(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	r.Logger.Printf("[%s] backfill blocks %d → %d, %d logs", rt.ID, from, latest, len(logs))
+	eth.SortLogs(logs)
+	for _, lg := range logs {
+		if !rt.MatchLog(lg) {
+			continue
+		}
+		if err := r.submit(ctx, rt, lg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// submit 按分区 key 把事件交给 worker 池处理，队列满时阻塞直到 ctx 取消
+func (r *Router) submit(ctx context.Context, rt *Route, lg types.Log) error {
+	return r.pool.Submit(ctx, r.Partition(lg), func() {
+		if err := r.Dispatch(r.handlerCtx, rt, lg); err != nil {
+			r.Logger.Printf("handler error: %v", err)
+		}
+	})
+}
+
+// Dispatch 经去重与完整中间件链把日志投递给路由，实时订阅与 Scanner 共用。
+// 去重按 (日志, 路由) 进行，同一日志命中的多个路由互不影响。
+// 执行处理器前先原子地占用，另一条路径正在处理同一日志时直接返回；
+// 处理成功后标记为已处理，失败（含转入死信队列）时释放占用，事件可被再次投递
+func (r *Router) Dispatch(ctx context.Context, rt *Route, lg types.Log) error {
+	if r.DedupeStore != nil {
+		claimed, err := r.DedupeStore.Claim(lg, rt.ID)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			return nil
+		}
+	}
+
+	c, err := newContext(ctx, r.Client, r.Logger, rt, lg)
+	if err == nil {
+		err = r.handlerFor(rt).OnEvent(c)
+	}
+	if err != nil {
+		r.unclaim(rt, lg)
+		// 死信事件同样记录到区块，重组时可回放 Removed 事件
+		if errors.Is(err, ErrDeadLettered) {
+			if hashErr := r.addBlockLog(ctx, lg); hashErr != nil {
+				return hashErr
+			}
+		}
+		return err
+	}
+	return r.markHandled(ctx, rt, lg)
+}
+
+// Replay 跳过去重重新投递死信事件，成功后标记为已处理
+func (r *Router) Replay(ctx context.Context, dl *DeadLetter) error {
+	rt := r.Registry.FindRoute(dl.Route)
+	if rt == nil {
+		return fmt.Errorf("no route %s for %s.%s", dl.Route, dl.Contract, dl.Event)
+	}
+
+	c, err := newContext(ctx, r.Client, r.Logger, rt, dl.Log)
+	if err != nil {
+		return err
+	}
+	if err := r.handlerFor(rt).OnEvent(c); err != nil {
+		return err
+	}
+	return r.markHandled(ctx, rt, dl.Log)
+}
+
+func (r *Router) markHandled(ctx context.Context, rt *Route, lg types.Log) error {
+	if r.DedupeStore != nil {
+		r.DedupeStore.MarkHandled(lg, rt.ID)
+	}
+	return r.addBlockLog(ctx, lg)
+}
+
+func (r *Router) unclaim(rt *Route, lg types.Log) {
+	if r.DedupeStore != nil {
+		r.DedupeStore.Unclaim(lg, rt.ID)
+	}
+}
 
-	if routeTable[addr] == nil {
-		routeTable[addr] = map[common.Hash]*Route{}
+// addBlockLog 记录区块内已处理的日志，Scanner 据此检测重组
+func (r *Router) addBlockLog(ctx context.Context, lg types.Log) error {
+	if r.HashStore != nil && !lg.Removed {
+		return r.HashStore.AddBlockLog(ctx, r.Chain, lg.BlockNumber, lg)
 	}
-	routeTable[addr][topic] = rt
+	return nil
+}
+
+// handlerFor 构建 handlerChain (route 中间件 + 全局中间件)
+func (r *Router) handlerFor(rt *Route) EventHandler {
+	r.chainMu.Lock()
+	defer r.chainMu.Unlock()
+
+	if h, ok := r.chains[rt]; ok {
+		return h
+	}
+
+	handler := rt.Handler()
+	for i := len(r.Middlewares) - 1; i >= 0; i-- {
+		handler = r.Middlewares[i](handler)
+	}
+
+	if r.chains == nil {
+		r.chains = map[*Route]EventHandler{}
+	}
+	r.chains[rt] = handler
+	return handler
 }
 
-func FindRouteByAddressAndTopic(addr common.Address, topic common.Hash) *Route {
-	routeMu.RLock()
-	defer routeMu.RUnlock()
+// registerRoute 注册路由，匹配规则相同的路由追加序号区分 ID
+func (r *Registry) registerRoute(rt *Route) {
+	r.routeMu.Lock()
+	defer r.routeMu.Unlock()
+
+	id := routeID(rt.Chain, rt.Contract, rt.Template, rt.Events)
+	rt.ID = id
+	for n := 2; ; n++ {
+		if r.findRoute(rt.ID) == nil {
+			break
+		}
+		rt.ID = fmt.Sprintf("%s-%d", id, n)
+	}
+	r.routes = append(r.routes, rt)
+}
+
+func (r *Registry) unregisterRoute(rt *Route) {
+	r.routeMu.Lock()
+	defer r.routeMu.Unlock()
+
+	for i, v := range r.routes {
+		if v == rt {
+			r.routes = append(r.routes[:i:i], r.routes[i+1:]...)
+			return
+		}
+	}
+}
 
-	if m, ok := routeTable[addr]; ok {
-		if rt, ok := m[topic]; ok {
+// AllRoutes 返回全部已注册路由
+func (r *Registry) AllRoutes() []*Route {
+	r.routeMu.RLock()
+	defer r.routeMu.RUnlock()
+
+	return append([]*Route(nil), r.routes...)
+}
+
+// FindRoute 按 ID 查找路由
+func (r *Registry) FindRoute(id string) *Route {
+	r.routeMu.RLock()
+	defer r.routeMu.RUnlock()
+
+	return r.findRoute(id)
+}
+
+func (r *Registry) findRoute(id string) *Route {
+	for _, rt := range r.routes {
+		if rt.ID == id {
 			return rt
 		}
 	}
 	return nil
 }
+
+// FindRoutes 返回链上日志所属合约名以及命中的全部路由
+func (r *Registry) FindRoutes(chain string, lg types.Log) (string, []*Route) {
+	abiInfo, ev, err := r.ResolveLog(chain, lg)
+	if err != nil {
+		return "", nil
+	}
+
+	r.routeMu.RLock()
+	defer r.routeMu.RUnlock()
+
+	var routes []*Route
+	for _, rt := range r.routes {
+		if rt.Match(abiInfo, ev.Name) && rt.matchTopics(ev, lg) {
+			routes = append(routes, rt)
+		}
+	}
+	return abiInfo.ContractName, routes
+}
+
+// routesForContract 返回在链上指定合约上有命中事件的路由
+func (r *Registry) routesForContract(chain string, contract string) []*Route {
+	r.routeMu.RLock()
+	defer r.routeMu.RUnlock()
+
+	var routes []*Route
+	for _, rt := range r.routes {
+		if rt.Chain != chain {
+			continue
+		}
+		if _, err := routeQuery(rt, contract); err == nil {
+			routes = append(routes, rt)
+		}
+	}
+	return routes
+}
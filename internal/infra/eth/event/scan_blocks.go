@@ -2,41 +2,107 @@ package event
 
 import (
 	"context"
+	"errors"
 	"go-web3/internal/infra/eth"
+	"go-web3/internal/infra/eth/chainclient"
 	"log"
 	"math/big"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
 )
 
 type Scanner struct {
-	Client        *ethclient.Client
+	Client        chainclient.Client
 	BlockStore    BlockStore // 接口：获取、更新 lastProcessedBlock
 	DedupeStore   DedupeStore
-	Chain         string   // 例如: "sepolia"
-	Contracts     []string // 要扫描的合约名
-	ReorgDepth    uint64   //Reorg 回滚保护用的值
-	Confirmations uint64   //确认区块数
+	HashStore     BlockHashStore // 可选：记录区块 hash，开启重组检测与 Removed 事件回放
+	Router        *Router        // 可选：设置后事件经 Router 分发，与实时订阅共享去重和全局中间件
+	Registry      *Registry      // 合约 ABI 与路由表
+	Chain         string         // 例如: "sepolia"
+	Contracts     []string       // 要扫描的合约名
+	ReorgDepth    uint64         //Reorg 回滚保护用的值（开启 HashStore 时为最大回溯深度）
+	Confirmations uint64         //确认区块数
 	Logger        *log.Logger
+
+	// 自适应批量扫描：成功后扩大区间，节点返回结果过多等错误时二分缩小区间
+	MinRange       uint64        // 单次 FilterLogs 最小区块数，默认 1
+	MaxRange       uint64        // 单次 FilterLogs 最大区块数，默认 2000
+	RequestTimeout time.Duration // 单次 FilterLogs 超时，默认 15s
+
+	batch uint64 // 当前区间大小
+
+	contractsMu sync.RWMutex // 运行时增删 Contracts
 }
 
-func (s *Scanner) Start() {
-	ticker := time.NewTicker(2 * time.Second)
+// AddContract 运行时加入扫描的合约。checkpoint 落后于 startBlock 时从 startBlock 开始补扫（如工厂新建的子合约）
+func (s *Scanner) AddContract(ctx context.Context, name string, startBlock uint64) error {
+	s.contractsMu.Lock()
+	defer s.contractsMu.Unlock()
 
-	for range ticker.C {
-		if err := s.scanOnce(); err != nil {
-			s.Logger.Printf("scan error: %v", err)
+	for _, c := range s.Contracts {
+		if c == name {
+			return nil
+		}
+	}
+
+	if startBlock > 0 {
+		last, err := s.BlockStore.GetLastBlock(ctx, s.Chain, name)
+		if err != nil {
+			return err
+		}
+		if last < startBlock-1 {
+			if err := s.BlockStore.SetLastBlock(ctx, s.Chain, name, startBlock-1); err != nil {
+				return err
+			}
 		}
 	}
+
+	s.Contracts = append(s.Contracts, name)
+	return nil
 }
 
-func (s *Scanner) scanOnce() error {
-	ctx := context.Background()
+// RemoveContract 停止扫描合约，保留其 checkpoint
+func (s *Scanner) RemoveContract(name string) {
+	s.contractsMu.Lock()
+	defer s.contractsMu.Unlock()
 
-	maxRange := uint64(10)
+	for i, c := range s.Contracts {
+		if c == name {
+			s.Contracts = append(s.Contracts[:i:i], s.Contracts[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *Scanner) contracts() []string {
+	s.contractsMu.RLock()
+	defer s.contractsMu.RUnlock()
+
+	return append([]string(nil), s.Contracts...)
+}
+
+// Start 周期性扫描，阻塞直到 ctx 取消。取消时当前批次在日志边界停止，并把 checkpoint 刷新到已完整处理的区块
+func (s *Scanner) Start(ctx context.Context) {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.Logger.Println("block scanner stopped")
+			return
+		case <-ticker.C:
+			if err := s.scanOnce(ctx); err != nil && ctx.Err() == nil {
+				s.Logger.Printf("scan error: %v", err)
+			}
+		}
+	}
+}
+
+func (s *Scanner) scanOnce(ctx context.Context) error {
 
 	latest, err := s.Client.BlockNumber(ctx)
 	if err != nil {
@@ -49,8 +115,15 @@ func (s *Scanner) scanOnce() error {
 	}
 	targetEnd := latest - s.Confirmations
 
+	// 先处理链重组：回滚孤块上已处理的事件，并把 lastBlock 回退到共同祖先
+	if s.HashStore != nil {
+		if err := s.handleReorg(ctx); err != nil {
+			return err
+		}
+	}
+
 	// 遍历每个合约独立存 lastBlock
-	for _, contract := range s.Contracts {
+	for _, contract := range s.contracts() {
 		// 1. 获取上次扫描高度
 		last, err := s.BlockStore.GetLastBlock(ctx, s.Chain, contract)
 		if err != nil {
@@ -59,88 +132,144 @@ func (s *Scanner) scanOnce() error {
 
 		// 2. 计算 start（含 Reorg 回退）
 		start := uint64(0)
-		if last > s.ReorgDepth {
+		if s.HashStore != nil {
+			// 重组由 hash 校验处理，无需固定回退
+			start = last + 1
+		} else if last > s.ReorgDepth {
 			start = last - s.ReorgDepth
 		}
 
-		if targetEnd <= start {
+		if targetEnd < start {
+			continue
+		}
+
+		// 该合约上所有路由的查询条件合并为一次扫描
+		var queries []ethereum.FilterQuery
+		for _, rt := range s.Registry.routesForContract(s.Chain, contract) {
+			q, err := routeQuery(rt, contract)
+			if err != nil {
+				return err
+			}
+			queries = append(queries, q)
+		}
+		if len(queries) == 0 {
+			// 没有路由关心该合约，直接推进 checkpoint
+			if err := s.BlockStore.SetLastBlock(ctx, s.Chain, contract, targetEnd); err != nil {
+				return err
+			}
 			continue
 		}
+		query := mergeQueries(queries)
 
 		s.Logger.Printf("[%s] scan blocks %d → %d", contract, start, targetEnd)
 
 		// 3. 扫描事件
 
-		for from := start; from <= targetEnd; from += maxRange {
-			to := from + maxRange - 1
+		for from := start; from <= targetEnd; {
+			to := from + s.rangeSize() - 1
 			if to > targetEnd {
 				to = targetEnd
 			}
 
 			s.Logger.Printf("  - batch %d → %d", from, to)
 
-			logs, err := s.fetchLogs(ctx, from, to)
+			logs, err := s.fetchLogs(ctx, query, from, to)
 			if err != nil {
+				// 区间过大：缩小区间后重试当前批次
+				if ctx.Err() == nil && IsLogRangeError(err) && to > from {
+					s.shrinkRange(to - from + 1)
+					s.Logger.Printf("  - range too large, shrink to %d: %v", s.batch, err)
+					continue
+				}
 				return err
 			}
+			s.growRange()
 
 			eth.SortLogs(logs)
 			// 4. 处理事件
 			for _, lg := range logs {
-				route := FindRouteByAddressAndTopic(lg.Address, lg.Topics[0])
-				if route == nil || route.Contract != contract {
+				owner, routes := s.Registry.FindRoutes(s.Chain, lg)
+				if owner != contract {
 					continue
 				}
 
-				if err := s.handleLog(ctx, lg, route); err != nil {
-					s.Logger.Printf("handler error: %v", err)
+				// 停止时把 checkpoint 刷新到上一个完整处理的区块，未处理完的区块下次重扫（已处理的日志由去重跳过）
+				if ctx.Err() != nil {
+					if lg.BlockNumber-1 > last {
+						if err := s.BlockStore.SetLastBlock(context.WithoutCancel(ctx), s.Chain, contract, lg.BlockNumber-1); err != nil {
+							return err
+						}
+					}
+					return ctx.Err()
+				}
+
+				// 扇出到全部命中的路由；在途的处理器不因停止而中断
+				for _, route := range routes {
+					if err := s.handleLog(context.WithoutCancel(ctx), lg, route); err != nil {
+						s.Logger.Printf("[%s] handler error: %v", route.ID, err)
+					}
 				}
 			}
-		}
 
-		// 5. 单独为该合约更新 lastBlock
-		if err := s.BlockStore.SetLastBlock(ctx, s.Chain, contract, targetEnd); err != nil {
-			return err
+			// 5. 每批次单独为该合约更新 lastBlock，追块中断后不必从头开始
+			if err := s.BlockStore.SetLastBlock(context.WithoutCancel(ctx), s.Chain, contract, to); err != nil {
+				return err
+			}
+			from = to + 1
 		}
+	}
 
+	// 6. 记录重组窗口内的区块 hash，供下一轮检测
+	if s.HashStore != nil {
+		return s.trackBlocks(ctx, targetEnd)
 	}
 	return nil
 }
 
 // 扫描区间日志
-func (s *Scanner) fetchLogs(ctx context.Context, start, end uint64) ([]types.Log, error) {
-	query := ethereum.FilterQuery{
-		FromBlock: new(big.Int).SetUint64(start),
-		ToBlock:   new(big.Int).SetUint64(end),
-	}
+func (s *Scanner) fetchLogs(ctx context.Context, query ethereum.FilterQuery, start, end uint64) ([]types.Log, error) {
+	query.FromBlock = new(big.Int).SetUint64(start)
+	query.ToBlock = new(big.Int).SetUint64(end)
 
-	// 添加所有注册过的合约地址
-	query.Addresses = AllWatchedAddresses()
+	ctx, cancel := context.WithTimeout(ctx, s.requestTimeout())
+	defer cancel()
 
 	return s.Client.FilterLogs(ctx, query)
 }
 
 // 处理事件（含路由 + BindEvent）
 func (s *Scanner) handleLog(ctx context.Context, lg types.Log, route *Route) error {
-	abiInfo, _ := GetABIByContract(route.Contract)
-
-	c := &Context{
-		Ctx:          ctx,
-		Client:       s.Client,
-		Log:          lg,
-		ContractName: route.Contract,
-		EventName:    route.Event,
-		ABIInfo:      abiInfo,
-		ABIEventUnpack: func(out interface{}, log types.Log) error {
-			return abiInfo.ABI.UnpackIntoInterface(out, route.Event, log.Data)
-		},
-		Logger: s.Logger,
+	if s.Router != nil {
+		return s.Router.Dispatch(ctx, route, lg)
 	}
 
-	if s.DedupeStore.AlreadyHandled(lg) {
+	claimed, err := s.DedupeStore.Claim(lg, route.ID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
 		return nil
 	}
-	s.DedupeStore.MarkHandled(lg)
 
-	return route.Handler().OnEvent(c)
+	c, err := newContext(ctx, s.Client, s.Logger, route, lg)
+	if err == nil {
+		err = route.Handler().OnEvent(c)
+	}
+	// 处理成功后才标记为已处理，失败（含转入死信队列）时释放占用
+	if err != nil {
+		s.DedupeStore.Unclaim(lg, route.ID)
+		if !errors.Is(err, ErrDeadLettered) {
+			return err
+		}
+	} else {
+		s.DedupeStore.MarkHandled(lg, route.ID)
+	}
+
+	// 记录区块内已处理的日志，重组时据此投递 Removed 事件
+	if s.HashStore != nil && !lg.Removed {
+		if hashErr := s.HashStore.AddBlockLog(ctx, s.Chain, lg.BlockNumber, lg); hashErr != nil {
+			return hashErr
+		}
+	}
+	return err
 }
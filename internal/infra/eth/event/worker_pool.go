@@ -0,0 +1,96 @@
+package event
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// PartitionFunc 计算事件的分区 key。相同 key 的事件按到达顺序串行处理，不同 key 并行处理
+type PartitionFunc func(lg types.Log) string
+
+// PartitionByContractTopic 按合约地址 + 第一个 indexed 参数分区（如同一拍卖 auctionId 的事件保持顺序）
+func PartitionByContractTopic(lg types.Log) string {
+	if len(lg.Topics) > 1 {
+		return lg.Address.Hex() + ":" + lg.Topics[1].Hex()
+	}
+	return lg.Address.Hex()
+}
+
+// PartitionByTxHash 按交易分区，同一交易内的事件保持顺序
+func PartitionByTxHash(lg types.Log) string {
+	return lg.TxHash.Hex()
+}
+
+const (
+	defaultWorkers   = 8
+	defaultQueueSize = 256
+)
+
+// WorkerPool 固定数量的 worker，每个 worker 一个有界队列。
+// key 哈希到固定 worker 保证同 key 有序；队列满时 Submit 阻塞，向订阅端施加背压
+type WorkerPool struct {
+	queues []chan func()
+	wg     sync.WaitGroup
+}
+
+func NewWorkerPool(workers, queueSize int) *WorkerPool {
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+
+	p := &WorkerPool{queues: make([]chan func(), workers)}
+	for i := range p.queues {
+		q := make(chan func(), queueSize)
+		p.queues[i] = q
+
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for task := range q {
+				task()
+			}
+		}()
+	}
+	return p
+}
+
+// Submit 把任务放入 key 对应 worker 的队列，队列满时阻塞直到 ctx 取消
+func (p *WorkerPool) Submit(ctx context.Context, key string, task func()) error {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+
+	select {
+	case p.queues[h.Sum32()%uint32(len(p.queues))] <- task:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown 停止接收任务，并在 timeout 内等待已入队的任务执行完毕。超时返回 false
+// 调用前必须确保不再有 Submit
+func (p *WorkerPool) Shutdown(timeout time.Duration) bool {
+	for _, q := range p.queues {
+		close(q)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
@@ -2,110 +2,371 @@ package nonce
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"go-web3/internal/infra/eth/chainclient"
+	"go-web3/internal/infra/redis/lock"
 	"log"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/redis/go-redis/v9"
 )
 
+// NonceManager 按链隔离：同一地址在不同链上的 nonce 互不影响。
+// nonce 先预留（Reserve），交易广播后提交（Commit），未广播则释放（Release）供下一笔交易复用；
+// 预留与已广播的 nonce 记录在 in-flight 集合中，Reconcile 与链上状态对账并找出需要填补的空洞
 type NonceManager struct {
-	redis  *redis.Client
-	client *ethclient.Client
+	redis   *redis.Client
+	client  chainclient.Client
+	chainID uint64
+	locker  *lock.Locker
+
+	// ReserveTimeout 预留或广播后超过该时间仍未在节点上出现的 nonce 视为空洞，默认 2m
+	ReserveTimeout time.Duration
 }
 
-func NewNonceManager(redis *redis.Client, client *ethclient.Client) *NonceManager {
+func NewNonceManager(redis *redis.Client, client chainclient.Client, chainID uint64) *NonceManager {
 	return &NonceManager{
-		redis:  redis,
-		client: client,
+		redis:   redis,
+		client:  client,
+		chainID: chainID,
+		locker:  lock.NewLocker(redis),
 	}
 }
 
+// State in-flight nonce 的状态
+type State string
+
+const (
+	StateReserved State = "reserved" // 已分配，交易尚未广播
+	StateSent     State = "sent"     // 交易已广播，未打包
+	StateReleased State = "released" // 交易未广播，等待复用或填补
+)
+
+// InFlight 已分配但未打包的 nonce
+type InFlight struct {
+	Nonce     uint64      `json:"nonce"`
+	State     State       `json:"state"`
+	Hash      common.Hash `json:"hash,omitempty"`
+	UpdatedAt time.Time   `json:"updatedAt"`
+}
+
 // redis key
 func (nm *NonceManager) nonceKey(addr common.Address) string {
-	return fmt.Sprintf("nonce_%s", addr.Hex())
+	return fmt.Sprintf("nonce_%d_%s", nm.chainID, addr.Hex())
+}
+
+// in-flight nonce 的 redis hash key，field 为 nonce
+func (nm *NonceManager) inFlightKey(addr common.Address) string {
+	return fmt.Sprintf("nonce_inflight_%d_%s", nm.chainID, addr.Hex())
 }
 
 // redis 锁 key
 func (nm *NonceManager) lockKey(addr common.Address) string {
-	return fmt.Sprintf("nonce_lock_%s", addr.Hex())
+	return fmt.Sprintf("nonce_lock_%d_%s", nm.chainID, addr.Hex())
+}
+
+// withLock 持有地址的分布式锁执行 fn。持有期间锁丢失时返回 lock.ErrLost，本次分配的 nonce 不可使用
+func (nm *NonceManager) withLock(ctx context.Context, addr common.Address, fn func() error) error {
+	lk, err := nm.locker.Acquire(ctx, nm.lockKey(addr))
+	if err != nil {
+		return err
+	}
+	err = fn()
+	if releaseErr := lk.Release(ctx); releaseErr != nil && err == nil {
+		return fmt.Errorf("nonce lock %s: %w", addr.Hex(), releaseErr)
+	}
+	return err
 }
 
-// 尝试加锁
-func (nm *NonceManager) acquireLock(ctx context.Context, key string) error {
-	for {
-		ok, err := nm.redis.SetNX(ctx, key, "1", 3*time.Second).Result()
+// LockStats 地址锁的竞争统计
+func (nm *NonceManager) LockStats() lock.Stats {
+	return nm.locker.Stats()
+}
+
+// Reservation 预留的 nonce。交易广播后调用 Commit，否则调用 Release 归还；Commit 之后 Release 不生效，可以直接 defer
+type Reservation struct {
+	Nonce uint64
+
+	nm   *NonceManager
+	addr common.Address
+	done bool
+}
+
+// Reserve 为地址预留下一个 nonce：优先复用已释放的最小 nonce，否则取计数器
+func (nm *NonceManager) Reserve(ctx context.Context, addr common.Address) (*Reservation, error) {
+	var n uint64
+	err := nm.withLock(ctx, addr, func() error {
+		entries, err := nm.inFlight(ctx, addr)
 		if err != nil {
 			return err
 		}
-		if ok {
-			return nil // 获得锁
+
+		// 1. 复用已释放的 nonce。已被其他交易占用的（低于节点 pending nonce）直接移除
+		var released []uint64
+		for _, e := range entries {
+			if e.State == StateReleased {
+				released = append(released, e.Nonce)
+			}
+		}
+		if len(released) > 0 {
+			pending, err := nm.client.PendingNonceAt(ctx, addr)
+			if err != nil {
+				return err
+			}
+			for _, r := range released {
+				if r < pending {
+					if err := nm.redis.HDel(ctx, nm.inFlightKey(addr), strconv.FormatUint(r, 10)).Err(); err != nil {
+						return err
+					}
+					continue
+				}
+				n = r
+				return nm.setInFlight(ctx, addr, InFlight{Nonce: n, State: StateReserved})
+			}
 		}
 
-		// 锁被占用，稍后重试
-		time.Sleep(30 * time.Millisecond)
+		// 2. 取计数器
+		next, err := nm.counter(ctx, addr, entries)
+		if err != nil {
+			return err
+		}
+		n = next
+		if err := nm.redis.Set(ctx, nm.nonceKey(addr), n+1, 0).Err(); err != nil {
+			return err
+		}
+		return nm.setInFlight(ctx, addr, InFlight{Nonce: n, State: StateReserved})
+	})
+	if err != nil {
+		return nil, err
 	}
+	return &Reservation{Nonce: n, nm: nm, addr: addr}, nil
 }
 
-// 释放分布式锁
-func (nm *NonceManager) releaseLock(ctx context.Context, key string) {
-	nm.redis.Del(ctx, key)
+// Commit 交易已广播，记录交易 hash
+func (r *Reservation) Commit(ctx context.Context, hash common.Hash) error {
+	if r.done {
+		return nil
+	}
+	r.done = true
+	return r.nm.MarkSent(ctx, r.addr, r.Nonce, hash)
 }
 
-// GetNextNonce 获取账户地址 nonce
-func (nm *NonceManager) GetNextNonce(ctx context.Context, addr common.Address) (uint64, error) {
-	lock := nm.lockKey(addr)
-
-	if err := nm.acquireLock(ctx, lock); err != nil {
-		return 0, err
+// Release 交易未广播，归还 nonce：是最后分配的 nonce 时回退计数器，否则留给下一笔交易复用
+func (r *Reservation) Release(ctx context.Context) error {
+	if r.done {
+		return nil
 	}
-	defer nm.releaseLock(ctx, lock)
+	r.done = true
 
-	key := nm.nonceKey(addr)
+	nm, addr := r.nm, r.addr
+	return nm.withLock(ctx, addr, func() error {
+		if err := nm.setInFlight(ctx, addr, InFlight{Nonce: r.Nonce, State: StateReleased}); err != nil {
+			return err
+		}
+		entries, err := nm.inFlight(ctx, addr)
+		if err != nil {
+			return err
+		}
+		return nm.trimReleased(ctx, addr, entries)
+	})
+}
+
+// MarkSent 记录 nonce 上已广播的交易，如加速、取消或填补空洞的交易
+func (nm *NonceManager) MarkSent(ctx context.Context, addr common.Address, nonce uint64, hash common.Hash) error {
+	return nm.setInFlight(ctx, addr, InFlight{Nonce: nonce, State: StateSent, Hash: hash})
+}
 
-	// 1. 尝试从 Redis 取 nonce
-	val, err := nm.redis.Get(ctx, key).Result()
+// InFlight 地址已分配但未打包的 nonce，按 nonce 升序
+func (nm *NonceManager) InFlight(ctx context.Context, addr common.Address) ([]InFlight, error) {
+	return nm.inFlight(ctx, addr)
+}
 
-	if errors.Is(err, redis.Nil) {
-		// Redis 中没有 → 从链上初始化
+// Reconcile 与链上状态对账：移除已打包的 nonce，计数器落后于节点时追上，计数器末尾已释放的 nonce 回退。
+// 返回需要填补的空洞：节点上没有交易、且不在预留或刚广播的超时时间内的 nonce，它们会阻塞后续交易。
+// 返回的 nonce 重新标记为预留，填补交易广播后调用 MarkSent
+func (nm *NonceManager) Reconcile(ctx context.Context, addr common.Address) ([]uint64, error) {
+	var gaps []uint64
+	err := nm.withLock(ctx, addr, func() error {
+		latest, err := nm.client.NonceAt(ctx, addr, nil)
+		if err != nil {
+			return err
+		}
 		pending, err := nm.client.PendingNonceAt(ctx, addr)
 		if err != nil {
-			return 0, err
+			return err
+		}
+		entries, err := nm.inFlight(ctx, addr)
+		if err != nil {
+			return err
 		}
 
-		// 保存下一次要用的 nonce（pending+1）
-		err = nm.redis.Set(ctx, key, pending+1, 0).Err()
+		// 1. 已打包的 nonce 不再跟踪
+		live := entries[:0]
+		for _, e := range entries {
+			if e.Nonce < latest {
+				if err := nm.redis.HDel(ctx, nm.inFlightKey(addr), strconv.FormatUint(e.Nonce, 10)).Err(); err != nil {
+					return err
+				}
+				continue
+			}
+			live = append(live, e)
+		}
+
+		// 2. 其他程序用同一地址发过交易时计数器追上节点
+		next, err := nm.counter(ctx, addr, live)
 		if err != nil {
-			return 0, err
+			return err
+		}
+		if next < pending {
+			log.Printf("nonce counter %d behind pending %d for %s on chain %d, sync", next, pending, addr.Hex(), nm.chainID)
+			next = pending
+		}
+		if err := nm.redis.Set(ctx, nm.nonceKey(addr), next, 0).Err(); err != nil {
+			return err
+		}
+		if err := nm.trimReleased(ctx, addr, live); err != nil {
+			return err
+		}
+		if next, err = nm.counter(ctx, addr, live); err != nil {
+			return err
+		}
+
+		// 3. [pending, next) 中节点上没有交易的 nonce
+		byNonce := map[uint64]InFlight{}
+		for _, e := range live {
+			byNonce[e.Nonce] = e
+		}
+		for n := pending; n < next; n++ {
+			e, ok := byNonce[n]
+			if ok && !nm.isGap(ctx, e) {
+				continue
+			}
+			gaps = append(gaps, n)
+		}
+
+		// 4. 末尾的空洞后面没有交易，回退计数器即可，不需要填补
+		for len(gaps) > 0 && gaps[len(gaps)-1] == next-1 {
+			next--
+			gaps = gaps[:len(gaps)-1]
+			if err := nm.redis.HDel(ctx, nm.inFlightKey(addr), strconv.FormatUint(next, 10)).Err(); err != nil {
+				return err
+			}
+		}
+		if err := nm.redis.Set(ctx, nm.nonceKey(addr), next, 0).Err(); err != nil {
+			return err
+		}
+		for _, n := range gaps {
+			if err := nm.setInFlight(ctx, addr, InFlight{Nonce: n, State: StateReserved}); err != nil {
+				return err
+			}
 		}
+		return nil
+	})
+	return gaps, err
+}
 
-		return pending, nil
+// isGap 在节点 pending nonce 之后的 in-flight nonce 是否需要填补
+func (nm *NonceManager) isGap(ctx context.Context, e InFlight) bool {
+	if time.Since(e.UpdatedAt) < nm.reserveTimeout() {
+		return false
 	}
+	if e.State == StateSent {
+		// 前面有空洞时交易在节点的 queued 队列中，不计入 pending nonce
+		if _, _, err := nm.client.TransactionByHash(ctx, e.Hash); err == nil {
+			return false
+		}
+	}
+	return true
+}
 
+// trimReleased 计数器末尾连续已释放的 nonce 回退给计数器，调用方持有锁
+func (nm *NonceManager) trimReleased(ctx context.Context, addr common.Address, entries []InFlight) error {
+	next, err := nm.counter(ctx, addr, entries)
 	if err != nil {
+		return err
+	}
+	states := map[uint64]State{}
+	for _, e := range entries {
+		states[e.Nonce] = e.State
+	}
+
+	trimmed := next
+	for trimmed > 0 && states[trimmed-1] == StateReleased {
+		trimmed--
+		if err := nm.redis.HDel(ctx, nm.inFlightKey(addr), strconv.FormatUint(trimmed, 10)).Err(); err != nil {
+			return err
+		}
+	}
+	if trimmed == next {
+		return nil
+	}
+	return nm.redis.Set(ctx, nm.nonceKey(addr), trimmed, 0).Err()
+}
+
+// counter 下一个未分配的 nonce。Redis 中没有时从链上初始化，且不低于已分配的 nonce
+func (nm *NonceManager) counter(ctx context.Context, addr common.Address, entries []InFlight) (uint64, error) {
+	val, err := nm.redis.Get(ctx, nm.nonceKey(addr)).Result()
+	if err == nil {
+		return strconv.ParseUint(val, 10, 64)
+	}
+	if !errors.Is(err, redis.Nil) {
 		return 0, err
 	}
 
-	// 2. Redis 已有 nonce
-	nonce, err := strconv.ParseUint(val, 10, 64)
+	// Redis 中没有 → 从链上初始化
+	next, err := nm.client.PendingNonceAt(ctx, addr)
 	if err != nil {
 		return 0, err
 	}
+	for _, e := range entries {
+		if e.Nonce >= next {
+			next = e.Nonce + 1
+		}
+	}
+	return next, nil
+}
 
-	// 存入下一次的 nonce
-	err = nm.redis.Set(ctx, key, nonce+1, time.Minute*5).Err()
+func (nm *NonceManager) inFlight(ctx context.Context, addr common.Address) ([]InFlight, error) {
+	raw, err := nm.redis.HGetAll(ctx, nm.inFlightKey(addr)).Result()
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	entries := make([]InFlight, 0, len(raw))
+	for _, v := range raw {
+		var e InFlight
+		if err := json.Unmarshal([]byte(v), &e); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
 	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Nonce < entries[j].Nonce })
+	return entries, nil
+}
+
+func (nm *NonceManager) setInFlight(ctx context.Context, addr common.Address, e InFlight) error {
+	e.UpdatedAt = time.Now()
+	buf, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return nm.redis.HSet(ctx, nm.inFlightKey(addr), strconv.FormatUint(e.Nonce, 10), buf).Err()
+}
 
-	return nonce, nil
+func (nm *NonceManager) reserveTimeout() time.Duration {
+	if nm.ReserveTimeout > 0 {
+		return nm.ReserveTimeout
+	}
+	return 2 * time.Minute
 }
 
+// IsNonceError 节点因 nonce 冲突拒绝交易，同步 nonce 后重新签名发送。
+// already known 表示节点已有同一笔交易，不属于 nonce 冲突，重新签名会让同一调用上链两次
 func IsNonceError(err error) bool {
 	if err == nil {
 		return false
@@ -118,16 +379,35 @@ func IsNonceError(err error) bool {
 	return strings.Contains(msg, "nonce too low") ||
 		strings.Contains(msg, "nonce too high") ||
 		strings.Contains(msg, "replacement transaction underpriced") ||
-		strings.Contains(msg, "already known") ||
 		strings.Contains(msg, "transaction underpriced")
 }
 
+// ForceSyncNonce 计数器同步为节点的 pending nonce，但不低于仍在途的 nonce
 func (nm *NonceManager) ForceSyncNonce(ctx context.Context, addr common.Address) error {
-	pending, err := nm.client.PendingNonceAt(ctx, addr)
-	if err != nil {
-		return err
-	}
+	return nm.withLock(ctx, addr, func() error {
+		pending, err := nm.client.PendingNonceAt(ctx, addr)
+		if err != nil {
+			return err
+		}
+		entries, err := nm.inFlight(ctx, addr)
+		if err != nil {
+			return err
+		}
 
-	key := nm.nonceKey(addr)
-	return nm.redis.Set(ctx, key, pending, 0).Err()
+		next := pending
+		for _, e := range entries {
+			if e.State != StateReleased && e.Nonce >= next {
+				next = e.Nonce + 1
+			}
+		}
+		// 低于 pending 的已被占用，不低于计数器的由计数器重新分配
+		for _, e := range entries {
+			if e.State == StateReleased && (e.Nonce < pending || e.Nonce >= next) {
+				if err := nm.redis.HDel(ctx, nm.inFlightKey(addr), strconv.FormatUint(e.Nonce, 10)).Err(); err != nil {
+					return err
+				}
+			}
+		}
+		return nm.redis.Set(ctx, nm.nonceKey(addr), next, 0).Err()
+	})
 }
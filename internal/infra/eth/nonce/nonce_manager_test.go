@@ -0,0 +1,283 @@
+package nonce
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"go-web3/internal/infra/eth/chainclient/chaintest"
+	"math/big"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/redis/go-redis/v9"
+)
+
+// nonceEnv 模拟链 + 内存 Redis 上的 NonceManager，from 有余额可直接发交易
+type nonceEnv struct {
+	sim  *chaintest.Simulated
+	nm   *NonceManager
+	key  *ecdsa.PrivateKey
+	from common.Address
+}
+
+func newNonceEnv(t *testing.T) *nonceEnv {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	sim := chaintest.NewSimulated(types.GenesisAlloc{from: {Balance: big.NewInt(1e18)}})
+	t.Cleanup(func() { _ = sim.Close() })
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	return &nonceEnv{sim: sim, nm: NewNonceManager(rdb, sim, 1337), key: key, from: from}
+}
+
+func (e *nonceEnv) reserve(t *testing.T) *Reservation {
+	t.Helper()
+	res, err := e.nm.Reserve(context.Background(), e.from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+// send 绕过 NonceManager 直接用指定 nonce 发一笔转账，返回交易 hash
+func (e *nonceEnv) send(t *testing.T, nonce uint64) common.Hash {
+	t.Helper()
+	tx, err := types.SignTx(types.NewTransaction(nonce, e.from, big.NewInt(0), 21000, big.NewInt(10e9), nil),
+		types.LatestSignerForChainID(big.NewInt(1337)), e.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.sim.SendTransaction(context.Background(), tx); err != nil {
+		t.Fatalf("send nonce %d: %v", nonce, err)
+	}
+	return tx.Hash()
+}
+
+func (e *nonceEnv) counter(t *testing.T) uint64 {
+	t.Helper()
+	val, err := e.nm.redis.Get(context.Background(), e.nm.nonceKey(e.from)).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := strconv.ParseUint(val, 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// states in-flight nonce → 状态
+func (e *nonceEnv) states(t *testing.T) map[uint64]State {
+	t.Helper()
+	entries, err := e.nm.InFlight(context.Background(), e.from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := map[uint64]State{}
+	for _, entry := range entries {
+		out[entry.Nonce] = entry.State
+	}
+	return out
+}
+
+func assertStates(t *testing.T, got, want map[uint64]State) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("in-flight = %v, want %v", got, want)
+	}
+}
+
+func TestReserveReusesReleasedNonce(t *testing.T) {
+	env := newNonceEnv(t)
+	ctx := context.Background()
+
+	r0, r1, r2 := env.reserve(t), env.reserve(t), env.reserve(t)
+	if r0.Nonce != 0 || r1.Nonce != 1 || r2.Nonce != 2 {
+		t.Fatalf("reserved %d, %d, %d, want 0, 1, 2", r0.Nonce, r1.Nonce, r2.Nonce)
+	}
+	// 中间的 nonce 释放后留给下一笔交易，计数器不变
+	if err := r1.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertStates(t, env.states(t), map[uint64]State{0: StateReserved, 1: StateReleased, 2: StateReserved})
+	if env.counter(t) != 3 {
+		t.Fatalf("counter = %d, want 3", env.counter(t))
+	}
+
+	if r := env.reserve(t); r.Nonce != 1 {
+		t.Fatalf("reserved %d, want released nonce 1", r.Nonce)
+	}
+	if r := env.reserve(t); r.Nonce != 3 {
+		t.Fatalf("reserved %d, want 3 from counter", r.Nonce)
+	}
+
+	// Commit 之后 Release 不生效
+	if err := r0.Commit(ctx, common.HexToHash("0x01")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r0.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := env.states(t)[0]; got != StateSent {
+		t.Fatalf("nonce 0 state = %s after commit and release, want sent", got)
+	}
+}
+
+func TestReleaseTrimsTrailingNonces(t *testing.T) {
+	env := newNonceEnv(t)
+	ctx := context.Background()
+
+	r0, r1, r2 := env.reserve(t), env.reserve(t), env.reserve(t)
+	if err := r1.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// 释放末尾的 2 后，连续已释放的 2、1 都回退给计数器
+	if err := r2.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertStates(t, env.states(t), map[uint64]State{0: StateReserved})
+	if env.counter(t) != 1 {
+		t.Fatalf("counter = %d, want 1", env.counter(t))
+	}
+
+	if err := r0.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertStates(t, env.states(t), map[uint64]State{})
+	if r := env.reserve(t); r.Nonce != 0 {
+		t.Fatalf("reserved %d, want 0", r.Nonce)
+	}
+}
+
+func TestReserveDropsReleasedBelowPending(t *testing.T) {
+	env := newNonceEnv(t)
+	ctx := context.Background()
+
+	r0 := env.reserve(t)
+	env.reserve(t)
+	if err := r0.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// 其他程序用同一地址占用了 nonce 0
+	env.send(t, 0)
+
+	if r := env.reserve(t); r.Nonce != 2 {
+		t.Fatalf("reserved %d, want 2: released nonce 0 is used on the node", r.Nonce)
+	}
+	if _, ok := env.states(t)[0]; ok {
+		t.Fatal("released nonce below pending still tracked")
+	}
+}
+
+// TestReconcileGaps 对账只把超时且节点上没有交易的 nonce 作为空洞，queued 交易不算空洞
+func TestReconcileGaps(t *testing.T) {
+	env := newNonceEnv(t)
+	ctx := context.Background()
+
+	// 0：已打包；1：广播结果丢失；2：在节点 queued 队列中（前面有空洞）；3：已释放在末尾
+	for n := uint64(0); n < 4; n++ {
+		env.reserve(t)
+	}
+	if err := env.nm.MarkSent(ctx, env.from, 0, env.send(t, 0)); err != nil {
+		t.Fatal(err)
+	}
+	env.sim.Commit()
+	if err := env.nm.MarkSent(ctx, env.from, 1, common.HexToHash("0xdead")); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.nm.MarkSent(ctx, env.from, 2, env.send(t, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.nm.setInFlight(ctx, env.from, InFlight{Nonce: 3, State: StateReleased}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 超时之内的 nonce 不是空洞
+	gaps, err := env.nm.Reconcile(ctx, env.from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gaps) != 0 {
+		t.Fatalf("gaps = %v before reserve timeout, want none", gaps)
+	}
+	assertStates(t, env.states(t), map[uint64]State{1: StateSent, 2: StateSent})
+	if env.counter(t) != 3 {
+		t.Fatalf("counter = %d, want 3 after trimming released nonce 3", env.counter(t))
+	}
+
+	env.nm.ReserveTimeout = time.Nanosecond
+	gaps, err = env.nm.Reconcile(ctx, env.from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gaps, []uint64{1}) {
+		t.Fatalf("gaps = %v, want [1]", gaps)
+	}
+	// 空洞重新标记为预留，等待填补交易
+	assertStates(t, env.states(t), map[uint64]State{1: StateReserved, 2: StateSent})
+	if env.counter(t) != 3 {
+		t.Fatalf("counter = %d, want 3", env.counter(t))
+	}
+}
+
+// TestReconcileAfterNodeReset 节点重置后 Redis 中的在途交易都不存在：末尾的空洞回退计数器，不需要填补
+func TestReconcileAfterNodeReset(t *testing.T) {
+	env := newNonceEnv(t)
+	ctx := context.Background()
+
+	for n := uint64(0); n < 3; n++ {
+		res := env.reserve(t)
+		if err := res.Commit(ctx, common.BigToHash(new(big.Int).SetUint64(n+1))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env.nm.ReserveTimeout = time.Nanosecond
+
+	gaps, err := env.nm.Reconcile(ctx, env.from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gaps) != 0 {
+		t.Fatalf("gaps = %v, want none", gaps)
+	}
+	assertStates(t, env.states(t), map[uint64]State{})
+	if env.counter(t) != 0 {
+		t.Fatalf("counter = %d, want 0", env.counter(t))
+	}
+	if r := env.reserve(t); r.Nonce != 0 {
+		t.Fatalf("reserved %d after reset, want 0", r.Nonce)
+	}
+}
+
+// TestReconcileCatchesUp 其他程序用同一地址发过交易，计数器追上节点
+func TestReconcileCatchesUp(t *testing.T) {
+	env := newNonceEnv(t)
+	ctx := context.Background()
+
+	if err := env.nm.redis.Set(ctx, env.nm.nonceKey(env.from), 0, 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	env.send(t, 0)
+	env.send(t, 1)
+	env.sim.Commit()
+
+	gaps, err := env.nm.Reconcile(ctx, env.from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gaps) != 0 {
+		t.Fatalf("gaps = %v, want none", gaps)
+	}
+	if r := env.reserve(t); r.Nonce != 2 {
+		t.Fatalf("reserved %d, want 2", r.Nonce)
+	}
+}
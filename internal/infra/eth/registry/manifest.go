@@ -0,0 +1,203 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ManifestVersion 当前支持的清单格式版本
+const ManifestVersion = 1
+
+// Manifest 合约清单：部署地址、ABI 等元数据，运维可替换清单切换部署而无需重新编译
+type Manifest struct {
+	Version   int            `json:"version"`
+	Revision  string         `json:"revision,omitempty"` // 部署批次标识，便于追溯
+	Contracts []ContractMeta `json:"contracts"`
+	Templates []TemplateMeta `json:"templates,omitempty"`
+}
+
+// ContractMeta 已部署合约
+type ContractMeta struct {
+	Name           string          `json:"name"`
+	Chain          string          `json:"chain"`
+	Address        string          `json:"address"`
+	ABI            json.RawMessage `json:"abi"`
+	DeployBlock    uint64          `json:"deployBlock"`
+	Implementation string          `json:"implementation,omitempty"` // 代理合约的实现地址，ABI 取实现合约的 ABI
+}
+
+// TemplateMeta 子合约模板（工厂合约创建的合约，地址运行时才知道）
+type TemplateMeta struct {
+	Name string          `json:"name"`
+	ABI  json.RawMessage `json:"abi"`
+}
+
+// Contract 校验、解析后的合约
+type Contract struct {
+	Name           string
+	Chain          string
+	Address        common.Address
+	ABI            abi.ABI
+	DeployBlock    uint64
+	Implementation common.Address // 非代理合约为零地址
+}
+
+// Template 校验、解析后的子合约模板
+type Template struct {
+	Name string
+	ABI  abi.ABI
+}
+
+// ParseManifest 解析 JSON 清单，未知字段视为错误，避免拼写错误被静默忽略
+func ParseManifest(data []byte) (*Manifest, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var m Manifest
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("parse contract manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// Validate 校验整个清单，一次返回全部问题
+func (m *Manifest) Validate() error {
+	_, _, err := m.Resolve()
+	return err
+}
+
+// Resolve 校验清单并解析出全部合约与模板
+func (m *Manifest) Resolve() ([]*Contract, []*Template, error) {
+	var errs []error
+	if m.Version != ManifestVersion {
+		errs = append(errs, fmt.Errorf("unsupported manifest version %d, want %d", m.Version, ManifestVersion))
+	}
+
+	var contracts []*Contract
+	names := map[string]bool{}
+	addrs := map[string]string{}
+	for i, meta := range m.Contracts {
+		c, err := meta.parse()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("contracts[%d]: %w", i, err))
+			continue
+		}
+
+		// 合约名在链内唯一，同一合约可部署在多条链上
+		nameKey := c.Chain + "/" + c.Name
+		if names[nameKey] {
+			errs = append(errs, fmt.Errorf("contracts[%d]: duplicate name %s on %s", i, c.Name, c.Chain))
+			continue
+		}
+		names[nameKey] = true
+
+		addrKey := c.Chain + ":" + c.Address.Hex()
+		if other, ok := addrs[addrKey]; ok {
+			errs = append(errs, fmt.Errorf("contracts[%d]: address %s already used by %s", i, c.Address.Hex(), other))
+			continue
+		}
+		addrs[addrKey] = c.Name
+
+		contracts = append(contracts, c)
+	}
+
+	var templates []*Template
+	templateNames := map[string]bool{}
+	for i, meta := range m.Templates {
+		if meta.Name == "" {
+			errs = append(errs, fmt.Errorf("templates[%d]: name is required", i))
+			continue
+		}
+		if templateNames[meta.Name] {
+			errs = append(errs, fmt.Errorf("templates[%d]: duplicate name %s", i, meta.Name))
+			continue
+		}
+		templateNames[meta.Name] = true
+
+		a, err := parseABI(meta.ABI)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("templates[%d] %s: %w", i, meta.Name, err))
+			continue
+		}
+		templates = append(templates, &Template{Name: meta.Name, ABI: a})
+	}
+
+	if len(errs) > 0 {
+		return nil, nil, fmt.Errorf("invalid contract manifest: %w", errors.Join(errs...))
+	}
+	return contracts, templates, nil
+}
+
+func (meta ContractMeta) parse() (*Contract, error) {
+	if meta.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	if meta.Chain == "" {
+		return nil, fmt.Errorf("%s: chain is required", meta.Name)
+	}
+
+	address, err := parseAddress(meta.Address)
+	if err != nil {
+		return nil, fmt.Errorf("%s: address: %w", meta.Name, err)
+	}
+
+	var impl common.Address
+	if meta.Implementation != "" {
+		if impl, err = parseAddress(meta.Implementation); err != nil {
+			return nil, fmt.Errorf("%s: implementation: %w", meta.Name, err)
+		}
+		if impl == address {
+			return nil, fmt.Errorf("%s: implementation equals proxy address", meta.Name)
+		}
+	}
+
+	a, err := parseABI(meta.ABI)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", meta.Name, err)
+	}
+
+	return &Contract{
+		Name:           meta.Name,
+		Chain:          meta.Chain,
+		Address:        address,
+		ABI:            a,
+		DeployBlock:    meta.DeployBlock,
+		Implementation: impl,
+	}, nil
+}
+
+func parseAddress(s string) (common.Address, error) {
+	if !common.IsHexAddress(s) {
+		return common.Address{}, fmt.Errorf("invalid address %q", s)
+	}
+	addr := common.HexToAddress(s)
+	if addr == (common.Address{}) {
+		return common.Address{}, errors.New("zero address")
+	}
+	return addr, nil
+}
+
+func parseABI(raw json.RawMessage) (abi.ABI, error) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return abi.ABI{}, errors.New("abi is required")
+	}
+	// 兼容以字符串形式内嵌的 ABI
+	if raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return abi.ABI{}, fmt.Errorf("abi: %w", err)
+		}
+		raw = json.RawMessage(s)
+	}
+
+	a, err := abi.JSON(bytes.NewReader(raw))
+	if err != nil {
+		return abi.ABI{}, fmt.Errorf("abi: %w", err)
+	}
+	return a, nil
+}
@@ -0,0 +1,147 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"go-web3/internal/config"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/redis/go-redis/v9"
+)
+
+// Registry 已加载的合约元数据，合约以 chain/合约名 为 key
+type Registry struct {
+	mu        sync.RWMutex
+	contracts map[string]*Contract
+	templates map[string]*Template
+	revision  string
+}
+
+func New() *Registry {
+	return &Registry{
+		contracts: map[string]*Contract{},
+		templates: map[string]*Template{},
+	}
+}
+
+// NewStore 按配置选择清单来源：文件或 Redis
+func NewStore(cfg config.ContractsConfig, rdb *redis.Client) (Store, error) {
+	switch cfg.Source {
+	case "redis":
+		return NewRedisStore(rdb, cfg.RedisKey), nil
+	default:
+		path := config.FindFile(cfg.File)
+		if path == "" {
+			return nil, errors.New("合约清单文件不存在: " + cfg.File)
+		}
+		return NewFileStore(path), nil
+	}
+}
+
+// Load 加载并校验清单，只保留 chains 上的合约（为空时保留全部）。校验失败时保留原有数据
+func (r *Registry) Load(ctx context.Context, store Store, chains ...string) error {
+	m, err := store.Load(ctx)
+	if err != nil {
+		return err
+	}
+	cs, ts, err := m.Resolve()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.contracts = make(map[string]*Contract, len(cs))
+	for _, c := range cs {
+		if len(chains) > 0 && !contains(chains, c.Chain) {
+			continue
+		}
+		r.contracts[c.Chain+"/"+c.Name] = c
+	}
+	r.templates = make(map[string]*Template, len(ts))
+	for _, t := range ts {
+		r.templates[t.Name] = t
+	}
+	r.revision = m.Revision
+	for _, chain := range chains {
+		log.Printf("加载合约清单成功: revision=%s, chain=%s, contracts=%d", m.Revision, chain, r.countLocked(chain))
+	}
+	return nil
+}
+
+func (r *Registry) countLocked(chain string) int {
+	n := 0
+	for _, c := range r.contracts {
+		if c.Chain == chain {
+			n++
+		}
+	}
+	return n
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Registry) Get(chain string, name string) (*Contract, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.contracts[chain+"/"+name]
+	if !ok {
+		return nil, errors.New("contract not found in manifest: " + chain + "/" + name)
+	}
+	return c, nil
+}
+
+// Address 合约地址（代理合约为代理地址）
+func (r *Registry) Address(chain string, name string) (common.Address, error) {
+	c, err := r.Get(chain, name)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return c.Address, nil
+}
+
+// All 按名称排序返回链上全部合约
+func (r *Registry) All(chain string) []*Contract {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]*Contract, 0, len(r.contracts))
+	for _, c := range r.contracts {
+		if c.Chain == chain {
+			list = append(list, c)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func (r *Registry) Templates() []*Template {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]*Template, 0, len(r.templates))
+	for _, t := range r.templates {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Revision 当前清单的部署批次标识
+func (r *Registry) Revision() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.revision
+}
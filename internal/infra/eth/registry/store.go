@@ -0,0 +1,83 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Store 合约清单存储
+type Store interface {
+	Load(ctx context.Context) (*Manifest, error)
+	Save(ctx context.Context, m *Manifest) error
+}
+
+// FileStore JSON 清单文件
+type FileStore struct {
+	Path string
+}
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{Path: path}
+}
+
+func (s *FileStore) Load(ctx context.Context) (*Manifest, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseManifest(data)
+}
+
+// Save 先写临时文件再重命名，避免写入中途失败留下半个清单
+func (s *FileStore) Save(ctx context.Context, m *Manifest) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := s.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.Path)
+}
+
+// RedisStore 清单以 JSON 字符串存在一个 key 下，多实例共享同一份部署信息
+type RedisStore struct {
+	rdb *redis.Client
+	key string
+}
+
+func NewRedisStore(rdb *redis.Client, key string) *RedisStore {
+	return &RedisStore{rdb: rdb, key: key}
+}
+
+func (s *RedisStore) Load(ctx context.Context) (*Manifest, error) {
+	data, err := s.rdb.Get(ctx, s.key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("contract manifest not found in redis key %s", s.key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseManifest(data)
+}
+
+func (s *RedisStore) Save(ctx context.Context, m *Manifest) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return s.rdb.Set(ctx, s.key, data, 0).Err()
+}
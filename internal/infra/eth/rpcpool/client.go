@@ -0,0 +1,246 @@
+package rpcpool
+
+import (
+	"context"
+	"go-web3/internal/infra/eth/chainclient"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/v2"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// 与 ethclient.Client 相同的调用方式，可直接用于合约绑定
+var _ chainclient.Client = (*Pool)(nil)
+var _ bind.ContractBackend = (*Pool)(nil)
+var _ bind.DeployBackend = (*Pool)(nil)
+
+// blockHead 指定区块的请求需要节点已同步到该区块
+func blockHead(number *big.Int) uint64 {
+	if number == nil || number.Sign() <= 0 || !number.IsUint64() {
+		return 0
+	}
+	return number.Uint64()
+}
+
+func (p *Pool) ChainID(ctx context.Context) (id *big.Int, err error) {
+	err = p.call(ctx, 0, func(_ *endpoint, c *ethclient.Client) error {
+		id, err = c.ChainID(ctx)
+		return err
+	})
+	return id, err
+}
+
+// BlockNumber 返回最新区块，不会低于池内已观察到的最高区块，落后节点不会让扫描进度回退
+func (p *Pool) BlockNumber(ctx context.Context) (n uint64, err error) {
+	err = p.call(ctx, 0, func(e *endpoint, c *ethclient.Client) error {
+		n, err = c.BlockNumber(ctx)
+		if err == nil {
+			p.observeHead(e, n)
+		}
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	if head := p.head.Load(); head > n {
+		n = head
+	}
+	return n, nil
+}
+
+func (p *Pool) HeaderByNumber(ctx context.Context, number *big.Int) (h *types.Header, err error) {
+	err = p.call(ctx, blockHead(number), func(_ *endpoint, c *ethclient.Client) error {
+		h, err = c.HeaderByNumber(ctx, number)
+		return err
+	})
+	return h, err
+}
+
+func (p *Pool) HeaderByHash(ctx context.Context, hash common.Hash) (h *types.Header, err error) {
+	err = p.call(ctx, 0, func(_ *endpoint, c *ethclient.Client) error {
+		h, err = c.HeaderByHash(ctx, hash)
+		return err
+	})
+	return h, err
+}
+
+func (p *Pool) BlockByNumber(ctx context.Context, number *big.Int) (b *types.Block, err error) {
+	err = p.call(ctx, blockHead(number), func(_ *endpoint, c *ethclient.Client) error {
+		b, err = c.BlockByNumber(ctx, number)
+		return err
+	})
+	return b, err
+}
+
+func (p *Pool) BalanceAt(ctx context.Context, account common.Address, number *big.Int) (b *big.Int, err error) {
+	err = p.call(ctx, blockHead(number), func(_ *endpoint, c *ethclient.Client) error {
+		b, err = c.BalanceAt(ctx, account, number)
+		return err
+	})
+	return b, err
+}
+
+func (p *Pool) NonceAt(ctx context.Context, account common.Address, number *big.Int) (n uint64, err error) {
+	err = p.call(ctx, blockHead(number), func(_ *endpoint, c *ethclient.Client) error {
+		n, err = c.NonceAt(ctx, account, number)
+		return err
+	})
+	return n, err
+}
+
+func (p *Pool) PendingNonceAt(ctx context.Context, account common.Address) (n uint64, err error) {
+	err = p.call(ctx, 0, func(_ *endpoint, c *ethclient.Client) error {
+		n, err = c.PendingNonceAt(ctx, account)
+		return err
+	})
+	return n, err
+}
+
+func (p *Pool) CodeAt(ctx context.Context, account common.Address, number *big.Int) (code []byte, err error) {
+	err = p.call(ctx, blockHead(number), func(_ *endpoint, c *ethclient.Client) error {
+		code, err = c.CodeAt(ctx, account, number)
+		return err
+	})
+	return code, err
+}
+
+func (p *Pool) PendingCodeAt(ctx context.Context, account common.Address) (code []byte, err error) {
+	err = p.call(ctx, 0, func(_ *endpoint, c *ethclient.Client) error {
+		code, err = c.PendingCodeAt(ctx, account)
+		return err
+	})
+	return code, err
+}
+
+func (p *Pool) CallContract(ctx context.Context, msg ethereum.CallMsg, number *big.Int) (out []byte, err error) {
+	err = p.call(ctx, blockHead(number), func(_ *endpoint, c *ethclient.Client) error {
+		out, err = c.CallContract(ctx, msg, number)
+		return err
+	})
+	return out, err
+}
+
+func (p *Pool) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (gas uint64, err error) {
+	err = p.call(ctx, 0, func(_ *endpoint, c *ethclient.Client) error {
+		gas, err = c.EstimateGas(ctx, msg)
+		return err
+	})
+	return gas, err
+}
+
+func (p *Pool) SuggestGasPrice(ctx context.Context) (price *big.Int, err error) {
+	err = p.call(ctx, 0, func(_ *endpoint, c *ethclient.Client) error {
+		price, err = c.SuggestGasPrice(ctx)
+		return err
+	})
+	return price, err
+}
+
+func (p *Pool) SuggestGasTipCap(ctx context.Context) (tip *big.Int, err error) {
+	err = p.call(ctx, 0, func(_ *endpoint, c *ethclient.Client) error {
+		tip, err = c.SuggestGasTipCap(ctx)
+		return err
+	})
+	return tip, err
+}
+
+func (p *Pool) FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (h *ethereum.FeeHistory, err error) {
+	err = p.call(ctx, blockHead(lastBlock), func(_ *endpoint, c *ethclient.Client) error {
+		h, err = c.FeeHistory(ctx, blockCount, lastBlock, rewardPercentiles)
+		return err
+	})
+	return h, err
+}
+
+// SendTransaction 节点故障时换节点重发同一笔已签名交易。前一个节点可能已经收到交易（超时、连接中断），
+// 重发时节点返回 already known 或 nonce too low，且能按哈希查到该交易时视为发送成功
+func (p *Pool) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	attempts := 0
+	return p.call(ctx, 0, func(_ *endpoint, c *ethclient.Client) error {
+		attempts++
+		err := c.SendTransaction(ctx, tx)
+		if err != nil && attempts > 1 && isKnownTxError(err) {
+			if _, _, lookupErr := c.TransactionByHash(ctx, tx.Hash()); lookupErr == nil {
+				return nil
+			}
+		}
+		return err
+	})
+}
+
+// isKnownTxError 节点已有该交易（交易池中或已打包）时可能返回的错误
+func isKnownTxError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "already known") ||
+		strings.Contains(msg, "known transaction") ||
+		strings.Contains(msg, "nonce too low")
+}
+
+func (p *Pool) TransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error) {
+	err = p.call(ctx, 0, func(_ *endpoint, c *ethclient.Client) error {
+		tx, isPending, err = c.TransactionByHash(ctx, hash)
+		return err
+	})
+	return tx, isPending, err
+}
+
+func (p *Pool) TransactionReceipt(ctx context.Context, hash common.Hash) (r *types.Receipt, err error) {
+	err = p.call(ctx, 0, func(_ *endpoint, c *ethclient.Client) error {
+		r, err = c.TransactionReceipt(ctx, hash)
+		return err
+	})
+	return r, err
+}
+
+// FilterLogs 只在已同步到 ToBlock 的节点上查询，避免落后节点返回不完整的日志
+func (p *Pool) FilterLogs(ctx context.Context, q ethereum.FilterQuery) (logs []types.Log, err error) {
+	err = p.call(ctx, blockHead(q.ToBlock), func(_ *endpoint, c *ethclient.Client) error {
+		logs, err = c.FilterLogs(ctx, q)
+		return err
+	})
+	return logs, err
+}
+
+// SubscribeFilterLogs 在最优节点上订阅，订阅出错时记为节点失败，调用方重新订阅时会切换到其他节点
+func (p *Pool) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	var sub ethereum.Subscription
+	err := p.call(ctx, 0, func(e *endpoint, c *ethclient.Client) error {
+		s, err := c.SubscribeFilterLogs(ctx, q, ch)
+		if err != nil {
+			return err
+		}
+		sub = p.watchSubscription(e, s)
+		return nil
+	})
+	return sub, err
+}
+
+// poolSubscription 转发订阅错误并记录到节点健康状态
+type poolSubscription struct {
+	ethereum.Subscription
+	errCh chan error
+}
+
+func (s *poolSubscription) Err() <-chan error {
+	return s.errCh
+}
+
+func (p *Pool) watchSubscription(e *endpoint, sub ethereum.Subscription) ethereum.Subscription {
+	ps := &poolSubscription{Subscription: sub, errCh: make(chan error, 1)}
+	go func() {
+		defer close(ps.errCh)
+		err, ok := <-sub.Err()
+		if !ok {
+			return
+		}
+		if err != nil && e.fail(err, p.opts.FailureThreshold) {
+			p.opts.Logger.Printf("endpoint %s unhealthy: %v", maskURL(e.url), err)
+		}
+		ps.errCh <- err
+	}()
+	return ps
+}
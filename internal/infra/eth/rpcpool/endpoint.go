@@ -0,0 +1,177 @@
+package rpcpool
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// 延迟与错误率的指数移动平均权重
+const ewmaWeight = 0.2
+
+// endpoint 单个 RPC 节点：连接、限流与健康状态
+type endpoint struct {
+	url     string
+	limiter *limiter
+
+	mu        sync.Mutex
+	client    *ethclient.Client // 断开后置空，下次使用或健康检查时重连
+	healthy   bool
+	failures  int           // 连续失败次数
+	latency   time.Duration // 平均延迟
+	errRate   float64       // 平均错误率
+	head      uint64        // 节点最近一次返回的最高区块
+	requests  uint64
+	errors    uint64
+	lastError string
+	lastCheck time.Time
+}
+
+// EndpointStats 节点状态，用于健康检查接口
+type EndpointStats struct {
+	URL       string    `json:"url"`
+	Healthy   bool      `json:"healthy"`
+	Head      uint64    `json:"head"`
+	LatencyMs int64     `json:"latencyMs"`
+	ErrorRate float64   `json:"errorRate"`
+	Requests  uint64    `json:"requests"`
+	Errors    uint64    `json:"errors"`
+	LastError string    `json:"lastError,omitempty"`
+	LastCheck time.Time `json:"lastCheck"`
+}
+
+func newEndpoint(rawURL string, rate float64, burst int) *endpoint {
+	return &endpoint{
+		url:     rawURL,
+		limiter: newLimiter(rate, burst),
+		healthy: true,
+	}
+}
+
+// conn 返回节点连接，未连接时重新拨号
+func (e *endpoint) conn(ctx context.Context) (*ethclient.Client, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.client != nil {
+		return e.client, nil
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+
+	client, err := ethclient.DialContext(dialCtx, e.url)
+	if err != nil {
+		return nil, err
+	}
+	e.client = client
+	return client, nil
+}
+
+// success 记录成功请求，返回节点是否由不健康恢复
+func (e *endpoint) success(latency time.Duration) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.requests++
+	e.failures = 0
+	e.errRate = e.errRate * (1 - ewmaWeight)
+	if e.latency == 0 {
+		e.latency = latency
+	} else {
+		e.latency = time.Duration(float64(e.latency)*(1-ewmaWeight) + float64(latency)*ewmaWeight)
+	}
+
+	recovered := !e.healthy
+	e.healthy = true
+	e.lastError = ""
+	return recovered
+}
+
+// fail 记录失败请求，连续失败达到阈值时标记为不健康并断开连接，返回节点是否由健康转为不健康
+func (e *endpoint) fail(err error, threshold int) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.requests++
+	e.errors++
+	e.failures++
+	e.errRate = e.errRate*(1-ewmaWeight) + ewmaWeight
+	e.lastError = err.Error()
+
+	if e.failures < threshold || !e.healthy {
+		return false
+	}
+	e.healthy = false
+	if e.client != nil {
+		e.client.Close()
+		e.client = nil
+	}
+	return true
+}
+
+func (e *endpoint) observeHead(n uint64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if n > e.head {
+		e.head = n
+	}
+}
+
+// score 越小越优先：平均延迟按错误率放大
+func (e *endpoint) score() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	latency := float64(e.latency)
+	if latency == 0 {
+		latency = float64(100 * time.Millisecond)
+	}
+	return latency * (1 + 10*e.errRate)
+}
+
+func (e *endpoint) state() (healthy bool, head uint64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.healthy, e.head
+}
+
+func (e *endpoint) stats() EndpointStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return EndpointStats{
+		URL:       maskURL(e.url),
+		Healthy:   e.healthy,
+		Head:      e.head,
+		LatencyMs: e.latency.Milliseconds(),
+		ErrorRate: e.errRate,
+		Requests:  e.requests,
+		Errors:    e.errors,
+		LastError: e.lastError,
+		LastCheck: e.lastCheck,
+	}
+}
+
+func (e *endpoint) close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.client != nil {
+		e.client.Close()
+		e.client = nil
+	}
+}
+
+// maskURL 节点地址路径中通常带 API Key，只保留协议与主机
+func maskURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "***"
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -0,0 +1,79 @@
+package rpcpool
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// limiter 令牌桶限流，rate 为每秒请求数，<=0 表示不限流
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(rate float64, burst int) *limiter {
+	if burst <= 0 {
+		burst = 1
+	}
+	return &limiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve 取一个令牌，返回还需等待的时间，0 表示可立即执行
+func (l *limiter) reserve(take bool) time.Duration {
+	if l.rate <= 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	if take {
+		// 预支令牌，等待结束后直接执行
+		l.tokens--
+	}
+	return wait
+}
+
+// allow 不等待，有令牌时返回 true
+func (l *limiter) allow() bool {
+	return l.reserve(false) == 0
+}
+
+// wait 阻塞到拿到令牌或 ctx 取消
+func (l *limiter) wait(ctx context.Context) error {
+	d := l.reserve(true)
+	if d == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
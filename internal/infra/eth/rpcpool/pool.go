@@ -0,0 +1,321 @@
+package rpcpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	dialTimeout  = 5 * time.Second
+	checkTimeout = 5 * time.Second
+)
+
+var (
+	errRateLimited = errors.New("rpc endpoint rate limited")
+	// ErrNoEndpoint 没有可用节点（全部不健康或落后于请求的区块）
+	ErrNoEndpoint = errors.New("no rpc endpoint available")
+)
+
+// Options 节点池参数
+type Options struct {
+	RateLimit        float64       // 每个节点每秒请求数，0 表示不限流
+	Burst            int           // 限流突发请求数，默认与 RateLimit 相同
+	MaxLag           uint64        // 落后池内最高区块超过该值的节点降级使用，默认 3
+	FailureThreshold int           // 连续失败多少次标记为不健康，默认 3
+	HealthInterval   time.Duration // 健康检查间隔，默认 10s
+	Logger           *log.Logger
+}
+
+// Pool 多个 RPC 节点组成的客户端：按健康状态、延迟与错误率选择节点，
+// 节点故障时自动切换并在后台重连；查询区块数据时跳过尚未同步到该区块的节点，
+// 避免落后节点返回空结果或回退的区块高度
+type Pool struct {
+	name      string
+	endpoints []*endpoint
+	opts      Options
+	head      atomic.Uint64 // 池内节点返回过的最高区块
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// New 创建节点池并启动健康检查。节点不可用时不会返回错误，后台持续重连
+func New(name string, urls []string, opts Options) (*Pool, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("rpc pool " + name + ": no endpoint configured")
+	}
+	if opts.MaxLag == 0 {
+		opts.MaxLag = 3
+	}
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = 3
+	}
+	if opts.HealthInterval <= 0 {
+		opts.HealthInterval = 10 * time.Second
+	}
+	if opts.Burst <= 0 {
+		opts.Burst = int(opts.RateLimit)
+	}
+	if opts.Logger == nil {
+		opts.Logger = log.New(os.Stdout, "[rpc-pool:"+name+"] ", log.LstdFlags)
+	}
+
+	p := &Pool{
+		name: name,
+		opts: opts,
+		stop: make(chan struct{}),
+	}
+	for _, u := range urls {
+		p.endpoints = append(p.endpoints, newEndpoint(u, opts.RateLimit, opts.Burst))
+	}
+
+	p.checkAll()
+	go p.healthLoop()
+	return p, nil
+}
+
+// Close 停止健康检查并断开全部节点
+func (p *Pool) Close() {
+	p.once.Do(func() {
+		close(p.stop)
+		for _, e := range p.endpoints {
+			e.close()
+		}
+	})
+}
+
+func (p *Pool) Name() string {
+	return p.name
+}
+
+// Stats 各节点状态
+func (p *Pool) Stats() []EndpointStats {
+	list := make([]EndpointStats, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		list = append(list, e.stats())
+	}
+	return list
+}
+
+// Head 池内节点返回过的最高区块
+func (p *Pool) Head() uint64 {
+	return p.head.Load()
+}
+
+func (p *Pool) observeHead(e *endpoint, n uint64) {
+	e.observeHead(n)
+	for {
+		cur := p.head.Load()
+		if n <= cur || p.head.CompareAndSwap(cur, n) {
+			return
+		}
+	}
+}
+
+func (p *Pool) healthLoop() {
+	ticker := time.NewTicker(p.opts.HealthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.checkAll()
+		}
+	}
+}
+
+func (p *Pool) checkAll() {
+	var wg sync.WaitGroup
+	for _, e := range p.endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.check(e)
+		}()
+	}
+	wg.Wait()
+}
+
+// check 探测节点最新区块，不健康的节点在此重连
+func (p *Pool) check(e *endpoint) {
+	// 限流中的节点跳过本轮，不占用业务请求配额
+	if !e.limiter.allow() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+
+	e.mu.Lock()
+	e.lastCheck = time.Now()
+	e.mu.Unlock()
+
+	client, err := e.conn(ctx)
+	if err == nil {
+		start := time.Now()
+		var n uint64
+		if n, err = client.BlockNumber(ctx); err == nil {
+			p.observeHead(e, n)
+			if e.success(time.Since(start)) {
+				p.opts.Logger.Printf("endpoint %s recovered", maskURL(e.url))
+			}
+			return
+		}
+	}
+	if e.fail(err, p.opts.FailureThreshold) {
+		p.opts.Logger.Printf("endpoint %s unhealthy: %v", maskURL(e.url), err)
+	}
+}
+
+// candidates 按优先级排序节点：健康且已同步到 minHead 的最优先，其次是落后的节点，最后是不健康的节点
+func (p *Pool) candidates(minHead uint64) []*endpoint {
+	head := p.head.Load()
+	tier := func(e *endpoint) int {
+		healthy, h := e.state()
+		switch {
+		case !healthy:
+			return 2
+		case h < minHead || h+p.opts.MaxLag < head:
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	list := append([]*endpoint(nil), p.endpoints...)
+	tiers := make(map[*endpoint]int, len(list))
+	scores := make(map[*endpoint]float64, len(list))
+	for _, e := range list {
+		tiers[e] = tier(e)
+		scores[e] = e.score()
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if tiers[list[i]] != tiers[list[j]] {
+			return tiers[list[i]] < tiers[list[j]]
+		}
+		return scores[list[i]] < scores[list[j]]
+	})
+	return list
+}
+
+// call 依次尝试节点直到成功或遇到非节点故障的错误（如合约 revert、nonce 错误），
+// minHead > 0 时跳过确认尚未同步到该区块的节点
+func (p *Pool) call(ctx context.Context, minHead uint64, fn func(*endpoint, *ethclient.Client) error) error {
+	var (
+		lastErr error
+		limited []*endpoint
+	)
+
+	for _, e := range p.candidates(minHead) {
+		if !e.limiter.allow() {
+			limited = append(limited, e)
+			continue
+		}
+		err := p.try(ctx, e, minHead, fn)
+		if err == nil || !p.isEndpointError(ctx, err) {
+			return err
+		}
+		lastErr = err
+	}
+
+	// 可用节点都在限流中：等待最优的节点
+	if len(limited) > 0 && ctx.Err() == nil {
+		e := limited[0]
+		if err := e.limiter.wait(ctx); err != nil {
+			return err
+		}
+		err := p.try(ctx, e, minHead, fn)
+		if err == nil || !p.isEndpointError(ctx, err) {
+			return err
+		}
+		lastErr = err
+	}
+
+	if lastErr == nil {
+		lastErr = ErrNoEndpoint
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return fmt.Errorf("rpc pool %s: %w", p.name, lastErr)
+}
+
+var errLagging = errors.New("rpc endpoint not synced")
+
+func (p *Pool) try(ctx context.Context, e *endpoint, minHead uint64, fn func(*endpoint, *ethclient.Client) error) error {
+	client, err := e.conn(ctx)
+	if err != nil {
+		p.markFailed(ctx, e, err)
+		return err
+	}
+
+	// 一致性保护：节点记录的区块落后于请求的区块时先确认其最新区块
+	if _, h := e.state(); minHead > 0 && h < minHead {
+		n, err := client.BlockNumber(ctx)
+		if err != nil {
+			p.markFailed(ctx, e, err)
+			return err
+		}
+		p.observeHead(e, n)
+		if n < minHead {
+			return fmt.Errorf("%w: %s at %d, want %d", errLagging, maskURL(e.url), n, minHead)
+		}
+	}
+
+	start := time.Now()
+	err = fn(e, client)
+	if err != nil && p.isEndpointError(ctx, err) {
+		p.markFailed(ctx, e, err)
+		return err
+	}
+	if e.success(time.Since(start)) {
+		p.opts.Logger.Printf("endpoint %s recovered", maskURL(e.url))
+	}
+	return err
+}
+
+func (p *Pool) markFailed(ctx context.Context, e *endpoint, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	if e.fail(err, p.opts.FailureThreshold) {
+		p.opts.Logger.Printf("endpoint %s unhealthy: %v", maskURL(e.url), err)
+	}
+}
+
+// isEndpointError 判断错误是否由节点本身引起（网络错误、超时、限流、5xx），是则切换节点重试。
+// 节点正常返回的 JSON-RPC 错误（revert、nonce 过低、未找到等）直接返回给调用方
+func (p *Pool) isEndpointError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, errLagging) {
+		return true
+	}
+	if errors.Is(err, ethereum.NotFound) {
+		return false
+	}
+
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == 429 || httpErr.StatusCode >= 500
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return false
+	}
+	return true
+}
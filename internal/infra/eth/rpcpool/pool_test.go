@@ -0,0 +1,370 @@
+package rpcpool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// rpcFailure 节点对某个方法的固定应答：HTTP 状态码错误或 JSON-RPC 错误
+type rpcFailure struct {
+	status  int    // 非 0 时返回该 HTTP 状态码
+	code    int    // JSON-RPC 错误码
+	message string // JSON-RPC 错误信息
+}
+
+// fakeNode 最小的 JSON-RPC 节点，记录每个方法收到的请求数
+type fakeNode struct {
+	srv   *httptest.Server
+	delay time.Duration
+
+	mu       sync.Mutex
+	head     uint64
+	calls    map[string]int
+	failures map[string]rpcFailure // 方法 → 应答，"*" 对全部方法生效
+	txs      map[common.Hash]*types.Transaction
+}
+
+func newFakeNode(t *testing.T, head uint64, delay time.Duration) *fakeNode {
+	t.Helper()
+	n := &fakeNode{
+		head:     head,
+		delay:    delay,
+		calls:    map[string]int{},
+		failures: map[string]rpcFailure{},
+		txs:      map[common.Hash]*types.Transaction{},
+	}
+	n.srv = httptest.NewServer(http.HandlerFunc(n.serve))
+	t.Cleanup(n.srv.Close)
+	return n
+}
+
+func (n *fakeNode) serve(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	time.Sleep(n.delay)
+
+	n.mu.Lock()
+	n.calls[req.Method]++
+	failure, ok := n.failures[req.Method]
+	if !ok {
+		failure, ok = n.failures["*"]
+	}
+	var (
+		result interface{}
+		err    error
+	)
+	if !ok {
+		result, err = n.result(req.Method, req.Params)
+	}
+	n.mu.Unlock()
+
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+	switch {
+	case ok && failure.status != 0:
+		http.Error(w, http.StatusText(failure.status), failure.status)
+		return
+	case ok:
+		resp["error"] = map[string]interface{}{"code": failure.code, "message": failure.message}
+	case err != nil:
+		resp["error"] = map[string]interface{}{"code": -32601, "message": err.Error()}
+	default:
+		resp["result"] = result
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// result 调用方持有 n.mu
+func (n *fakeNode) result(method string, params []json.RawMessage) (interface{}, error) {
+	switch method {
+	case "eth_chainId":
+		return hexutil.Uint64(1337), nil
+	case "eth_blockNumber":
+		return hexutil.Uint64(n.head), nil
+	case "eth_getBalance":
+		return (*hexutil.Big)(big.NewInt(1)), nil
+	case "eth_sendRawTransaction":
+		var raw hexutil.Bytes
+		if err := json.Unmarshal(params[0], &raw); err != nil {
+			return nil, err
+		}
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(raw); err != nil {
+			return nil, err
+		}
+		n.txs[tx.Hash()] = tx
+		return tx.Hash(), nil
+	case "eth_getTransactionByHash":
+		var hash common.Hash
+		if err := json.Unmarshal(params[0], &hash); err != nil {
+			return nil, err
+		}
+		if tx, ok := n.txs[hash]; ok {
+			return tx, nil
+		}
+		return nil, nil
+	}
+	return nil, errors.New("method not found: " + method)
+}
+
+func (n *fakeNode) fail(method string, f rpcFailure) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.failures[method] = f
+}
+
+func (n *fakeNode) count(method string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.calls[method]
+}
+
+func newTestPool(t *testing.T, opts Options, nodes ...*fakeNode) *Pool {
+	t.Helper()
+	urls := make([]string, len(nodes))
+	for i, n := range nodes {
+		urls[i] = n.srv.URL
+	}
+	opts.HealthInterval = time.Hour // 健康检查只在创建时执行一次
+	opts.Logger = log.New(io.Discard, "", 0)
+	p, err := New("test", urls, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Close)
+	return p
+}
+
+var testAccount = common.HexToAddress("0x0000000000000000000000000000000000000001")
+
+// TestPoolPrefersFastEndpointAndFailsOver 延迟低的节点优先；节点故障（5xx、连接断开）时切换到下一个节点，
+// 连续失败达到阈值后不再使用
+func TestPoolPrefersFastEndpointAndFailsOver(t *testing.T) {
+	ctx := context.Background()
+	primary := newFakeNode(t, 100, 0)
+	backup := newFakeNode(t, 100, 20*time.Millisecond)
+	down := newFakeNode(t, 100, 0)
+	down.srv.Close()
+	p := newTestPool(t, Options{FailureThreshold: 2}, down, backup, primary)
+
+	if _, err := p.BalanceAt(ctx, testAccount, nil); err != nil {
+		t.Fatal(err)
+	}
+	if primary.count("eth_getBalance") != 1 || backup.count("eth_getBalance") != 0 {
+		t.Fatalf("balance served by primary %d, backup %d times; want primary", primary.count("eth_getBalance"), backup.count("eth_getBalance"))
+	}
+
+	primary.fail("*", rpcFailure{status: http.StatusServiceUnavailable})
+	for i := 1; i <= 3; i++ {
+		if _, err := p.BalanceAt(ctx, testAccount, nil); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	// 前两次先打到 primary 失败后切换，之后 primary 被标记为不健康直接跳过
+	if got := primary.count("eth_getBalance"); got != 3 {
+		t.Fatalf("primary tried %d times, want 3", got)
+	}
+	if got := backup.count("eth_getBalance"); got != 3 {
+		t.Fatalf("backup served %d requests, want 3", got)
+	}
+	stats := p.Stats()
+	// 不可用的节点只在健康检查时失败过一次，评分靠后，业务请求没有用到它
+	if stats[0].Requests != 1 || stats[0].Errors != 1 {
+		t.Fatalf("down endpoint stats = %+v, want only the failed health check", stats[0])
+	}
+	if stats[2].Healthy || stats[2].Errors != 2 {
+		t.Fatalf("primary stats = %+v, want unhealthy after 2 errors", stats[2])
+	}
+}
+
+// TestPoolJSONRPCErrorNoFailover 节点正常返回的 JSON-RPC 错误直接交给调用方，不切换节点也不计入节点故障
+func TestPoolJSONRPCErrorNoFailover(t *testing.T) {
+	primary := newFakeNode(t, 100, 0)
+	backup := newFakeNode(t, 100, 20*time.Millisecond)
+	p := newTestPool(t, Options{}, backup, primary)
+
+	primary.fail("eth_getBalance", rpcFailure{code: -32000, message: "header not found"})
+	for i := 0; i < 5; i++ {
+		if _, err := p.BalanceAt(context.Background(), testAccount, nil); err == nil || err.Error() != "header not found" {
+			t.Fatalf("err = %v, want the JSON-RPC error", err)
+		}
+	}
+	if got := backup.count("eth_getBalance"); got != 0 {
+		t.Fatalf("JSON-RPC error failed over to backup %d times", got)
+	}
+	if stats := p.Stats()[1]; !stats.Healthy || stats.Errors != 0 {
+		t.Fatalf("primary stats = %+v, want healthy with no errors", stats)
+	}
+}
+
+// TestPoolSkipsLaggingEndpoint 落后节点降级使用；查询指定区块时不使用尚未同步到该区块的节点，最新区块不会回退
+func TestPoolSkipsLaggingEndpoint(t *testing.T) {
+	ctx := context.Background()
+	lagging := newFakeNode(t, 10, 0)
+	synced := newFakeNode(t, 100, 20*time.Millisecond)
+	p := newTestPool(t, Options{MaxLag: 3}, lagging, synced)
+
+	if _, err := p.ChainID(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if lagging.count("eth_chainId") != 0 || synced.count("eth_chainId") != 1 {
+		t.Fatalf("chainId served by lagging %d, synced %d times; want synced", lagging.count("eth_chainId"), synced.count("eth_chainId"))
+	}
+
+	// 同步节点不可用时，区块 50 的查询不会交给只同步到 10 的节点
+	synced.fail("*", rpcFailure{status: http.StatusBadGateway})
+	if _, err := p.BalanceAt(ctx, testAccount, big.NewInt(50)); !errors.Is(err, errLagging) {
+		t.Fatalf("err = %v, want errLagging", err)
+	}
+	if got := lagging.count("eth_getBalance"); got != 0 {
+		t.Fatalf("lagging endpoint answered %d balance queries at block 50", got)
+	}
+	// 不指定区块的请求可以使用落后节点，但返回的最新区块不低于池内已观察到的最高区块
+	if _, err := p.BalanceAt(ctx, testAccount, nil); err != nil {
+		t.Fatalf("latest balance: %v", err)
+	}
+	if n, err := p.BlockNumber(ctx); err != nil || n != 100 {
+		t.Fatalf("block number = %d, %v; want 100", n, err)
+	}
+}
+
+// TestPoolRateLimit 最优节点限流时立即使用其他节点，全部限流时等待最优节点
+func TestPoolRateLimit(t *testing.T) {
+	ctx := context.Background()
+	primary := newFakeNode(t, 100, 0)
+	backup := newFakeNode(t, 100, 20*time.Millisecond)
+	p := newTestPool(t, Options{RateLimit: 5, Burst: 1}, backup, primary)
+	time.Sleep(250 * time.Millisecond) // 健康检查用掉的令牌恢复
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.ChainID(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if primary.count("eth_chainId") != 1 || backup.count("eth_chainId") != 1 {
+		t.Fatalf("chainId served by primary %d, backup %d times; want 1 each", primary.count("eth_chainId"), backup.count("eth_chainId"))
+	}
+
+	start := time.Now()
+	if _, err := p.ChainID(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < 100*time.Millisecond {
+		t.Fatalf("request with all endpoints limited returned after %s, want to wait for a token", waited)
+	}
+	if got := primary.count("eth_chainId"); got != 2 {
+		t.Fatalf("primary served %d requests, want 2", got)
+	}
+
+	// 等待期间 ctx 取消时返回 ctx 的错误
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.ChainID(cctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	unlimited := newLimiter(0, 0)
+	for i := 0; i < 100; i++ {
+		if !unlimited.allow() {
+			t.Fatal("unlimited limiter denied a request")
+		}
+	}
+
+	l := newLimiter(10, 2)
+	if !l.allow() || !l.allow() {
+		t.Fatal("burst of 2 not allowed")
+	}
+	if l.allow() {
+		t.Fatal("third request allowed without tokens")
+	}
+	start := time.Now()
+	if err := l.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < 50*time.Millisecond || waited > time.Second {
+		t.Fatalf("waited %s for a token at 10/s", waited)
+	}
+}
+
+func signedTx(t *testing.T) *types.Transaction {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := types.SignTx(types.NewTransaction(0, testAccount, big.NewInt(1), 21000, big.NewInt(1e9), nil),
+		types.LatestSignerForChainID(big.NewInt(1337)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+// TestSendTransactionResend 换节点重发同一笔交易时，already known / nonce too low 只有在按哈希查到交易时才视为成功
+func TestSendTransactionResend(t *testing.T) {
+	tests := []struct {
+		name      string
+		first     rpcFailure // primary 的应答
+		second    rpcFailure // backup 的应答
+		backupHas bool       // backup 上能查到该交易
+		wantErr   bool
+	}{
+		{"already known and found", rpcFailure{status: http.StatusBadGateway}, rpcFailure{code: -32000, message: "already known"}, true, false},
+		{"nonce too low and found", rpcFailure{status: http.StatusBadGateway}, rpcFailure{code: -32000, message: "nonce too low"}, true, false},
+		{"already known but not found", rpcFailure{status: http.StatusBadGateway}, rpcFailure{code: -32000, message: "already known"}, false, true},
+		{"nonce too low but not found", rpcFailure{status: http.StatusBadGateway}, rpcFailure{code: -32000, message: "nonce too low"}, false, true},
+		// 第一次发送就返回 nonce too low 是真实的 nonce 冲突，不查询也不切换
+		{"nonce too low on first attempt", rpcFailure{code: -32000, message: "nonce too low"}, rpcFailure{}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := newFakeNode(t, 100, 0)
+			backup := newFakeNode(t, 100, 20*time.Millisecond)
+			p := newTestPool(t, Options{}, backup, primary)
+
+			tx := signedTx(t)
+			primary.txs[tx.Hash()] = tx
+			if tt.backupHas {
+				backup.txs[tx.Hash()] = tx
+			}
+			primary.fail("eth_sendRawTransaction", tt.first)
+			if tt.second.message != "" {
+				backup.fail("eth_sendRawTransaction", tt.second)
+			}
+
+			err := p.SendTransaction(context.Background(), tx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.first.status == 0 && backup.count("eth_sendRawTransaction") != 0 {
+				t.Fatal("JSON-RPC error on first attempt failed over to backup")
+			}
+			if tt.first.status == 0 && primary.count("eth_getTransactionByHash") != 0 {
+				t.Fatal("first attempt error looked up the tx hash")
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// KeySigner 使用内存中的私钥签名，用于测试与明文私钥配置
+type KeySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+func NewKeySigner(key *ecdsa.PrivateKey) *KeySigner {
+	return &KeySigner{
+		key:     key,
+		address: crypto.PubkeyToAddress(key.PublicKey),
+	}
+}
+
+// NewKeySignerFromHex 解析 64 位 hex 私钥，可带 0x 前缀
+func NewKeySignerFromHex(privateKey string) (*KeySigner, error) {
+	privateKeyHex := strings.TrimPrefix(strings.TrimSpace(privateKey), "0x")
+	if privateKeyHex == "" {
+		return nil, errors.New("missing ETH_PRIVATE in config")
+	}
+
+	if len(privateKeyHex) != 64 {
+		return nil, errors.New("invalid private key length: should be 64 hex characters")
+	}
+
+	key, err := crypto.HexToECDSA(privateKeyHex)
+	if err != nil {
+		return nil, errors.New("failed to parse private key: " + err.Error())
+	}
+	return NewKeySigner(key), nil
+}
+
+func (s *KeySigner) Address() common.Address {
+	return s.address
+}
+
+func (s *KeySigner) SignTx(_ context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
+
+func (s *KeySigner) SignMessage(_ context.Context, msg []byte) ([]byte, error) {
+	return s.signHash(accounts.TextHash(msg))
+}
+
+func (s *KeySigner) SignTypedData(_ context.Context, data apitypes.TypedData) ([]byte, error) {
+	hash, err := typedDataHash(data)
+	if err != nil {
+		return nil, err
+	}
+	return s.signHash(hash)
+}
+
+func (s *KeySigner) signHash(hash []byte) ([]byte, error) {
+	sig, err := crypto.Sign(hash, s.key)
+	if err != nil {
+		return nil, err
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return sig, nil
+}
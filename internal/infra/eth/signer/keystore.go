@@ -0,0 +1,21 @@
+package signer
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+)
+
+// NewKeystoreSigner 用口令解密 geth keystore JSON 文件（UTC--... 格式），解密后的私钥只保存在内存中
+func NewKeystoreSigner(path, password string) (*KeySigner, error) {
+	keyJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read keystore %s: %w", path, err)
+	}
+	key, err := keystore.DecryptKey(keyJSON, password)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt keystore %s: %w", path, err)
+	}
+	return NewKeySigner(key.PrivateKey), nil
+}
@@ -0,0 +1,114 @@
+package signer
+
+import (
+	"context"
+	"go-web3/internal/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// TestKeystoreSigner 口令正确时解出的账户与 keystore 文件一致，口令错误或文件不存在时报错
+func TestKeystoreSigner(t *testing.T) {
+	dir := t.TempDir()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.ImportECDSA(key, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := account.URL.Path
+
+	tests := []struct {
+		name     string
+		path     string
+		password string
+		wantErr  string
+	}{
+		{name: "ok", path: file, password: "secret"},
+		{name: "wrong password", path: file, password: "guess", wantErr: "decrypt keystore"},
+		{name: "missing file", path: filepath.Join(dir, "missing.json"), password: "secret", wantErr: "read keystore"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewKeystoreSigner(tt.path, tt.password)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Address() != account.Address {
+				t.Fatalf("address = %s, want %s", s.Address().Hex(), account.Address.Hex())
+			}
+			// 解出的私钥可以正常签名
+			sig, err := s.SignMessage(context.Background(), []byte("hello"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sig[crypto.RecoveryIDOffset] != 27 && sig[crypto.RecoveryIDOffset] != 28 {
+				t.Fatalf("v = %d, want 27 or 28", sig[crypto.RecoveryIDOffset])
+			}
+		})
+	}
+}
+
+// TestNewKeystoreFromPasswordFile 口令文件末尾的换行不属于口令
+func TestNewKeystoreFromPasswordFile(t *testing.T) {
+	dir := t.TempDir()
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	var (
+		files []string
+		want  []accounts.Account
+	)
+	for i := 0; i < 2; i++ {
+		account, err := ks.NewAccount("secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, account.URL.Path)
+		want = append(want, account)
+	}
+	passwordFile := filepath.Join(dir, "password")
+	if err := os.WriteFile(passwordFile, []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	signers, err := New(context.Background(), config.EthConfig{Signer: config.SignerConfig{
+		Type:                 "keystore",
+		KeystoreFile:         strings.Join(files, ", "),
+		KeystorePasswordFile: passwordFile,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signers) != len(want) {
+		t.Fatalf("got %d signers, want %d", len(signers), len(want))
+	}
+	for i, account := range want {
+		if signers[i].Address() != account.Address {
+			t.Fatalf("signer %d = %s, want %s", i, signers[i].Address().Hex(), account.Address.Hex())
+		}
+	}
+
+	// 同一账户配置两次
+	_, err = New(context.Background(), config.EthConfig{Signer: config.SignerConfig{
+		Type:             "keystore",
+		KeystoreFile:     files[0] + "," + files[0],
+		KeystorePassword: "secret",
+	}})
+	if err == nil || !strings.Contains(err.Error(), "duplicate signer") {
+		t.Fatalf("err = %v, want duplicate signer", err)
+	}
+}
@@ -0,0 +1,144 @@
+package signer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// RemoteSigner 通过 HTTP JSON-RPC 调用远程签名服务（Web3Signer 的 eth_signTransaction / eth_sign / eth_signTypedData），
+// 私钥不进入本进程。签名结果会校验签名地址与交易内容
+type RemoteSigner struct {
+	client  *rpc.Client
+	address common.Address
+}
+
+// DialRemoteSigner 连接远程签名服务，address 为服务中用于签名的账户
+func DialRemoteSigner(ctx context.Context, url string, address common.Address) (*RemoteSigner, error) {
+	if url == "" {
+		return nil, errors.New("missing remote signer url")
+	}
+	client, err := rpc.DialOptions(ctx, url, rpc.WithHTTPClient(&http.Client{Timeout: 10 * time.Second}))
+	if err != nil {
+		return nil, fmt.Errorf("dial remote signer: %w", err)
+	}
+	return &RemoteSigner{client: client, address: address}, nil
+}
+
+func (s *RemoteSigner) Address() common.Address {
+	return s.address
+}
+
+// sendTxArgs eth_signTransaction 参数
+type sendTxArgs struct {
+	From                 common.Address  `json:"from"`
+	To                   *common.Address `json:"to,omitempty"`
+	Gas                  hexutil.Uint64  `json:"gas"`
+	GasPrice             *hexutil.Big    `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas,omitempty"`
+	Value                *hexutil.Big    `json:"value"`
+	Data                 hexutil.Bytes   `json:"data"`
+	Nonce                hexutil.Uint64  `json:"nonce"`
+	ChainID              *hexutil.Big    `json:"chainId"`
+}
+
+func (s *RemoteSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := sendTxArgs{
+		From:    s.address,
+		To:      tx.To(),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   (*hexutil.Big)(tx.Value()),
+		Data:    tx.Data(),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		ChainID: (*hexutil.Big)(chainID),
+	}
+	switch tx.Type() {
+	case types.LegacyTxType:
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	case types.DynamicFeeTxType:
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	default:
+		return nil, fmt.Errorf("remote signer: unsupported tx type %d", tx.Type())
+	}
+
+	var raw hexutil.Bytes
+	if err := s.client.CallContext(ctx, &raw, "eth_signTransaction", args); err != nil {
+		return nil, fmt.Errorf("remote sign tx: %w", err)
+	}
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("remote sign tx: decode: %w", err)
+	}
+
+	// 签名服务返回的交易必须与请求一致，且由配置的账户签名
+	signer := types.LatestSignerForChainID(chainID)
+	if signer.Hash(signed) != signer.Hash(tx) {
+		return nil, errors.New("remote sign tx: signed tx does not match request")
+	}
+	from, err := types.Sender(signer, signed)
+	if err != nil {
+		return nil, fmt.Errorf("remote sign tx: %w", err)
+	}
+	if from != s.address {
+		return nil, fmt.Errorf("remote sign tx: signed by %s, want %s", from.Hex(), s.address.Hex())
+	}
+	return signed, nil
+}
+
+func (s *RemoteSigner) SignMessage(ctx context.Context, msg []byte) ([]byte, error) {
+	var sig hexutil.Bytes
+	if err := s.client.CallContext(ctx, &sig, "eth_sign", s.address, hexutil.Bytes(msg)); err != nil {
+		return nil, fmt.Errorf("remote sign message: %w", err)
+	}
+	return s.verify(accounts.TextHash(msg), sig)
+}
+
+func (s *RemoteSigner) SignTypedData(ctx context.Context, data apitypes.TypedData) ([]byte, error) {
+	var sig hexutil.Bytes
+	if err := s.client.CallContext(ctx, &sig, "eth_signTypedData", s.address, data); err != nil {
+		return nil, fmt.Errorf("remote sign typed data: %w", err)
+	}
+	hash, err := typedDataHash(data)
+	if err != nil {
+		return nil, err
+	}
+	return s.verify(hash, sig)
+}
+
+// verify 校验签名由配置的账户生成，V 统一为 27/28
+func (s *RemoteSigner) verify(hash []byte, sig []byte) ([]byte, error) {
+	if len(sig) != crypto.SignatureLength {
+		return nil, fmt.Errorf("remote signer: invalid signature length %d", len(sig))
+	}
+	sig = common.CopyBytes(sig)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return nil, fmt.Errorf("remote signer: %w", err)
+	}
+	if from := crypto.PubkeyToAddress(*pub); from != s.address {
+		return nil, fmt.Errorf("remote signer: signed by %s, want %s", from.Hex(), s.address.Hex())
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return sig, nil
+}
+
+// Close 关闭与签名服务的连接
+func (s *RemoteSigner) Close() {
+	s.client.Close()
+}
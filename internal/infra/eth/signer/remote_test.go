@@ -0,0 +1,282 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// fakeSignService 模拟远程签名服务：按请求参数组装交易并用 key 签名，tamper 篡改签名前的交易字段
+type fakeSignService struct {
+	key     *ecdsa.PrivateKey
+	tamper  func(args *sendTxArgs)
+	chainID *big.Int // 非空时用该 chain ID 签名
+	legacyV bool     // 消息签名返回 V 为 0/1
+}
+
+func (f *fakeSignService) SignTransaction(args sendTxArgs) (hexutil.Bytes, error) {
+	if f.tamper != nil {
+		f.tamper(&args)
+	}
+	chainID := (*big.Int)(args.ChainID)
+	if f.chainID != nil {
+		chainID = f.chainID
+	}
+	var inner types.TxData
+	if args.GasPrice != nil {
+		inner = &types.LegacyTx{
+			Nonce:    uint64(args.Nonce),
+			GasPrice: (*big.Int)(args.GasPrice),
+			Gas:      uint64(args.Gas),
+			To:       args.To,
+			Value:    (*big.Int)(args.Value),
+			Data:     args.Data,
+		}
+	} else {
+		inner = &types.DynamicFeeTx{
+			ChainID:   chainID,
+			Nonce:     uint64(args.Nonce),
+			GasTipCap: (*big.Int)(args.MaxPriorityFeePerGas),
+			GasFeeCap: (*big.Int)(args.MaxFeePerGas),
+			Gas:       uint64(args.Gas),
+			To:        args.To,
+			Value:     (*big.Int)(args.Value),
+			Data:      args.Data,
+		}
+	}
+	tx, err := types.SignNewTx(f.key, types.LatestSignerForChainID(chainID), inner)
+	if err != nil {
+		return nil, err
+	}
+	return tx.MarshalBinary()
+}
+
+func (f *fakeSignService) Sign(_ common.Address, msg hexutil.Bytes) (hexutil.Bytes, error) {
+	return f.signHash(accounts.TextHash(msg))
+}
+
+func (f *fakeSignService) SignTypedData(_ common.Address, data apitypes.TypedData) (hexutil.Bytes, error) {
+	hash, err := typedDataHash(data)
+	if err != nil {
+		return nil, err
+	}
+	return f.signHash(hash)
+}
+
+func (f *fakeSignService) signHash(hash []byte) (hexutil.Bytes, error) {
+	sig, err := crypto.Sign(hash, f.key)
+	if err != nil {
+		return nil, err
+	}
+	if !f.legacyV {
+		sig[crypto.RecoveryIDOffset] += 27
+	}
+	return sig, nil
+}
+
+// newRemoteSigner 连接用 svc 应答的签名服务，签名账户为 address
+func newRemoteSigner(t *testing.T, svc *fakeSignService, address common.Address) *RemoteSigner {
+	t.Helper()
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("eth", svc); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(func() {
+		ts.Close()
+		srv.Stop()
+	})
+	s, err := DialRemoteSigner(context.Background(), ts.URL, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newKey(t *testing.T) (*ecdsa.PrivateKey, common.Address) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, crypto.PubkeyToAddress(key.PublicKey)
+}
+
+// TestRemoteSignTx 签名服务返回的交易必须由配置的账户签名，且与请求的交易一致
+func TestRemoteSignTx(t *testing.T) {
+	key, address := newKey(t)
+	otherKey, other := newKey(t)
+	chainID := big.NewInt(1337)
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+
+	dynamic := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     7,
+		GasTipCap: big.NewInt(1e9),
+		GasFeeCap: big.NewInt(30e9),
+		Gas:       50000,
+		To:        &to,
+		Value:     big.NewInt(100),
+		Data:      []byte{0xde, 0xad},
+	})
+	legacy := types.NewTx(&types.LegacyTx{
+		Nonce:    7,
+		GasPrice: big.NewInt(20e9),
+		Gas:      21000,
+		To:       &to,
+		Value:    big.NewInt(100),
+	})
+
+	tests := []struct {
+		name    string
+		tx      *types.Transaction
+		svc     *fakeSignService
+		wantErr string
+	}{
+		{name: "dynamic fee", tx: dynamic, svc: &fakeSignService{key: key}},
+		{name: "legacy", tx: legacy, svc: &fakeSignService{key: key}},
+		{
+			name:    "wrong key",
+			tx:      dynamic,
+			svc:     &fakeSignService{key: otherKey},
+			wantErr: "signed by " + other.Hex(),
+		},
+		{
+			name: "altered value",
+			tx:   dynamic,
+			svc: &fakeSignService{key: key, tamper: func(args *sendTxArgs) {
+				args.Value = (*hexutil.Big)(big.NewInt(1e18))
+			}},
+			wantErr: "does not match request",
+		},
+		{
+			name: "altered recipient",
+			tx:   dynamic,
+			svc: &fakeSignService{key: key, tamper: func(args *sendTxArgs) {
+				attacker := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+				args.To = &attacker
+			}},
+			wantErr: "does not match request",
+		},
+		{
+			name: "altered fee",
+			tx:   dynamic,
+			svc: &fakeSignService{key: key, tamper: func(args *sendTxArgs) {
+				args.MaxFeePerGas = (*hexutil.Big)(big.NewInt(300e9))
+			}},
+			wantErr: "does not match request",
+		},
+		{
+			name: "altered nonce",
+			tx:   legacy,
+			svc: &fakeSignService{key: key, tamper: func(args *sendTxArgs) {
+				args.Nonce++
+			}},
+			wantErr: "does not match request",
+		},
+		{
+			name:    "other chain",
+			tx:      dynamic,
+			svc:     &fakeSignService{key: key, chainID: big.NewInt(1)},
+			wantErr: "invalid chain id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newRemoteSigner(t, tt.svc, address)
+			signed, err := s.SignTx(context.Background(), tt.tx, chainID)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			signer := types.LatestSignerForChainID(chainID)
+			if signer.Hash(signed) != signer.Hash(tt.tx) {
+				t.Fatal("signed tx differs from request")
+			}
+			if from, err := types.Sender(signer, signed); err != nil || from != address {
+				t.Fatalf("sender = %s, %v; want %s", from.Hex(), err, address.Hex())
+			}
+		})
+	}
+}
+
+// TestRemoteSignMessage 消息与结构化数据签名校验签名地址，V 统一为 27/28
+func TestRemoteSignMessage(t *testing.T) {
+	key, address := newKey(t)
+	otherKey, _ := newKey(t)
+	data := apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {{Name: "name", Type: "string"}, {Name: "chainId", Type: "uint256"}},
+			"Bid":          {{Name: "auctionId", Type: "uint256"}},
+		},
+		PrimaryType: "Bid",
+		Domain:      apitypes.TypedDataDomain{Name: "NftAuction", ChainId: math.NewHexOrDecimal256(1337)},
+		Message:     apitypes.TypedDataMessage{"auctionId": "1"},
+	}
+	typedHash, err := typedDataHash(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		svc     *fakeSignService
+		wantErr string
+	}{
+		{name: "ok", svc: &fakeSignService{key: key}},
+		{name: "v 0/1", svc: &fakeSignService{key: key, legacyV: true}},
+		{name: "wrong key", svc: &fakeSignService{key: otherKey}, wantErr: "signed by"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newRemoteSigner(t, tt.svc, address)
+			msg := []byte("login nonce 42")
+			for _, c := range []struct {
+				hash []byte
+				sign func() ([]byte, error)
+			}{
+				{accounts.TextHash(msg), func() ([]byte, error) { return s.SignMessage(context.Background(), msg) }},
+				{typedHash, func() ([]byte, error) { return s.SignTypedData(context.Background(), data) }},
+			} {
+				sig, err := c.sign()
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("err = %v, want %q", err, tt.wantErr)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				v := sig[crypto.RecoveryIDOffset]
+				if v != 27 && v != 28 {
+					t.Fatalf("v = %d, want 27 or 28", v)
+				}
+				sig = common.CopyBytes(sig)
+				sig[crypto.RecoveryIDOffset] -= 27
+				pub, err := crypto.SigToPub(c.hash, sig)
+				if err != nil || crypto.PubkeyToAddress(*pub) != address {
+					t.Fatalf("signature does not recover to %s", address.Hex())
+				}
+			}
+		})
+	}
+}
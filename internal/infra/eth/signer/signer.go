@@ -0,0 +1,164 @@
+package signer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-web3/internal/config"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Signer 交易与消息签名。私钥可以在本进程内存中（明文私钥、keystore 文件），也可以在远程签名服务中，
+// 交易发送方只依赖地址与签名能力
+type Signer interface {
+	// Address 签名账户地址
+	Address() common.Address
+	// SignTx 按 chainID 对交易签名
+	SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+	// SignMessage EIP-191 personal_sign 签名，V 为 27/28
+	SignMessage(ctx context.Context, msg []byte) ([]byte, error)
+	// SignTypedData EIP-712 结构化数据签名，V 为 27/28
+	SignTypedData(ctx context.Context, data apitypes.TypedData) ([]byte, error)
+}
+
+// New 按配置创建发送账户的签名器，ETH_PRIVATE、ETH_KEYSTORE_FILE、ETH_REMOTE_SIGNER_ADDRESS 逗号分隔配置多个账户：
+// key 使用明文私钥，keystore 用同一口令解密 geth keystore 文件，remote 使用远程签名服务
+func New(ctx context.Context, cfg config.EthConfig) ([]Signer, error) {
+	sc := cfg.Signer
+	var list string
+	switch sc.Type {
+	case "", "key":
+		list = cfg.Private
+	case "keystore":
+		list = sc.KeystoreFile
+	case "remote":
+		list = sc.RemoteAddress
+	}
+	signers, err := build(ctx, sc, splitList(list))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(signers) == 0 {
+		return nil, errors.New("no signer configured")
+	}
+	seen := map[common.Address]bool{}
+	for _, s := range signers {
+		if seen[s.Address()] {
+			closeAll(signers)
+			return nil, errors.New("duplicate signer address: " + s.Address().Hex())
+		}
+		seen[s.Address()] = true
+	}
+	return signers, nil
+}
+
+// NewTreasury 按配置创建余额补充使用的资金账户签名器，签名方式与发送账户相同：
+// ETH_TREASURY 为私钥、keystore 文件或远程签名服务中的账户地址。未配置时返回 nil
+func NewTreasury(ctx context.Context, cfg config.EthConfig) (Signer, error) {
+	if cfg.TopUp.Treasury == "" {
+		return nil, nil
+	}
+	signers, err := build(ctx, cfg.Signer, []string{cfg.TopUp.Treasury})
+	if err != nil {
+		return nil, fmt.Errorf("treasury: %w", err)
+	}
+	return signers[0], nil
+}
+
+// build 按签名方式逐个创建签名器，values 为私钥、keystore 文件或远程账户地址。失败时关闭已创建的远程签名器
+func build(ctx context.Context, sc config.SignerConfig, values []string) (signers []Signer, err error) {
+	defer func() {
+		if err != nil {
+			closeAll(signers)
+		}
+	}()
+
+	switch sc.Type {
+	case "", "key":
+		for _, key := range values {
+			s, err := NewKeySignerFromHex(key)
+			if err != nil {
+				return signers, err
+			}
+			signers = append(signers, s)
+		}
+	case "keystore":
+		password := sc.KeystorePassword
+		if password == "" && sc.KeystorePasswordFile != "" {
+			buf, err := os.ReadFile(sc.KeystorePasswordFile)
+			if err != nil {
+				return nil, fmt.Errorf("read keystore password: %w", err)
+			}
+			password = strings.TrimRight(string(buf), "\r\n")
+		}
+		for _, file := range values {
+			s, err := NewKeystoreSigner(file, password)
+			if err != nil {
+				return signers, err
+			}
+			signers = append(signers, s)
+		}
+	case "remote":
+		for _, addr := range values {
+			if !common.IsHexAddress(addr) {
+				return signers, errors.New("invalid remote signer address: " + addr)
+			}
+			s, err := DialRemoteSigner(ctx, sc.RemoteURL, common.HexToAddress(addr))
+			if err != nil {
+				return signers, err
+			}
+			signers = append(signers, s)
+		}
+	default:
+		return nil, errors.New("unsupported signer type: " + sc.Type)
+	}
+	return signers, nil
+}
+
+// closeAll 关闭其中的远程签名器
+func closeAll(signers []Signer) {
+	for _, s := range signers {
+		if remote, ok := s.(*RemoteSigner); ok {
+			remote.Close()
+		}
+	}
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// TransactOpts 由签名器构建合约调用的授权对象，ctx 用于签名请求
+func TransactOpts(ctx context.Context, s Signer, chainID *big.Int) *bind.TransactOpts {
+	from := s.Address()
+	return &bind.TransactOpts{
+		From: from,
+		Signer: func(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if addr != from {
+				return nil, bind.ErrNotAuthorized
+			}
+			return s.SignTx(ctx, tx, chainID)
+		},
+		Context: ctx,
+	}
+}
+
+// typedDataHash EIP-712 待签名 hash
+func typedDataHash(data apitypes.TypedData) ([]byte, error) {
+	hash, _, err := apitypes.TypedDataAndHash(data)
+	return hash, err
+}
@@ -1,43 +1,57 @@
 package trans
 
 import (
+	"context"
 	"go-web3/internal/infra/eth"
+	"go-web3/internal/infra/eth/chainclient"
 	"go-web3/internal/infra/eth/nonce"
-
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/redis/go-redis/v9"
+	"go-web3/internal/infra/eth/signer"
 )
 
 type EthFactory struct {
-	Client       *ethclient.Client
+	Client       chainclient.Client
 	NonceManager *nonce.NonceManager
-}
+	Senders      *SenderPool
+	Gas          GasStrategy     // nil 时使用 DefaultGasStrategy
+	GasLimits    *GasLimitPolicy // nil 时使用 DefaultGasLimitPolicy
 
-func NewEthFactory(client *ethclient.Client, rdb *redis.Client) *EthFactory {
-	// 先判断 nonce 管理器 是否已经构建出
-	var nonceManager *nonce.NonceManager
+	chain *eth.Chain
+}
 
-	if eth.NonceMgr == nil {
-		nonceManager = nonce.NewNonceManager(rdb, client)
-	} else {
-		nonceManager = eth.NonceMgr
-	}
+// NewEthFactory 为指定链创建交易器工厂，共用该链的 nonce 管理器，发送账户从账户池中选择
+func NewEthFactory(chain *eth.Chain, senders *SenderPool) *EthFactory {
 	return &EthFactory{
-		Client:       client,
-		NonceManager: nonceManager,
+		Client:       chain.Client,
+		NonceManager: chain.NonceMgr,
+		Senders:      senders,
+		chain:        chain,
+	}
+}
+
+// NewTransactor —— 工厂为指定签名器产生一个“交易器”
+func (f *EthFactory) NewTransactor(s signer.Signer) (*Transactor, error) {
+	transactor, err := NewTransactor(f.chain, s)
+	if err != nil {
+		return nil, err
 	}
+	transactor.SetGas(f.Gas, GasOptions{FeeCap: f.chain.MaxFeeCap})
+	transactor.SetGasLimits(f.GasLimits)
+	return transactor, nil
 }
 
-// NewTransactor —— 工厂为指定私钥产生一个“交易器”
-func (f *EthFactory) NewTransactor(privateKeyHex string) *Transactor {
-	pk, err := crypto.HexToECDSA(privateKeyHex)
+// AcquireTransactor 从账户池选择发送账户并产生交易器，pin 见 SenderPool.Acquire。
+// 发送结束后调用 lease.Release 归还账户
+func (f *EthFactory) AcquireTransactor(ctx context.Context, pin string) (*Transactor, *Lease, error) {
+	lease, err := f.Senders.Acquire(ctx, f.chain, pin)
 	if err != nil {
-		panic("invalid private key")
+		return nil, nil, err
 	}
-	transactor, err := NewTransactor(f.Client, f.NonceManager, pk)
+	transactor, err := NewTransactor(f.chain, lease.Signer)
 	if err != nil {
-		panic("failed to create transactor")
+		lease.Release(err)
+		return nil, nil, err
 	}
-	return transactor
+	transactor.SetGas(f.Gas, GasOptions{FeeCap: f.chain.MaxFeeCap})
+	transactor.SetGasLimits(f.GasLimits)
+	return transactor, lease, nil
 }
@@ -0,0 +1,42 @@
+package trans
+
+import (
+	"context"
+	"errors"
+	"go-web3/internal/infra/eth"
+	"go-web3/internal/infra/eth/chainclient"
+	"go-web3/internal/infra/eth/signer"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// downClient 节点不可用：ChainID 请求失败，其余方法不应被调用
+type downClient struct{ chainclient.Client }
+
+func (downClient) ChainID(context.Context) (*big.Int, error) {
+	return nil, errors.New("connection refused")
+}
+
+// TestNewTransactorUsesChainID 创建交易器使用已校验的 chain.ID，不请求节点，也不会因节点不可用而 panic
+func TestNewTransactorUsesChainID(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := signer.NewKeySigner(key)
+	factory := NewEthFactory(eth.NewChain(11155111, "sepolia", downClient{}, nil), nil)
+
+	transactor, err := factory.NewTransactor(s)
+	if err != nil {
+		t.Fatalf("new transactor: %v", err)
+	}
+	if transactor.chainID.Uint64() != 11155111 {
+		t.Fatalf("chain id = %s, want 11155111", transactor.chainID)
+	}
+
+	if _, err := NewTransactor(eth.NewChain(0, "unknown", downClient{}, nil), s); err == nil {
+		t.Fatal("want error for chain without chain id")
+	}
+}
@@ -2,27 +2,244 @@ package trans
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"go-web3/internal/infra/eth"
+	"go-web3/internal/infra/eth/chainclient"
+	"go-web3/internal/infra/eth/txtrack"
 	"math/big"
+	"sort"
 
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 )
 
-// SuggestEIP1559Fee —— EIP-1559 动态 gas
-func SuggestEIP1559Fee(ctx context.Context, client *ethclient.Client) (*big.Int, *big.Int, error) {
-	tip, err := client.SuggestGasTipCap(ctx)
+// ErrBaseFeeAboveCap 下一区块 baseFee 已超过 gas 费上限，按上限发出的交易无法打包
+var ErrBaseFeeAboveCap = errors.New("base fee exceeds max fee cap")
+
+// Speed gas 费档位
+type Speed string
+
+const (
+	SpeedSlow     Speed = "slow"
+	SpeedStandard Speed = "standard"
+	SpeedFast     Speed = "fast"
+)
+
+// Speeds 全部档位，从慢到快
+var Speeds = []Speed{SpeedSlow, SpeedStandard, SpeedFast}
+
+// ParseSpeed 解析档位，空字符串为 standard
+func ParseSpeed(s string) (Speed, error) {
+	switch Speed(s) {
+	case "":
+		return SpeedStandard, nil
+	case SpeedSlow, SpeedStandard, SpeedFast:
+		return Speed(s), nil
+	}
+	return "", fmt.Errorf("invalid gas speed %q, expect slow, standard or fast", s)
+}
+
+// GasOptions 一次交易的 gas 费参数
+type GasOptions struct {
+	Speed        Speed    // 档位，默认 standard
+	MaxFeePerGas *big.Int // 覆盖计算的 maxFeePerGas（legacy 链为 gasPrice），可选
+	MaxTipPerGas *big.Int // 覆盖计算的 maxPriorityFeePerGas，可选
+	FeeCap       *big.Int // 硬上限，maxFeePerGas 与 gasPrice 都不超过该值，nil 不限
+}
+
+// GasOptionsOf 请求中的档位与覆盖值，上限取链配置的 MaxFeeCap
+func GasOptionsOf(chain *eth.Chain, req txtrack.Request) GasOptions {
+	speed, _ := ParseSpeed(req.Speed)
+	return GasOptions{
+		Speed:        speed,
+		MaxFeePerGas: req.MaxFeePerGas,
+		MaxTipPerGas: req.MaxTipPerGas,
+		FeeCap:       chain.MaxFeeCap,
+	}
+}
+
+// Fee 计算出的 gas 费：EIP-1559 链为 tip 与 feeCap，不支持 EIP-1559 的链为 gasPrice
+type Fee struct {
+	Legacy    bool     `json:"legacy"`
+	GasPrice  *big.Int `json:"gasPrice,omitempty"`
+	GasTipCap *big.Int `json:"maxPriorityFeePerGas,omitempty"`
+	GasFeeCap *big.Int `json:"maxFeePerGas,omitempty"`
+	BaseFee   *big.Int `json:"baseFee,omitempty"` // 下一区块的 baseFee
+}
+
+// MaxPerGas 每单位 gas 最多支付的费用
+func (f *Fee) MaxPerGas() *big.Int {
+	if f.Legacy {
+		return f.GasPrice
+	}
+	return f.GasFeeCap
+}
+
+// Apply 设置到合约调用的授权对象
+func (f *Fee) Apply(auth *bind.TransactOpts) {
+	if f.Legacy {
+		auth.GasPrice = f.GasPrice
+		auth.GasTipCap, auth.GasFeeCap = nil, nil
+		return
+	}
+	auth.GasPrice = nil
+	auth.GasTipCap = f.GasTipCap
+	auth.GasFeeCap = f.GasFeeCap
+}
+
+// NewTx 按 gas 费类型构建 legacy 或 EIP-1559 交易
+func (f *Fee) NewTx(chainID *big.Int, nonce uint64, to *common.Address, value *big.Int, gas uint64, data []byte) *types.Transaction {
+	if f.Legacy {
+		return types.NewTx(&types.LegacyTx{
+			Nonce:    nonce,
+			GasPrice: f.GasPrice,
+			Gas:      gas,
+			To:       to,
+			Value:    value,
+			Data:     data,
+		})
+	}
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     nonce,
+		GasTipCap: f.GasTipCap,
+		GasFeeCap: f.GasFeeCap,
+		Gas:       gas,
+		To:        to,
+		Value:     value,
+		Data:      data,
+	})
+}
+
+// GasStrategy gas 费计算策略，交易发送器、转账、合约调用与替换交易共用
+type GasStrategy interface {
+	Suggest(ctx context.Context, client chainclient.Client, opts GasOptions) (*Fee, error)
+}
+
+// DefaultGasStrategy 未指定策略时使用
+var DefaultGasStrategy GasStrategy = NewFeeHistoryStrategy()
+
+// FeeHistoryStrategy 由 eth_feeHistory 计算 gas 费：tip 取最近 Blocks 个区块中各档位分位数的中位数，
+// maxFee = 预估 baseFee + tip。预估 baseFee 按档位预留连续满块的涨幅（每块最多 12.5%），
+// baseFee 处于上升趋势时多预留一个区块。节点不返回 baseFee 时按 legacy gasPrice 计算
+type FeeHistoryStrategy struct {
+	Blocks      uint64            // 参考的区块数，默认 20
+	Percentiles map[Speed]float64 // 各档位 tip 分位数，默认 slow 10、standard 50、fast 90
+	Headroom    map[Speed]int     // 各档位预留满块涨幅的区块数，默认 slow 1、standard 3、fast 6
+	LegacyBump  map[Speed]int64   // legacy 链各档位相对 eth_gasPrice 的千分比，默认 slow 1000、standard 1100、fast 1250
+}
+
+func NewFeeHistoryStrategy() *FeeHistoryStrategy {
+	return &FeeHistoryStrategy{
+		Blocks:      20,
+		Percentiles: map[Speed]float64{SpeedSlow: 10, SpeedStandard: 50, SpeedFast: 90},
+		Headroom:    map[Speed]int{SpeedSlow: 1, SpeedStandard: 3, SpeedFast: 6},
+		LegacyBump:  map[Speed]int64{SpeedSlow: 1000, SpeedStandard: 1100, SpeedFast: 1250},
+	}
+}
+
+func (s *FeeHistoryStrategy) Suggest(ctx context.Context, client chainclient.Client, opts GasOptions) (*Fee, error) {
+	speed := opts.Speed
+	if speed == "" {
+		speed = SpeedStandard
+	}
+
+	head, err := client.HeaderByNumber(ctx, nil)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	if head.BaseFee == nil {
+		return s.legacy(ctx, client, speed, opts)
 	}
 
-	block, err := client.BlockByNumber(ctx, nil)
+	history, err := client.FeeHistory(ctx, s.Blocks, nil, []float64{s.Percentiles[speed]})
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	if len(history.BaseFee) == 0 {
+		return nil, errors.New("empty fee history")
+	}
+	// BaseFee 比区块多一个，最后一个为下一区块的 baseFee
+	next := history.BaseFee[len(history.BaseFee)-1]
+
+	tip := opts.MaxTipPerGas
+	if tip == nil {
+		tip = medianReward(history.Reward)
+		if tip == nil {
+			// 最近区块都没有交易，使用节点建议值
+			if tip, err = client.SuggestGasTipCap(ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	feeCap := opts.MaxFeePerGas
+	if feeCap == nil {
+		blocks := s.Headroom[speed]
+		if rising(history.BaseFee) {
+			blocks++
+		}
+		base := new(big.Int).Set(next)
+		for i := 0; i < blocks; i++ {
+			base = bump(base, 1125)
+		}
+		feeCap = base.Add(base, tip)
 	}
 
-	baseFee := block.BaseFee()
+	if opts.FeeCap != nil && feeCap.Cmp(opts.FeeCap) > 0 {
+		if next.Cmp(opts.FeeCap) > 0 {
+			return nil, fmt.Errorf("%w: base fee %s wei, max %s wei", ErrBaseFeeAboveCap, next, opts.FeeCap)
+		}
+		feeCap = new(big.Int).Set(opts.FeeCap)
+	}
+	if tip.Cmp(feeCap) > 0 {
+		tip = new(big.Int).Set(feeCap)
+	}
+	return &Fee{GasTipCap: tip, GasFeeCap: feeCap, BaseFee: next}, nil
+}
 
-	// MaxFee = BaseFee + 2 * Tip
-	maxFee := new(big.Int).Add(baseFee, new(big.Int).Mul(tip, big.NewInt(2)))
+// legacy 不支持 EIP-1559 的链按 eth_gasPrice 与档位计算 gasPrice
+func (s *FeeHistoryStrategy) legacy(ctx context.Context, client chainclient.Client, speed Speed, opts GasOptions) (*Fee, error) {
+	price := opts.MaxFeePerGas
+	if price == nil {
+		suggest, err := client.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, err
+		}
+		price = bump(suggest, s.LegacyBump[speed])
+	}
+	if opts.FeeCap != nil && price.Cmp(opts.FeeCap) > 0 {
+		price = new(big.Int).Set(opts.FeeCap)
+	}
+	return &Fee{Legacy: true, GasPrice: price}, nil
+}
 
-	return tip, maxFee, nil
+// medianReward 各区块分位数 tip 的中位数，忽略没有交易的区块
+func medianReward(rewards [][]*big.Int) *big.Int {
+	var list []*big.Int
+	for _, r := range rewards {
+		if len(r) > 0 && r[0] != nil && r[0].Sign() > 0 {
+			list = append(list, r[0])
+		}
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Cmp(list[j]) < 0 })
+	return new(big.Int).Set(list[len(list)/2])
+}
+
+// rising 下一区块 baseFee 高于参考区块的平均值
+func rising(baseFees []*big.Int) bool {
+	if len(baseFees) < 2 {
+		return false
+	}
+	sum := new(big.Int)
+	for _, fee := range baseFees[:len(baseFees)-1] {
+		sum.Add(sum, fee)
+	}
+	avg := sum.Div(sum, big.NewInt(int64(len(baseFees)-1)))
+	return baseFees[len(baseFees)-1].Cmp(avg) > 0
 }
@@ -0,0 +1,159 @@
+package trans
+
+import (
+	"context"
+	"errors"
+	"go-web3/internal/infra/eth/chainclient"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// feeClient 返回固定 fee history 的节点，记录请求的 tip 分位数
+type feeClient struct {
+	chainclient.Client
+	baseFees    []int64   // 最后一个为下一区块的 baseFee，nil 表示不支持 EIP-1559
+	rewards     [][]int64 // 各区块的分位数 tip
+	tipCap      int64     // eth_maxPriorityFeePerGas
+	gasPrice    int64     // eth_gasPrice
+	percentiles []float64
+}
+
+func (c *feeClient) HeaderByNumber(context.Context, *big.Int) (*types.Header, error) {
+	head := &types.Header{Number: big.NewInt(100)}
+	if c.baseFees != nil {
+		head.BaseFee = big.NewInt(c.baseFees[len(c.baseFees)-2])
+	}
+	return head, nil
+}
+
+func (c *feeClient) FeeHistory(_ context.Context, _ uint64, _ *big.Int, percentiles []float64) (*ethereum.FeeHistory, error) {
+	c.percentiles = percentiles
+	h := &ethereum.FeeHistory{OldestBlock: big.NewInt(80)}
+	for _, fee := range c.baseFees {
+		h.BaseFee = append(h.BaseFee, big.NewInt(fee))
+	}
+	for _, r := range c.rewards {
+		var row []*big.Int
+		for _, v := range r {
+			row = append(row, big.NewInt(v))
+		}
+		h.Reward = append(h.Reward, row)
+	}
+	return h, nil
+}
+
+func (c *feeClient) SuggestGasTipCap(context.Context) (*big.Int, error) {
+	return big.NewInt(c.tipCap), nil
+}
+
+func (c *feeClient) SuggestGasPrice(context.Context) (*big.Int, error) {
+	return big.NewInt(c.gasPrice), nil
+}
+
+func bigOrNil(v int64) *big.Int {
+	if v == 0 {
+		return nil
+	}
+	return big.NewInt(v)
+}
+
+func TestFeeHistoryStrategy(t *testing.T) {
+	flat := []int64{1000, 1000, 1000, 1000}
+	rewards := [][]int64{{10}, {30}, {20}}
+
+	tests := []struct {
+		name           string
+		baseFees       []int64
+		rewards        [][]int64
+		speed          Speed
+		maxFee, maxTip int64 // 请求覆盖值，0 表示不覆盖
+		feeCap         int64 // 链上限，0 表示不限
+		wantPercentile float64
+		wantTip        int64
+		wantFeeCap     int64
+		wantErr        error
+	}{
+		// baseFee 1000 每块预留 12.5%（向上取整）：1125、1266、1425、1604、1805、2031
+		{name: "standard", baseFees: flat, rewards: rewards, speed: SpeedStandard, wantPercentile: 50, wantTip: 20, wantFeeCap: 1425 + 20},
+		{name: "default speed", baseFees: flat, rewards: rewards, wantPercentile: 50, wantTip: 20, wantFeeCap: 1425 + 20},
+		{name: "slow", baseFees: flat, rewards: rewards, speed: SpeedSlow, wantPercentile: 10, wantTip: 20, wantFeeCap: 1125 + 20},
+		{name: "fast", baseFees: flat, rewards: rewards, speed: SpeedFast, wantPercentile: 90, wantTip: 20, wantFeeCap: 2031 + 20},
+		{name: "rising base fee adds a block", baseFees: []int64{900, 900, 900, 1000}, rewards: rewards, wantPercentile: 50, wantTip: 20, wantFeeCap: 1604 + 20},
+		{name: "median of even count", baseFees: flat, rewards: [][]int64{{40}, {10}, {30}, {20}}, wantPercentile: 50, wantTip: 30, wantFeeCap: 1425 + 30},
+		{name: "empty blocks use node tip", baseFees: flat, rewards: [][]int64{{0}, {}, {0}}, wantPercentile: 50, wantTip: 7, wantFeeCap: 1425 + 7},
+		{name: "tip override", baseFees: flat, rewards: rewards, maxTip: 5, wantPercentile: 50, wantTip: 5, wantFeeCap: 1425 + 5},
+		{name: "max fee override", baseFees: flat, rewards: rewards, maxFee: 3000, wantPercentile: 50, wantTip: 20, wantFeeCap: 3000},
+		{name: "capped", baseFees: flat, rewards: rewards, feeCap: 1200, wantPercentile: 50, wantTip: 20, wantFeeCap: 1200},
+		{name: "override capped", baseFees: flat, rewards: rewards, maxFee: 3000, feeCap: 1200, wantPercentile: 50, wantTip: 20, wantFeeCap: 1200},
+		{name: "tip clipped to fee cap", baseFees: flat, rewards: rewards, maxTip: 5000, feeCap: 1200, wantPercentile: 50, wantTip: 1200, wantFeeCap: 1200},
+		{name: "base fee above cap", baseFees: flat, rewards: rewards, feeCap: 900, wantErr: ErrBaseFeeAboveCap},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &feeClient{baseFees: tt.baseFees, rewards: tt.rewards, tipCap: 7}
+			fee, err := NewFeeHistoryStrategy().Suggest(context.Background(), client, GasOptions{
+				Speed:        tt.speed,
+				MaxFeePerGas: bigOrNil(tt.maxFee),
+				MaxTipPerGas: bigOrNil(tt.maxTip),
+				FeeCap:       bigOrNil(tt.feeCap),
+			})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(client.percentiles) != 1 || client.percentiles[0] != tt.wantPercentile {
+				t.Fatalf("requested percentiles %v, want [%v]", client.percentiles, tt.wantPercentile)
+			}
+			if fee.Legacy || fee.GasTipCap.Int64() != tt.wantTip || fee.GasFeeCap.Int64() != tt.wantFeeCap {
+				t.Fatalf("fee = legacy %v tip %s feeCap %s, want tip %d feeCap %d", fee.Legacy, fee.GasTipCap, fee.GasFeeCap, tt.wantTip, tt.wantFeeCap)
+			}
+			if fee.BaseFee.Int64() != tt.baseFees[len(tt.baseFees)-1] {
+				t.Fatalf("base fee = %s, want next block base fee %d", fee.BaseFee, tt.baseFees[len(tt.baseFees)-1])
+			}
+		})
+	}
+}
+
+func TestFeeHistoryStrategyLegacy(t *testing.T) {
+	tests := []struct {
+		name   string
+		speed  Speed
+		maxFee int64
+		feeCap int64
+		want   int64
+	}{
+		{name: "slow", speed: SpeedSlow, want: 1000},
+		{name: "standard", speed: SpeedStandard, want: 1100},
+		{name: "fast", speed: SpeedFast, want: 1250},
+		{name: "capped", speed: SpeedFast, feeCap: 1200, want: 1200},
+		{name: "override", speed: SpeedFast, maxFee: 777, want: 777},
+		{name: "override capped", maxFee: 5000, feeCap: 1200, want: 1200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &feeClient{gasPrice: 1000}
+			fee, err := NewFeeHistoryStrategy().Suggest(context.Background(), client, GasOptions{
+				Speed:        tt.speed,
+				MaxFeePerGas: bigOrNil(tt.maxFee),
+				FeeCap:       bigOrNil(tt.feeCap),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if client.percentiles != nil {
+				t.Fatal("legacy chain queried fee history")
+			}
+			if !fee.Legacy || fee.GasPrice.Int64() != tt.want || fee.GasFeeCap != nil || fee.GasTipCap != nil {
+				t.Fatalf("fee = %+v, want legacy gas price %d", fee, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package trans
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-web3/internal/config"
+	"go-web3/internal/infra/eth/chainclient"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// TransferMethod 无 calldata 的转账在 GasLimitPolicy.Methods 中的 key
+const TransferMethod = "transfer"
+
+// GasLimitRule gas limit = max(估算值 × Multiplier, Floor)，不低于估算值
+type GasLimitRule struct {
+	Multiplier float64
+	Floor      uint64
+}
+
+// GasLimitPolicy 估算 gas 后预留的余量：依赖链上状态的代码路径在打包时可能比估算时消耗更多 gas
+type GasLimitPolicy struct {
+	Default GasLimitRule
+	Methods map[string]GasLimitRule // key 为 4 字节 selector（0x 开头的小写十六进制）或 TransferMethod
+}
+
+// DefaultGasLimitPolicy 未配置时估算值上浮 20%
+var DefaultGasLimitPolicy = &GasLimitPolicy{Default: GasLimitRule{Multiplier: 1.2}}
+
+// NewGasLimitPolicy 由配置创建。Methods 中的方法可以写函数签名（如 cancelAuction(uint256)）或 selector（如 0x96b5a755）。
+// 默认倍数与方法倍数都不能小于 1
+func NewGasLimitPolicy(cfg config.GasLimitConfig) (*GasLimitPolicy, error) {
+	if cfg.Multiplier < 1 {
+		return nil, fmt.Errorf("invalid default gas limit multiplier %v, must be at least 1", cfg.Multiplier)
+	}
+	p := &GasLimitPolicy{
+		Default: GasLimitRule{Multiplier: cfg.Multiplier, Floor: cfg.Floor},
+		Methods: map[string]GasLimitRule{},
+	}
+	for _, item := range strings.Split(cfg.Methods, ";") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		method, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid gas limit rule %q, expect method=multiplier[:floor]", item)
+		}
+		rule := p.Default
+		multiplier, floor, hasFloor := strings.Cut(value, ":")
+		var err error
+		if rule.Multiplier, err = strconv.ParseFloat(multiplier, 64); err != nil || rule.Multiplier < 1 {
+			return nil, fmt.Errorf("invalid gas limit multiplier in %q", item)
+		}
+		if hasFloor {
+			if rule.Floor, err = strconv.ParseUint(floor, 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid gas limit floor in %q", item)
+			}
+		}
+		p.Methods[methodKey(strings.TrimSpace(method))] = rule
+	}
+	return p, nil
+}
+
+// methodKey 函数签名转换为 selector，selector 与 transfer 原样返回
+func methodKey(method string) string {
+	if method == TransferMethod {
+		return method
+	}
+	if strings.HasPrefix(method, "0x") && len(method) == 10 {
+		return strings.ToLower(method)
+	}
+	return hexutil.Encode(crypto.Keccak256([]byte(strings.ReplaceAll(method, " ", "")))[:4])
+}
+
+// Limit 按交易 calldata 匹配的规则计算 gas limit
+func (p *GasLimitPolicy) Limit(data []byte, estimated uint64) uint64 {
+	rule := p.Default
+	key := TransferMethod
+	if len(data) >= 4 {
+		key = hexutil.Encode(data[:4])
+	}
+	if r, ok := p.Methods[key]; ok {
+		rule = r
+	}
+
+	limit := estimated
+	if rule.Multiplier > 1 {
+		limit = uint64(math.Ceil(float64(estimated) * rule.Multiplier))
+	}
+	return max(limit, rule.Floor)
+}
+
+// InsufficientFundsError 账户余额不足以支付最坏情况下的交易费用（gasLimit × maxFeePerGas + value）
+type InsufficientFundsError struct {
+	Address   common.Address
+	Balance   *big.Int // wei
+	Cost      *big.Int // wei
+	Shortfall *big.Int // wei
+}
+
+// MarshalJSON 金额以十进制字符串输出，避免客户端精度丢失
+func (e *InsufficientFundsError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{
+		"address":   e.Address.Hex(),
+		"balance":   e.Balance.String(),
+		"cost":      e.Cost.String(),
+		"shortfall": e.Shortfall.String(),
+	})
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("insufficient funds for gas * price + value: address %s have %s want %s, short %s wei",
+		e.Address.Hex(), e.Balance, e.Cost, e.Shortfall)
+}
+
+// MaxCost 交易最坏情况下的费用：gasLimit × maxFeePerGas（legacy 为 gasPrice）+ value
+func MaxCost(gas uint64, maxPerGas, value *big.Int) *big.Int {
+	cost := new(big.Int).Mul(new(big.Int).SetUint64(gas), maxPerGas)
+	if value != nil {
+		cost.Add(cost, value)
+	}
+	return cost
+}
+
+// Preflight 发送前检查账户余额足以支付 cost，不足时返回 *InsufficientFundsError
+func Preflight(ctx context.Context, client chainclient.Client, from common.Address, cost *big.Int) error {
+	balance, err := client.BalanceAt(ctx, from, nil)
+	if err != nil {
+		return err
+	}
+	if balance.Cmp(cost) >= 0 {
+		return nil
+	}
+	return &InsufficientFundsError{
+		Address:   from,
+		Balance:   balance,
+		Cost:      cost,
+		Shortfall: new(big.Int).Sub(cost, balance),
+	}
+}
+
+// SendResult 交易发送结果
+type SendResult struct {
+	TxHash   string `json:"txHash"`
+	GasLimit uint64 `json:"gasLimit"`
+	MaxCost  string `json:"maxCost"` // 最坏情况下的费用（wei）：gasLimit × maxFeePerGas + value
+}
+
+func NewSendResult(tx *types.Transaction) *SendResult {
+	return &SendResult{
+		TxHash:   tx.Hash().Hex(),
+		GasLimit: tx.Gas(),
+		MaxCost:  tx.Cost().String(),
+	}
+}
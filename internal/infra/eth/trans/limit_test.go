@@ -0,0 +1,28 @@
+package trans
+
+import (
+	"go-web3/internal/config"
+	"testing"
+)
+
+func TestNewGasLimitPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.GasLimitConfig
+		wantErr bool
+	}{
+		{"default multiplier", config.GasLimitConfig{Multiplier: 1.2}, false},
+		{"method rules", config.GasLimitConfig{Multiplier: 1.2, Methods: "cancelAuction(uint256)=1.5:80000;transfer=1"}, false},
+		{"default multiplier below 1", config.GasLimitConfig{Multiplier: 0.8}, true},
+		{"default multiplier unset", config.GasLimitConfig{}, true},
+		{"method multiplier below 1", config.GasLimitConfig{Multiplier: 1.2, Methods: "transfer=0.5"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewGasLimitPolicy(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,371 @@
+package trans
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-web3/internal/infra/eth"
+	"go-web3/internal/infra/eth/signer"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrNoSender 发送账户池中没有可用账户（余额不足或连续失败暂停中）
+var ErrNoSender = errors.New("no healthy sender available")
+
+// SenderPinKey 固定发送账户的 redis key 前缀，完整 key 为 sender:pin:<chainID>:<pin>
+const SenderPinKey = "sender:pin:"
+
+// SenderPool 发送账户池（热钱包）：每次发送按链选择在途交易最少、余额充足且近期没有连续失败的账户。
+// 指定 pin 的请求固定使用同一账户，如同一拍卖的结算与取消
+type SenderPool struct {
+	Redis       *redis.Client
+	MinBalance  *big.Int      // 余额低于该值（wei）的账户不参与选择，nil 不检查
+	BalanceTTL  time.Duration // 余额缓存时间，默认 15s
+	PinTTL      time.Duration // pin 有效期，默认 24h
+	MaxFailures int           // 连续失败次数达到该值后暂停使用，默认 3
+	Cooldown    time.Duration // 暂停时间，默认 1m
+
+	signers []signer.Signer
+	byAddr  map[common.Address]signer.Signer
+
+	mu     sync.Mutex
+	states map[senderKey]*senderState
+}
+
+type senderKey struct {
+	chainID uint64
+	addr    common.Address
+}
+
+type senderState struct {
+	inFlight      int // 本实例正在使用该账户发送的请求数
+	failures      int // 连续失败次数
+	lastError     string
+	cooldownUntil time.Time
+	balance       *big.Int
+	balanceAt     time.Time
+	lastUsed      time.Time
+}
+
+// SenderState 发送账户在一条链上的状态，用于管理接口
+type SenderState struct {
+	Address       common.Address `json:"address"`
+	Chain         string         `json:"chain"`
+	InFlight      int            `json:"inFlight"` // 本实例正在发送的请求数
+	Pending       int            `json:"pending"`  // 已分配 nonce 但未打包的交易数
+	Balance       string         `json:"balance"`  // wei
+	Healthy       bool           `json:"healthy"`
+	Failures      int            `json:"failures"`
+	LastError     string         `json:"lastError,omitempty"`
+	CooldownUntil *time.Time     `json:"cooldownUntil,omitempty"`
+	LastUsed      *time.Time     `json:"lastUsed,omitempty"`
+}
+
+// NewSenderPool 创建发送账户池，signers 至少包含一个账户，第一个为默认账户
+func NewSenderPool(rdb *redis.Client, signers []signer.Signer) (*SenderPool, error) {
+	if len(signers) == 0 {
+		return nil, errors.New("sender pool requires at least one signer")
+	}
+	byAddr := map[common.Address]signer.Signer{}
+	for _, s := range signers {
+		byAddr[s.Address()] = s
+	}
+	return &SenderPool{
+		Redis:   rdb,
+		signers: signers,
+		byAddr:  byAddr,
+		states:  map[senderKey]*senderState{},
+	}, nil
+}
+
+// Signers 池中全部账户，第一个为默认账户
+func (p *SenderPool) Signers() []signer.Signer {
+	return p.signers
+}
+
+// Primary 默认账户，未指定发送账户的操作使用
+func (p *SenderPool) Primary() signer.Signer {
+	return p.signers[0]
+}
+
+// Signer 按地址查找池中的账户
+func (p *SenderPool) Signer(addr common.Address) (signer.Signer, bool) {
+	s, ok := p.byAddr[addr]
+	return s, ok
+}
+
+// Addresses 池中全部账户地址
+func (p *SenderPool) Addresses() []common.Address {
+	list := make([]common.Address, 0, len(p.signers))
+	for _, s := range p.signers {
+		list = append(list, s.Address())
+	}
+	return list
+}
+
+// Lease 一次发送占用的账户，发送结束后调用 Release
+type Lease struct {
+	Signer signer.Signer
+
+	pool *SenderPool
+	key  senderKey
+	once sync.Once
+}
+
+// Acquire 为一次发送选择账户。pin 不为空时同一 pin 固定使用首次选中的账户，
+// 该账户之后暂停或余额不足也不会切换，保证相关交易由同一账户按 nonce 顺序发出
+func (p *SenderPool) Acquire(ctx context.Context, chain *eth.Chain, pin string) (*Lease, error) {
+	var (
+		s   signer.Signer
+		err error
+	)
+	if pin != "" {
+		s, err = p.pinned(ctx, chain, pin)
+	} else {
+		s, err = p.choose(ctx, chain)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := senderKey{chain.ID, s.Address()}
+	p.mu.Lock()
+	st := p.state(key)
+	st.inFlight++
+	st.lastUsed = time.Now()
+	p.mu.Unlock()
+	return &Lease{Signer: s, pool: p, key: key}, nil
+}
+
+// Release 结束发送。err 为余额不足、nonce 冲突等账户问题时计入连续失败
+func (l *Lease) Release(err error) {
+	l.once.Do(func() {
+		p := l.pool
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		st := p.state(l.key)
+		st.inFlight--
+		if err == nil {
+			st.failures = 0
+			return
+		}
+		if !isSenderError(err) {
+			return
+		}
+		st.failures++
+		st.lastError = err.Error()
+		if strings.Contains(err.Error(), "insufficient funds") {
+			st.balanceAt = time.Time{}
+		}
+		if st.failures >= p.maxFailures() {
+			st.cooldownUntil = time.Now().Add(p.cooldown())
+			st.failures = 0
+		}
+	})
+}
+
+// isSenderError 与发送账户本身有关的错误，换一个账户可能成功
+func isSenderError(err error) bool {
+	msg := err.Error()
+	for _, s := range []string{"insufficient funds", "nonce too", "underpriced", "already known", "lock wait timeout", "remote sign"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// pinned pin 对应的账户，没有时选择一个并记录
+func (p *SenderPool) pinned(ctx context.Context, chain *eth.Chain, pin string) (signer.Signer, error) {
+	key := fmt.Sprintf("%s%d:%s", SenderPinKey, chain.ID, pin)
+	for {
+		addr, err := p.Redis.Get(ctx, key).Result()
+		if err == nil {
+			if s, ok := p.Signer(common.HexToAddress(addr)); ok {
+				return s, nil
+			}
+			// 账户已移出池，重新选择
+			if err := p.Redis.Del(ctx, key).Err(); err != nil {
+				return nil, err
+			}
+		} else if !errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+
+		s, err := p.choose(ctx, chain)
+		if err != nil {
+			return nil, err
+		}
+		ok, err := p.Redis.SetNX(ctx, key, s.Address().Hex(), p.pinTTL()).Result()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return s, nil
+		}
+		// 其他请求同时记录了 pin，使用它选中的账户
+	}
+}
+
+// choose 选择在途交易最少的健康账户，相同时选择最久未使用的
+func (p *SenderPool) choose(ctx context.Context, chain *eth.Chain) (signer.Signer, error) {
+	// 在途 nonce 数与余额需要查询 Redis 与节点，不持有锁
+	pending := make([]int, len(p.signers))
+	healthy := make([]bool, len(p.signers))
+	for i, s := range p.signers {
+		n, err := p.pending(ctx, chain, s.Address())
+		if err != nil {
+			return nil, err
+		}
+		pending[i] = n
+		healthy[i] = p.balanceOK(ctx, chain, s.Address())
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	best := -1
+	var bestLoad int
+	var bestUsed time.Time
+	for i, s := range p.signers {
+		st := p.state(senderKey{chain.ID, s.Address()})
+		if !healthy[i] || now.Before(st.cooldownUntil) {
+			continue
+		}
+		load := st.inFlight + pending[i]
+		if best < 0 || load < bestLoad || (load == bestLoad && st.lastUsed.Before(bestUsed)) {
+			best, bestLoad, bestUsed = i, load, st.lastUsed
+		}
+	}
+	if best < 0 {
+		return nil, fmt.Errorf("%w on chain %s", ErrNoSender, chain.Name)
+	}
+	return p.signers[best], nil
+}
+
+// pending 已分配 nonce 但未打包的交易数
+func (p *SenderPool) pending(ctx context.Context, chain *eth.Chain, addr common.Address) (int, error) {
+	if chain.NonceMgr == nil {
+		return 0, nil
+	}
+	list, err := chain.NonceMgr.InFlight(ctx, addr)
+	if err != nil {
+		return 0, err
+	}
+	return len(list), nil
+}
+
+// balanceOK 余额不低于 MinBalance。查询失败时沿用上次结果，从未查到时视为可用
+func (p *SenderPool) balanceOK(ctx context.Context, chain *eth.Chain, addr common.Address) bool {
+	if p.MinBalance == nil {
+		return true
+	}
+	balance := p.balance(ctx, chain, addr)
+	return balance == nil || balance.Cmp(p.MinBalance) >= 0
+}
+
+// balance 带缓存的账户余额
+func (p *SenderPool) balance(ctx context.Context, chain *eth.Chain, addr common.Address) *big.Int {
+	key := senderKey{chain.ID, addr}
+	p.mu.Lock()
+	st := p.state(key)
+	cached, at := st.balance, st.balanceAt
+	p.mu.Unlock()
+	if cached != nil && time.Since(at) < p.balanceTTL() {
+		return cached
+	}
+
+	balance, err := chain.Client.BalanceAt(ctx, addr, nil)
+	if err != nil {
+		return cached
+	}
+	p.mu.Lock()
+	st.balance, st.balanceAt = balance, time.Now()
+	p.mu.Unlock()
+	return balance
+}
+
+// States 各账户在链上的状态
+func (p *SenderPool) States(ctx context.Context, chain *eth.Chain) ([]SenderState, error) {
+	list := make([]SenderState, 0, len(p.signers))
+	for _, s := range p.signers {
+		addr := s.Address()
+		pending, err := p.pending(ctx, chain, addr)
+		if err != nil {
+			return nil, err
+		}
+		balance := p.balance(ctx, chain, addr)
+
+		p.mu.Lock()
+		st := p.state(senderKey{chain.ID, addr})
+		state := SenderState{
+			Address:   addr,
+			Chain:     chain.Name,
+			InFlight:  st.inFlight,
+			Pending:   pending,
+			Healthy:   time.Now().After(st.cooldownUntil) && (p.MinBalance == nil || balance == nil || balance.Cmp(p.MinBalance) >= 0),
+			Failures:  st.failures,
+			LastError: st.lastError,
+		}
+		if balance != nil {
+			state.Balance = balance.String()
+		}
+		if time.Now().Before(st.cooldownUntil) {
+			until := st.cooldownUntil
+			state.CooldownUntil = &until
+		}
+		if !st.lastUsed.IsZero() {
+			used := st.lastUsed
+			state.LastUsed = &used
+		}
+		p.mu.Unlock()
+		list = append(list, state)
+	}
+	return list, nil
+}
+
+// state 调用方持有 mu
+func (p *SenderPool) state(key senderKey) *senderState {
+	st, ok := p.states[key]
+	if !ok {
+		st = &senderState{}
+		p.states[key] = st
+	}
+	return st
+}
+
+func (p *SenderPool) balanceTTL() time.Duration {
+	if p.BalanceTTL > 0 {
+		return p.BalanceTTL
+	}
+	return 15 * time.Second
+}
+
+func (p *SenderPool) pinTTL() time.Duration {
+	if p.PinTTL > 0 {
+		return p.PinTTL
+	}
+	return 24 * time.Hour
+}
+
+func (p *SenderPool) maxFailures() int {
+	if p.MaxFailures > 0 {
+		return p.MaxFailures
+	}
+	return 3
+}
+
+func (p *SenderPool) cooldown() time.Duration {
+	if p.Cooldown > 0 {
+		return p.Cooldown
+	}
+	return time.Minute
+}
@@ -0,0 +1,199 @@
+package trans
+
+import (
+	"context"
+	"errors"
+	"go-web3/internal/infra/eth"
+	"go-web3/internal/infra/eth/chainclient/chaintest"
+	"go-web3/internal/infra/eth/nonce"
+	"go-web3/internal/infra/eth/signer"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/redis/go-redis/v9"
+)
+
+var oneEther = big.NewInt(1e18)
+
+// newTestPool 模拟链上的发送账户池，balances 为各账户的创世余额
+func newTestPool(t *testing.T, balances ...*big.Int) (*SenderPool, *eth.Chain, []common.Address) {
+	t.Helper()
+	alloc := types.GenesisAlloc{}
+	var (
+		signers []signer.Signer
+		addrs   []common.Address
+	)
+	for _, balance := range balances {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := signer.NewKeySigner(key)
+		alloc[s.Address()] = types.Account{Balance: balance}
+		signers = append(signers, s)
+		addrs = append(addrs, s.Address())
+	}
+	sim := chaintest.NewSimulated(alloc)
+	t.Cleanup(func() { _ = sim.Close() })
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	pool, err := NewSenderPool(rdb, signers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pool, eth.NewChain(1337, "sim", sim, nonce.NewNonceManager(rdb, sim, 1337)), addrs
+}
+
+// reserveNonces 为账户预留 n 个 nonce，模拟已分配未打包的交易
+func reserveNonces(t *testing.T, chain *eth.Chain, addr common.Address, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if _, err := chain.NonceMgr.Reserve(context.Background(), addr); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func acquire(t *testing.T, pool *SenderPool, chain *eth.Chain, pin string) *Lease {
+	t.Helper()
+	lease, err := pool.Acquire(context.Background(), chain, pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lease
+}
+
+func TestNewSenderPoolRequiresSigners(t *testing.T) {
+	if _, err := NewSenderPool(nil, nil); err == nil {
+		t.Fatal("want error for empty signer list")
+	}
+}
+
+// TestChooseLeastPending 选择在途交易（已分配 nonce + 本实例发送中）最少的账户，相同时选择最久未使用的
+func TestChooseLeastPending(t *testing.T) {
+	pool, chain, addrs := newTestPool(t, oneEther, oneEther, oneEther)
+	a, b, c := addrs[0], addrs[1], addrs[2]
+	reserveNonces(t, chain, a, 2)
+	reserveNonces(t, chain, b, 1)
+
+	first := acquire(t, pool, chain, "")
+	if first.Signer.Address() != c {
+		t.Fatalf("chose %s, want %s with no pending nonce", first.Signer.Address().Hex(), c.Hex())
+	}
+	// b 与发送中的 c 都是 1，选择从未使用过的 b
+	second := acquire(t, pool, chain, "")
+	if second.Signer.Address() != b {
+		t.Fatalf("chose %s, want least recently used %s", second.Signer.Address().Hex(), b.Hex())
+	}
+
+	first.Release(nil)
+	second.Release(nil)
+	if got := acquire(t, pool, chain, "").Signer.Address(); got != c {
+		t.Fatalf("chose %s after release, want %s", got.Hex(), c.Hex())
+	}
+}
+
+// TestChooseSkipsLowBalance 余额低于 MinBalance 的账户不参与选择，全部不足时返回 ErrNoSender
+func TestChooseSkipsLowBalance(t *testing.T) {
+	half := new(big.Int).Div(oneEther, big.NewInt(2))
+	pool, chain, addrs := newTestPool(t, oneEther, half)
+	pool.MinBalance = oneEther
+	reserveNonces(t, chain, addrs[0], 3)
+
+	for i := 0; i < 3; i++ {
+		if got := acquire(t, pool, chain, "").Signer.Address(); got != addrs[0] {
+			t.Fatalf("chose %s, want funded %s despite pending nonces", got.Hex(), addrs[0].Hex())
+		}
+	}
+
+	pool.MinBalance = new(big.Int).Mul(oneEther, big.NewInt(2))
+	if _, err := pool.Acquire(context.Background(), chain, ""); !errors.Is(err, ErrNoSender) {
+		t.Fatalf("err = %v, want ErrNoSender", err)
+	}
+}
+
+// TestChooseSkipsCoolingDown 连续账户错误达到阈值的账户暂停使用，其他错误不计入
+func TestChooseSkipsCoolingDown(t *testing.T) {
+	pool, chain, addrs := newTestPool(t, oneEther, oneEther)
+	pool.MaxFailures = 2
+	reserveNonces(t, chain, addrs[1], 1)
+
+	acquire(t, pool, chain, "").Release(errors.New("execution reverted"))
+	for i := 0; i < 2; i++ {
+		lease := acquire(t, pool, chain, "")
+		if lease.Signer.Address() != addrs[0] {
+			t.Fatalf("attempt %d chose %s, want %s", i, lease.Signer.Address().Hex(), addrs[0].Hex())
+		}
+		lease.Release(errors.New("insufficient funds for gas * price + value"))
+	}
+	if got := acquire(t, pool, chain, "").Signer.Address(); got != addrs[1] {
+		t.Fatalf("chose %s, want %s while %s cools down", got.Hex(), addrs[1].Hex(), addrs[0].Hex())
+	}
+}
+
+// TestPinnedSticky 同一 pin 固定使用首次选中的账户，不同 pin 各自选择；pin 指向已移出池的账户时重新选择
+func TestPinnedSticky(t *testing.T) {
+	pool, chain, addrs := newTestPool(t, oneEther, oneEther)
+	ctx := context.Background()
+
+	pinned := acquire(t, pool, chain, "auction:1")
+	pinned.Release(nil)
+	// 被固定的账户变得最忙也不切换
+	reserveNonces(t, chain, pinned.Signer.Address(), 5)
+	for i := 0; i < 3; i++ {
+		if got := acquire(t, pool, chain, "auction:1").Signer.Address(); got != pinned.Signer.Address() {
+			t.Fatalf("pin moved to %s, want %s", got.Hex(), pinned.Signer.Address().Hex())
+		}
+	}
+	other := acquire(t, pool, chain, "auction:2").Signer.Address()
+	if other == pinned.Signer.Address() {
+		t.Fatal("new pin chose the busy pinned sender")
+	}
+
+	key := SenderPinKey + "1337:auction:3"
+	if err := pool.Redis.Set(ctx, key, common.HexToAddress("0x01").Hex(), 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	got := acquire(t, pool, chain, "auction:3").Signer.Address()
+	if got != addrs[0] && got != addrs[1] {
+		t.Fatalf("chose %s, want a pool sender", got.Hex())
+	}
+	if stored, _ := pool.Redis.Get(ctx, key).Result(); stored != got.Hex() {
+		t.Fatalf("pin stored %s, want %s", stored, got.Hex())
+	}
+}
+
+// TestPinnedConcurrent 同一新 pin 的并发请求都使用同一个账户
+func TestPinnedConcurrent(t *testing.T) {
+	pool, chain, _ := newTestPool(t, oneEther, oneEther, oneEther)
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		seen = map[common.Address]int{}
+	)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lease, err := pool.Acquire(context.Background(), chain, "auction:9")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			seen[lease.Signer.Address()]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if len(seen) != 1 {
+		t.Fatalf("pin resolved to %d senders: %v", len(seen), seen)
+	}
+}
@@ -0,0 +1,295 @@
+package trans
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-web3/internal/infra/eth"
+	"go-web3/internal/infra/eth/signer"
+	"go-web3/internal/infra/eth/txtrack"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrFeeCeiling 替换交易需要的 gas 费超过链配置的上限
+var ErrFeeCeiling = errors.New("replacement fee exceeds max fee cap")
+
+// BumpFees 计算替换交易的 tip 与 feeCap：节点要求两者都比原交易至少高 10%，这里提高 12.5% 留出余量，
+// 当前建议值更高时使用建议值。maxFeeCap 不为 nil 时 feeCap 不超过该值，截断后达不到 10% 返回 ErrFeeCeiling
+func BumpFees(oldTip, oldFeeCap, suggestTip, suggestFeeCap, maxFeeCap *big.Int) (*big.Int, *big.Int, error) {
+	tip := maxBig(bump(oldTip, 1125), suggestTip)
+	feeCap := maxBig(bump(oldFeeCap, 1125), suggestFeeCap)
+
+	if maxFeeCap != nil && feeCap.Cmp(maxFeeCap) > 0 {
+		feeCap = new(big.Int).Set(maxFeeCap)
+	}
+	if tip.Cmp(feeCap) > 0 {
+		tip = new(big.Int).Set(feeCap)
+	}
+	if feeCap.Cmp(bump(oldFeeCap, 1100)) < 0 || tip.Cmp(bump(oldTip, 1100)) < 0 {
+		return nil, nil, fmt.Errorf("%w: need %s wei, max %s wei", ErrFeeCeiling, bump(oldFeeCap, 1100), maxFeeCap)
+	}
+	return tip, feeCap, nil
+}
+
+// bump 返回 v * permille / 1000，向上取整
+func bump(v *big.Int, permille int64) *big.Int {
+	n := new(big.Int).Mul(v, big.NewInt(permille))
+	n.Add(n, big.NewInt(999))
+	return n.Div(n, big.NewInt(1000))
+}
+
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return new(big.Int).Set(a)
+	}
+	return new(big.Int).Set(b)
+}
+
+// Replacer 用同一 nonce 替换未打包的交易：加速（原交易内容 + 更高 gas 费）或取消（0 ETH 转给自己）。
+// Start 周期性对账账户池中每个账户在各链上的 nonce，用取消交易填补预留后未广播的空洞；
+// 并检查阻塞后续交易的 nonce，超过链配置的 StuckAfter 仍未打包时自动加速
+type Replacer struct {
+	Tracker  *txtrack.Tracker
+	Chains   *eth.Chains
+	Senders  *SenderPool
+	Gas      GasStrategy // nil 时使用 DefaultGasStrategy
+	Logger   *log.Logger
+	Interval time.Duration // 自动加速检查间隔，默认 30s
+}
+
+func NewReplacer(tracker *txtrack.Tracker, chains *eth.Chains, senders *SenderPool, logger *log.Logger) *Replacer {
+	return &Replacer{
+		Tracker: tracker,
+		Chains:  chains,
+		Senders: senders,
+		Logger:  logger,
+	}
+}
+
+// SpeedUp 以更高的 gas 费重发 from 在 nonce 上最近一笔未打包的交易
+func (r *Replacer) SpeedUp(ctx context.Context, chain *eth.Chain, from common.Address, nonce uint64, req txtrack.Request) (*txtrack.Tx, error) {
+	s, err := r.signer(from)
+	if err != nil {
+		return nil, err
+	}
+	orig, err := r.Tracker.Latest(ctx, chain.ID, from, nonce)
+	if errors.Is(err, txtrack.ErrTxNotFound) {
+		return nil, fmt.Errorf("no pending tx with nonce %d", nonce)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.replace(ctx, chain, s, nonce, orig, false, req)
+}
+
+// Cancel 用 0 ETH 转给自己的交易占用 from 的 nonce。nonce 上没有跟踪中的交易时按当前建议 gas 费发送
+func (r *Replacer) Cancel(ctx context.Context, chain *eth.Chain, from common.Address, nonce uint64, req txtrack.Request) (*txtrack.Tx, error) {
+	s, err := r.signer(from)
+	if err != nil {
+		return nil, err
+	}
+	orig, err := r.Tracker.Latest(ctx, chain.ID, from, nonce)
+	if err != nil && !errors.Is(err, txtrack.ErrTxNotFound) {
+		return nil, err
+	}
+	return r.replace(ctx, chain, s, nonce, orig, true, req)
+}
+
+// signer 账户池中 from 的签名器，替换交易必须由原交易的账户签名
+func (r *Replacer) signer(from common.Address) (signer.Signer, error) {
+	s, ok := r.Senders.Signer(from)
+	if !ok {
+		return nil, fmt.Errorf("sender %s is not managed", from.Hex())
+	}
+	return s, nil
+}
+
+func (r *Replacer) replace(ctx context.Context, chain *eth.Chain, s signer.Signer, nonce uint64, orig *txtrack.Tx, cancel bool, req txtrack.Request) (*txtrack.Tx, error) {
+	if orig != nil && orig.Status != txtrack.StatusPending {
+		return nil, fmt.Errorf("tx %s is %s, cannot replace", orig.ID, orig.Status)
+	}
+	from := s.Address()
+	used, err := chain.Client.NonceAt(ctx, from, nil)
+	if err != nil {
+		return nil, err
+	}
+	if used > nonce {
+		return nil, fmt.Errorf("nonce %d already used", nonce)
+	}
+
+	oldTip, oldFeeCap := new(big.Int), new(big.Int)
+	if orig != nil {
+		oldTip, oldFeeCap = txFees(orig)
+	}
+	// 当前建议值按请求的档位计算，上限由 BumpFees 处理
+	opts := GasOptionsOf(chain, req)
+	opts.FeeCap = nil
+	suggest, err := r.gas().Suggest(ctx, chain.Client, opts)
+	if err != nil {
+		return nil, err
+	}
+	suggestTip, suggestFeeCap := suggest.GasTipCap, suggest.GasFeeCap
+	if suggest.Legacy {
+		suggestTip, suggestFeeCap = suggest.GasPrice, suggest.GasPrice
+	}
+	tip, feeCap, err := BumpFees(oldTip, oldFeeCap, suggestTip, suggestFeeCap, chain.MaxFeeCap)
+	if err != nil {
+		return nil, err
+	}
+
+	// 不支持 EIP-1559 的链发送 legacy 交易，gasPrice 取提高后的 feeCap
+	fee := &Fee{GasTipCap: tip, GasFeeCap: feeCap}
+	if suggest.Legacy {
+		fee = &Fee{Legacy: true, GasPrice: feeCap}
+	}
+	to, value, gas, data := &from, big.NewInt(0), uint64(21000), []byte(nil)
+	if !cancel {
+		value, _ = new(big.Int).SetString(orig.Value, 10)
+		to, gas, data = orig.To, orig.Gas, orig.Data
+	}
+	chainID := new(big.Int).SetUint64(chain.ID)
+
+	signTx, err := s.SignTx(ctx, fee.NewTx(chainID, nonce, to, value, gas, data), chainID)
+	if err != nil {
+		return nil, err
+	}
+	if err := chain.Client.SendTransaction(ctx, signTx); err != nil {
+		return nil, err
+	}
+	if chain.NonceMgr != nil {
+		if err := chain.NonceMgr.MarkSent(ctx, from, nonce, signTx.Hash()); err != nil {
+			r.Logger.Printf("[%s] mark nonce %d of %s sent: %v", chain.Name, nonce, from.Hex(), err)
+		}
+	}
+
+	if orig != nil {
+		req.Replaces = orig.ID
+		if req.CallbackURL == "" {
+			req.CallbackURL = orig.CallbackURL
+		}
+	}
+	return r.Tracker.Track(ctx, chain, signTx, req)
+}
+
+// txFees 原交易的 tip 与 feeCap，legacy 交易两者都取 gasPrice
+func txFees(tx *txtrack.Tx) (*big.Int, *big.Int) {
+	parse := func(s string) *big.Int {
+		v, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return new(big.Int)
+		}
+		return v
+	}
+	if tx.GasFeeCap == "" {
+		price := parse(tx.GasPrice)
+		return price, new(big.Int).Set(price)
+	}
+	return parse(tx.GasTipCap), parse(tx.GasFeeCap)
+}
+
+// Start 周期性自动加速卡住的交易，阻塞直到 ctx 取消
+func (r *Replacer) Start(ctx context.Context) {
+	ticker := time.NewTicker(r.interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.Logger.Println("tx replacer stopped")
+			return
+		case <-ticker.C:
+			r.fillGaps(ctx)
+			if err := r.speedUpStuck(ctx); err != nil && ctx.Err() == nil {
+				r.Logger.Printf("speed up error: %v", err)
+			}
+		}
+	}
+}
+
+// fillGaps 对账各账户在各链上的 nonce，空洞用 0 ETH 转给自己的交易占用
+func (r *Replacer) fillGaps(ctx context.Context) {
+	for _, chain := range r.Chains.All() {
+		if chain.NonceMgr == nil {
+			continue
+		}
+		for _, s := range r.Senders.Signers() {
+			from := s.Address()
+			gaps, err := chain.NonceMgr.Reconcile(ctx, from)
+			if err != nil {
+				if ctx.Err() == nil {
+					r.Logger.Printf("[%s] reconcile nonce of %s: %v", chain.Name, from.Hex(), err)
+				}
+				continue
+			}
+			for _, nonce := range gaps {
+				rec, err := r.Cancel(ctx, chain, from, nonce, txtrack.Request{})
+				if err != nil {
+					r.Logger.Printf("[%s] fill nonce gap %d of %s: %v", chain.Name, nonce, from.Hex(), err)
+					continue
+				}
+				r.Logger.Printf("[%s] nonce gap %d of %s filled by %s", chain.Name, nonce, from.Hex(), rec.Hash.Hex())
+			}
+		}
+	}
+}
+
+// speedUpStuck 只处理各账户链上当前 nonce 的交易：它打包之前该账户后续 nonce 都无法打包
+func (r *Replacer) speedUpStuck(ctx context.Context) error {
+	for _, chain := range r.Chains.All() {
+		if chain.StuckAfter <= 0 {
+			continue
+		}
+		for _, s := range r.Senders.Signers() {
+			if err := r.speedUpSender(ctx, chain, s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Replacer) speedUpSender(ctx context.Context, chain *eth.Chain, s signer.Signer) error {
+	from := s.Address()
+	nonce, err := chain.Client.NonceAt(ctx, from, nil)
+	if err != nil {
+		r.Logger.Printf("[%s] nonce of %s: %v", chain.Name, from.Hex(), err)
+		return nil
+	}
+	latest, err := r.Tracker.Latest(ctx, chain.ID, from, nonce)
+	if errors.Is(err, txtrack.ErrTxNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if latest.Status != txtrack.StatusPending || time.Since(latest.CreatedAt) < chain.StuckAfter {
+		return nil
+	}
+
+	rec, err := r.replace(ctx, chain, s, nonce, latest, false, txtrack.Request{})
+	if err != nil {
+		r.Logger.Printf("[%s] speed up nonce %d of %s (%s): %v", chain.Name, nonce, from.Hex(), latest.ID, err)
+		return nil
+	}
+	r.Logger.Printf("[%s] nonce %d of %s stuck for %s, speed up %s → %s", chain.Name, nonce, from.Hex(),
+		time.Since(latest.CreatedAt).Round(time.Second), latest.Hash.Hex(), rec.Hash.Hex())
+	return nil
+}
+
+func (r *Replacer) gas() GasStrategy {
+	if r.Gas != nil {
+		return r.Gas
+	}
+	return DefaultGasStrategy
+}
+
+func (r *Replacer) interval() time.Duration {
+	if r.Interval > 0 {
+		return r.Interval
+	}
+	return 30 * time.Second
+}
@@ -0,0 +1,86 @@
+package trans
+
+import (
+	"context"
+	"go-web3/internal/infra/eth"
+	"go-web3/internal/infra/eth/chainclient"
+	"go-web3/internal/infra/eth/chainclient/chaintest"
+	"go-web3/internal/infra/eth/nonce"
+	"go-web3/internal/infra/eth/signer"
+	"go-web3/internal/infra/eth/txtrack"
+	"io"
+	"log"
+	"math/big"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/redis/go-redis/v9"
+)
+
+// legacyGas 模拟不支持 EIP-1559 的链：只返回 gasPrice
+type legacyGas struct{ price *big.Int }
+
+func (g legacyGas) Suggest(context.Context, chainclient.Client, GasOptions) (*Fee, error) {
+	return &Fee{Legacy: true, GasPrice: new(big.Int).Set(g.price)}, nil
+}
+
+func TestReplaceOnLegacyChain(t *testing.T) {
+	ctx := context.Background()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	sim := chaintest.NewSimulated(types.GenesisAlloc{from: {Balance: big.NewInt(1e18)}})
+	defer sim.Close()
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	defer rdb.Close()
+
+	chain := eth.NewChain(1337, "sim", sim, nonce.NewNonceManager(rdb, sim, 1337))
+	chains := eth.NewChains()
+	if err := chains.Register(chain); err != nil {
+		t.Fatal(err)
+	}
+	logger := log.New(io.Discard, "", 0)
+	tracker := txtrack.NewTracker(txtrack.NewRedisStore(rdb), chains, txtrack.NewWebhookNotifier(), logger)
+	pool, err := NewSenderPool(rdb, []signer.Signer{signer.NewKeySigner(key)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	replacer := NewReplacer(tracker, chains, pool, logger)
+	price := big.NewInt(2e9)
+	replacer.Gas = legacyGas{price: price}
+
+	// 取消（填补空洞）与加速都应发出 legacy 交易，加速后的 gasPrice 至少提高 10%
+	cancelled, err := replacer.Cancel(ctx, chain, from, 0, txtrack.Request{})
+	if err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	spedUp, err := replacer.SpeedUp(ctx, chain, from, 0, txtrack.Request{})
+	if err != nil {
+		t.Fatalf("speed up: %v", err)
+	}
+
+	for _, rec := range []*txtrack.Tx{cancelled, spedUp} {
+		if rec.GasPrice == "" || rec.GasFeeCap != "" {
+			t.Fatalf("tx %s is not legacy: gasPrice %q, maxFeePerGas %q", rec.Hash.Hex(), rec.GasPrice, rec.GasFeeCap)
+		}
+	}
+	first, _ := new(big.Int).SetString(cancelled.GasPrice, 10)
+	second, _ := new(big.Int).SetString(spedUp.GasPrice, 10)
+	minPrice := new(big.Int).Div(new(big.Int).Mul(first, big.NewInt(110)), big.NewInt(100))
+	if second.Cmp(minPrice) < 0 {
+		t.Fatalf("speed-up gas price %s, want at least %s", second, minPrice)
+	}
+
+	sim.Commit()
+	receipt, err := sim.TransactionReceipt(ctx, spedUp.Hash)
+	if err != nil {
+		t.Fatalf("speed-up tx not mined: %v", err)
+	}
+	if receipt.Type != types.LegacyTxType || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("speed-up tx mined with type %d status %d, want successful legacy tx", receipt.Type, receipt.Status)
+	}
+}
@@ -0,0 +1,269 @@
+package trans
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-web3/internal/infra/eth"
+	"go-web3/internal/infra/eth/signer"
+	"go-web3/internal/infra/eth/txtrack"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrTopUpCap 当天从资金账户补充的总额已达上限
+var ErrTopUpCap = errors.New("daily top-up cap reached")
+
+// 余额补充的 redis key：同一账户的补充间隔 topup:last:<chainID>:<addr>，每天的补充总额（gwei） topup:daily:<chainID>:<yyyymmdd>
+const (
+	topUpLastKey  = "topup:last:"
+	topUpDailyKey = "topup:daily:"
+)
+
+var gwei = big.NewInt(1e9)
+
+// BalanceAlert 发送账户余额告警，POST 到 AlertURL
+type BalanceAlert struct {
+	Chain     string         `json:"chain"`
+	ChainID   uint64         `json:"chainId"`
+	Address   common.Address `json:"address"`
+	Balance   string         `json:"balance"`   // wei
+	Threshold string         `json:"threshold"` // wei
+	TopUpTx   string         `json:"topUpTx,omitempty"`
+	TopUpErr  string         `json:"topUpError,omitempty"`
+	Time      time.Time      `json:"time"`
+}
+
+// BalanceMonitor 周期性检查账户池中每个发送账户在各链上的余额，低于 Threshold 时告警；
+// 配置 Treasury 时用资金账户通过 Transactor 把余额补充到 Target，每条链每天补充总额不超过 DailyCap
+type BalanceMonitor struct {
+	Chains    *eth.Chains
+	Senders   *SenderPool
+	Tracker   *txtrack.Tracker
+	Redis     *redis.Client
+	Logger    *log.Logger
+	Threshold *big.Int // wei
+
+	AlertURL   string        // 告警 webhook，可选
+	AlertEvery time.Duration // 余额持续不足时重复告警的间隔，默认 1h
+
+	Treasury  signer.Signer   // 资金账户，nil 只告警
+	Target    *big.Int        // 补充到的余额（wei）
+	DailyCap  *big.Int        // 每条链每天补充总额上限（wei）
+	Cooldown  time.Duration   // 同一账户两次补充的最小间隔，等待补充交易上链，默认 10m
+	Gas       GasStrategy     // 补充交易的 gas 费策略，nil 时使用 DefaultGasStrategy
+	GasLimits *GasLimitPolicy // nil 时使用 DefaultGasLimitPolicy
+	Interval  time.Duration   // 检查间隔，默认 1m
+
+	client  *http.Client
+	mu      sync.Mutex
+	alerted map[senderKey]time.Time
+}
+
+func NewBalanceMonitor(chains *eth.Chains, senders *SenderPool, tracker *txtrack.Tracker, rdb *redis.Client,
+	threshold *big.Int, logger *log.Logger) *BalanceMonitor {
+	return &BalanceMonitor{
+		Chains:    chains,
+		Senders:   senders,
+		Tracker:   tracker,
+		Redis:     rdb,
+		Logger:    logger,
+		Threshold: threshold,
+		client:    &http.Client{Timeout: 5 * time.Second},
+		alerted:   map[senderKey]time.Time{},
+	}
+}
+
+// Start 周期性检查余额，阻塞直到 ctx 取消
+func (m *BalanceMonitor) Start(ctx context.Context) {
+	ticker := time.NewTicker(m.interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			m.Logger.Println("balance monitor stopped")
+			return
+		case <-ticker.C:
+			m.checkOnce(ctx)
+		}
+	}
+}
+
+func (m *BalanceMonitor) checkOnce(ctx context.Context) {
+	for _, chain := range m.Chains.All() {
+		for _, addr := range m.Senders.Addresses() {
+			if ctx.Err() != nil {
+				return
+			}
+			if err := m.check(ctx, chain, addr); err != nil {
+				m.Logger.Printf("[%s] check balance of %s: %v", chain.Name, addr.Hex(), err)
+			}
+		}
+	}
+}
+
+// check 检查单个账户，余额不足时补充并告警
+func (m *BalanceMonitor) check(ctx context.Context, chain *eth.Chain, addr common.Address) error {
+	balance, err := chain.Client.BalanceAt(ctx, addr, nil)
+	if err != nil {
+		return err
+	}
+	key := senderKey{chain.ID, addr}
+	if balance.Cmp(m.Threshold) >= 0 {
+		m.mu.Lock()
+		delete(m.alerted, key)
+		m.mu.Unlock()
+		return nil
+	}
+
+	alert := &BalanceAlert{
+		Chain:     chain.Name,
+		ChainID:   chain.ID,
+		Address:   addr,
+		Balance:   balance.String(),
+		Threshold: m.Threshold.String(),
+		Time:      time.Now(),
+	}
+	toppedUp := false
+	if m.Treasury != nil {
+		hash, err := m.topUp(ctx, chain, addr, balance)
+		switch {
+		case err != nil:
+			alert.TopUpErr = err.Error()
+		case hash != (common.Hash{}):
+			alert.TopUpTx = hash.Hex()
+			toppedUp = true
+		}
+	}
+	// 余额持续不足时按 AlertEvery 限制告警频率，发生补充时总是告警
+	m.mu.Lock()
+	last, ok := m.alerted[key]
+	m.mu.Unlock()
+	if ok && !toppedUp && time.Since(last) < m.alertEvery() {
+		return nil
+	}
+	m.Logger.Printf("[%s] sender %s balance %s wei below %s wei, top up tx %q, error %q",
+		chain.Name, addr.Hex(), alert.Balance, alert.Threshold, alert.TopUpTx, alert.TopUpErr)
+	if err := m.alert(ctx, alert); err != nil {
+		return fmt.Errorf("send alert: %w", err)
+	}
+	m.mu.Lock()
+	m.alerted[key] = time.Now()
+	m.mu.Unlock()
+	return nil
+}
+
+// topUp 从资金账户转入 Target 与当前余额的差额。冷却期内（上次补充可能还未上链）跳过，返回空 hash
+func (m *BalanceMonitor) topUp(ctx context.Context, chain *eth.Chain, addr common.Address, balance *big.Int) (common.Hash, error) {
+	amount := new(big.Int).Sub(m.Target, balance)
+	if amount.Sign() <= 0 {
+		return common.Hash{}, nil
+	}
+
+	lastKey := fmt.Sprintf("%s%d:%s", topUpLastKey, chain.ID, addr.Hex())
+	ok, err := m.Redis.SetNX(ctx, lastKey, time.Now().Unix(), m.cooldown()).Result()
+	if err != nil || !ok {
+		return common.Hash{}, err
+	}
+
+	// 先占用当天额度，多实例同时补充也不会超过上限；发送失败时归还额度并清除冷却
+	dailyKey := fmt.Sprintf("%s%d:%s", topUpDailyKey, chain.ID, time.Now().UTC().Format("20060102"))
+	units := ceilDiv(amount, gwei).Int64()
+	undo := func() {
+		ctx := context.WithoutCancel(ctx)
+		_ = m.Redis.DecrBy(ctx, dailyKey, units).Err()
+		_ = m.Redis.Del(ctx, lastKey).Err()
+	}
+	used, err := m.Redis.IncrBy(ctx, dailyKey, units).Result()
+	if err != nil {
+		_ = m.Redis.Del(ctx, lastKey).Err()
+		return common.Hash{}, err
+	}
+	_ = m.Redis.Expire(ctx, dailyKey, 48*time.Hour).Err()
+	if used > ceilDiv(m.DailyCap, gwei).Int64() {
+		undo()
+		return common.Hash{}, fmt.Errorf("%w: %s wei", ErrTopUpCap, m.DailyCap)
+	}
+
+	ts, err := NewTransactor(chain, m.Treasury)
+	if err != nil {
+		undo()
+		return common.Hash{}, err
+	}
+	ts.SetGas(m.Gas, GasOptions{FeeCap: chain.MaxFeeCap})
+	ts.SetGasLimits(m.GasLimits)
+	tx, err := ts.Transfer(addr, amount)
+	if err != nil {
+		// 广播结果未知时转账可能已上链，保留额度与冷却，避免重复补充
+		if !errors.Is(err, ErrSendUnknown) {
+			undo()
+		}
+		return common.Hash{}, err
+	}
+	if _, err := m.Tracker.Track(ctx, chain, tx, txtrack.Request{}); err != nil {
+		m.Logger.Printf("[%s] track top up tx %s failed: %v", chain.Name, tx.Hash().Hex(), err)
+	}
+	return tx.Hash(), nil
+}
+
+func (m *BalanceMonitor) alert(ctx context.Context, alert *BalanceAlert) error {
+	if m.AlertURL == "" {
+		return nil
+	}
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.AlertURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alert %s responded %d", m.AlertURL, resp.StatusCode)
+	}
+	return nil
+}
+
+// ceilDiv a / b 向上取整
+func ceilDiv(a, b *big.Int) *big.Int {
+	n := new(big.Int).Add(a, b)
+	n.Sub(n, big.NewInt(1))
+	return n.Div(n, b)
+}
+
+func (m *BalanceMonitor) interval() time.Duration {
+	if m.Interval > 0 {
+		return m.Interval
+	}
+	return time.Minute
+}
+
+func (m *BalanceMonitor) alertEvery() time.Duration {
+	if m.AlertEvery > 0 {
+		return m.AlertEvery
+	}
+	return time.Hour
+}
+
+func (m *BalanceMonitor) cooldown() time.Duration {
+	if m.Cooldown > 0 {
+		return m.Cooldown
+	}
+	return 10 * time.Minute
+}
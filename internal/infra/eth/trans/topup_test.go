@@ -0,0 +1,223 @@
+package trans
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-web3/internal/infra/eth"
+	"go-web3/internal/infra/eth/chainclient"
+	"go-web3/internal/infra/eth/chainclient/chaintest"
+	"go-web3/internal/infra/eth/nonce"
+	"go-web3/internal/infra/eth/signer"
+	"go-web3/internal/infra/eth/txtrack"
+	"io"
+	"log"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/redis/go-redis/v9"
+)
+
+// lostSendClient 交易已进入交易池，但广播的应答丢失（连接中断）
+type lostSendClient struct {
+	chainclient.Client
+}
+
+func (c lostSendClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if err := c.Client.SendTransaction(ctx, tx); err != nil {
+		return err
+	}
+	return errors.New("read tcp: connection reset by peer")
+}
+
+type topUpEnv struct {
+	sim      *chaintest.Simulated
+	mr       *miniredis.Miniredis
+	monitor  *BalanceMonitor
+	chain    *eth.Chain
+	sender   common.Address
+	treasury common.Address
+}
+
+// newTopUpEnv 发送账户余额 0.1 ETH，低于 1 ETH 阈值时由资金账户补充到 2 ETH
+func newTopUpEnv(t *testing.T, treasuryBalance *big.Int, wrap func(chainclient.Client) chainclient.Client) *topUpEnv {
+	t.Helper()
+	senderKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	treasuryKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(senderKey.PublicKey)
+	treasury := crypto.PubkeyToAddress(treasuryKey.PublicKey)
+	sim := chaintest.NewSimulated(types.GenesisAlloc{
+		sender:   {Balance: new(big.Int).Div(oneEther, big.NewInt(10))},
+		treasury: {Balance: treasuryBalance},
+	})
+	t.Cleanup(func() { _ = sim.Close() })
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	var client chainclient.Client = sim
+	if wrap != nil {
+		client = wrap(sim)
+	}
+	chain := eth.NewChain(1337, "sim", client, nonce.NewNonceManager(rdb, client, 1337))
+	chains := eth.NewChains()
+	if err := chains.Register(chain); err != nil {
+		t.Fatal(err)
+	}
+	pool, err := NewSenderPool(rdb, []signer.Signer{signer.NewKeySigner(senderKey)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := log.New(io.Discard, "", 0)
+	tracker := txtrack.NewTracker(txtrack.NewRedisStore(rdb), chains, txtrack.NewWebhookNotifier(), logger)
+
+	monitor := NewBalanceMonitor(chains, pool, tracker, rdb, oneEther, logger)
+	monitor.Treasury = signer.NewKeySigner(treasuryKey)
+	monitor.Target = new(big.Int).Mul(oneEther, big.NewInt(2))
+	monitor.DailyCap = new(big.Int).Mul(oneEther, big.NewInt(5))
+	return &topUpEnv{sim: sim, mr: mr, monitor: monitor, chain: chain, sender: sender, treasury: treasury}
+}
+
+func (e *topUpEnv) dailyUsed(t *testing.T) string {
+	t.Helper()
+	v, _ := e.mr.Get(fmt.Sprintf("%s1337:%s", topUpDailyKey, time.Now().UTC().Format("20060102")))
+	return v
+}
+
+func (e *topUpEnv) cooling() bool {
+	return e.mr.Exists(fmt.Sprintf("%s1337:%s", topUpLastKey, e.sender.Hex()))
+}
+
+func (e *topUpEnv) treasuryTxs(t *testing.T) uint64 {
+	t.Helper()
+	n, err := e.sim.PendingNonceAt(context.Background(), e.treasury)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// TestTopUpCooldown 补充后冷却期内不重复补充，冷却结束后再次补充
+func TestTopUpCooldown(t *testing.T) {
+	env := newTopUpEnv(t, new(big.Int).Mul(oneEther, big.NewInt(10)), nil)
+	ctx := context.Background()
+	balance := new(big.Int).Div(oneEther, big.NewInt(10))
+
+	hash, err := env.monitor.topUp(ctx, env.chain, env.sender, balance)
+	if err != nil || hash == (common.Hash{}) {
+		t.Fatalf("top up = %s, %v; want a tx", hash.Hex(), err)
+	}
+	// 1.9 ETH = 1.9e9 gwei
+	if got := env.dailyUsed(t); got != "1900000000" {
+		t.Fatalf("daily used = %s gwei, want 1900000000", got)
+	}
+
+	// 补充交易还未上链，余额仍然不足
+	hash, err = env.monitor.topUp(ctx, env.chain, env.sender, balance)
+	if err != nil || hash != (common.Hash{}) {
+		t.Fatalf("top up during cooldown = %s, %v; want skipped", hash.Hex(), err)
+	}
+	if n := env.treasuryTxs(t); n != 1 {
+		t.Fatalf("treasury sent %d txs, want 1", n)
+	}
+
+	env.mr.FastForward(env.monitor.cooldown())
+	if hash, err = env.monitor.topUp(ctx, env.chain, env.sender, balance); err != nil || hash == (common.Hash{}) {
+		t.Fatalf("top up after cooldown = %s, %v; want a tx", hash.Hex(), err)
+	}
+	if got := env.dailyUsed(t); got != "3800000000" {
+		t.Fatalf("daily used = %s gwei, want 3800000000", got)
+	}
+}
+
+// TestTopUpDailyCap 超过当天上限时不发送，归还额度并清除冷却
+func TestTopUpDailyCap(t *testing.T) {
+	env := newTopUpEnv(t, new(big.Int).Mul(oneEther, big.NewInt(10)), nil)
+	env.monitor.DailyCap = new(big.Int).Mul(oneEther, big.NewInt(3))
+	ctx := context.Background()
+	balance := new(big.Int).Div(oneEther, big.NewInt(10))
+
+	if _, err := env.monitor.topUp(ctx, env.chain, env.sender, balance); err != nil {
+		t.Fatal(err)
+	}
+	env.mr.FastForward(env.monitor.cooldown())
+
+	// 1.9 + 1.9 > 3
+	if _, err := env.monitor.topUp(ctx, env.chain, env.sender, balance); !errors.Is(err, ErrTopUpCap) {
+		t.Fatalf("err = %v, want ErrTopUpCap", err)
+	}
+	if got := env.dailyUsed(t); got != "1900000000" {
+		t.Fatalf("daily used = %s gwei after cap, want 1900000000", got)
+	}
+	if env.cooling() {
+		t.Fatal("cooldown kept after cap rejection")
+	}
+	if n := env.treasuryTxs(t); n != 1 {
+		t.Fatalf("treasury sent %d txs, want 1", n)
+	}
+
+	// 差额更小的补充仍在上限内
+	if _, err := env.monitor.topUp(ctx, env.chain, env.sender, oneEther); err != nil {
+		t.Fatalf("top up within cap: %v", err)
+	}
+	if got := env.dailyUsed(t); got != "2900000000" {
+		t.Fatalf("daily used = %s gwei, want 2900000000", got)
+	}
+}
+
+// TestTopUpFailedSend 发送失败时归还当天额度并清除冷却，下一轮可以重试
+func TestTopUpFailedSend(t *testing.T) {
+	env := newTopUpEnv(t, big.NewInt(0), nil)
+	ctx := context.Background()
+
+	if _, err := env.monitor.topUp(ctx, env.chain, env.sender, new(big.Int).Div(oneEther, big.NewInt(10))); err == nil {
+		t.Fatal("top up from an empty treasury succeeded")
+	}
+	if got := env.dailyUsed(t); got != "0" {
+		t.Fatalf("daily used = %s gwei after failed send, want 0", got)
+	}
+	if env.cooling() {
+		t.Fatal("cooldown kept after failed send")
+	}
+
+	// check 把失败写入告警，不返回错误
+	if err := env.monitor.check(ctx, env.chain, env.sender); err != nil {
+		t.Fatal(err)
+	}
+	if n := env.treasuryTxs(t); n != 0 {
+		t.Fatalf("treasury sent %d txs, want 0", n)
+	}
+}
+
+// TestTopUpUnknownSend 广播结果未知时转账可能已上链，保留额度与冷却
+func TestTopUpUnknownSend(t *testing.T) {
+	env := newTopUpEnv(t, new(big.Int).Mul(oneEther, big.NewInt(10)), func(c chainclient.Client) chainclient.Client {
+		return lostSendClient{c}
+	})
+	ctx := context.Background()
+
+	_, err := env.monitor.topUp(ctx, env.chain, env.sender, new(big.Int).Div(oneEther, big.NewInt(10)))
+	if !errors.Is(err, ErrSendUnknown) {
+		t.Fatalf("err = %v, want ErrSendUnknown", err)
+	}
+	if got := env.dailyUsed(t); got != "1900000000" {
+		t.Fatalf("daily used = %s gwei, want the amount kept", got)
+	}
+	if !env.cooling() {
+		t.Fatal("cooldown cleared after ambiguous send")
+	}
+	if n := env.treasuryTxs(t); n != 1 {
+		t.Fatalf("treasury sent %d txs, want 1", n)
+	}
+}
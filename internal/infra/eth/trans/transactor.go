@@ -2,9 +2,13 @@ package trans
 
 import (
 	"context"
-	"crypto/ecdsa"
+	"errors"
 	"fmt"
+	"go-web3/internal/infra/eth"
+	"go-web3/internal/infra/eth/chainclient"
 	"go-web3/internal/infra/eth/nonce"
+	"go-web3/internal/infra/eth/signer"
+	"log"
 	"math/big"
 	"strings"
 	"time"
@@ -13,61 +17,79 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 )
 
+// ErrSendUnknown 广播结果未知（超时、连接中断），交易可能已经上链。nonce 保留给该交易，由对账确认或填补，调用方不应直接重试
+var ErrSendUnknown = errors.New("transaction broadcast result unknown")
+
 // Transactor —— 链上交易发送器
 type Transactor struct {
-	client     *ethclient.Client
-	nonceMgr   *nonce.NonceManager
-	privateKey *ecdsa.PrivateKey
-	from       common.Address
-	chainID    *big.Int
+	client   chainclient.Client
+	nonceMgr *nonce.NonceManager
+	signer   signer.Signer
+	from     common.Address
+	chainID  *big.Int
+	gas      GasStrategy
+	gasOpts  GasOptions
+	limits   *GasLimitPolicy
 }
 
-func NewTransactor(client *ethclient.Client, nonceMgr *nonce.NonceManager, privateKey *ecdsa.PrivateKey) (*Transactor, error) {
-	from := crypto.PubkeyToAddress(privateKey.PublicKey)
-	chainID, err := client.ChainID(context.Background())
-	if err != nil {
-		return nil, err
+// NewTransactor 创建指定链与签名器的交易器，gas 费使用 DefaultGasStrategy 的 standard 档位，不超过链的 MaxFeeCap。
+// chain ID 使用接入时已校验的 chain.ID，不再请求节点
+func NewTransactor(chain *eth.Chain, s signer.Signer) (*Transactor, error) {
+	if chain.ID == 0 {
+		return nil, errors.New("chain " + chain.Name + " has no chain id")
 	}
 
 	return &Transactor{
-		client:     client,
-		nonceMgr:   nonceMgr,
-		privateKey: privateKey,
-		from:       from,
-		chainID:    chainID,
+		client:   chain.Client,
+		nonceMgr: chain.NonceMgr,
+		signer:   s,
+		from:     s.Address(),
+		chainID:  new(big.Int).SetUint64(chain.ID),
+		gas:      DefaultGasStrategy,
+		gasOpts:  GasOptions{FeeCap: chain.MaxFeeCap},
+		limits:   DefaultGasLimitPolicy,
 	}, nil
 }
 
-// NewAuth 构建带 EIP-1559 的交易授权对象
-func (t *Transactor) NewAuth(ctx context.Context) (*bind.TransactOpts, error) {
-	auth, err := bind.NewKeyedTransactorWithChainID(t.privateKey, t.chainID)
-	if err != nil {
-		return nil, err
+// SetGas 替换 gas 费策略与参数，strategy 为 nil 时只替换参数
+func (t *Transactor) SetGas(strategy GasStrategy, opts GasOptions) {
+	if strategy != nil {
+		t.gas = strategy
 	}
+	t.gasOpts = opts
+}
+
+// SetGasLimits 替换 gas limit 余量规则，nil 时不替换
+func (t *Transactor) SetGasLimits(limits *GasLimitPolicy) {
+	if limits != nil {
+		t.limits = limits
+	}
+}
 
-	// EIP-1559 gas 设置
-	tip, feeCap, err := SuggestEIP1559Fee(ctx, t.client)
+// NewAuth 构建交易授权对象，gas 费由 GasStrategy 计算，nonce 为预留的 nonce，调用方广播后 Commit，否则 Release
+func (t *Transactor) NewAuth(ctx context.Context) (*bind.TransactOpts, *nonce.Reservation, error) {
+	auth := signer.TransactOpts(ctx, t.signer, t.chainID)
+
+	// gas 费设置：EIP-1559 或 legacy gasPrice
+	fee, err := t.gas.Suggest(ctx, t.client, t.gasOpts)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	auth.GasTipCap = tip
-	auth.GasFeeCap = feeCap
+	fee.Apply(auth)
 	auth.Value = big.NewInt(0)
 	auth.From = t.from
 
 	// nonce 设置
-	nonce, err := t.nonceMgr.GetNextNonce(ctx, t.from)
+	res, err := t.nonceMgr.Reserve(ctx, t.from)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	auth.Nonce = new(big.Int).SetUint64(nonce)
+	auth.Nonce = new(big.Int).SetUint64(res.Nonce)
 
-	return auth, nil
+	return auth, res, nil
 }
 
 // EstimateGas —— 根据 dry-run 交易估算 gaslimit
@@ -76,7 +98,8 @@ func (t *Transactor) EstimateGas(ctx context.Context, dry *types.Transaction, au
 		From:      t.from,
 		To:        dry.To(),
 		Data:      dry.Data(),
-		Value:     auth.Value,
+		Value:     dry.Value(),
+		GasPrice:  auth.GasPrice,
 		GasFeeCap: auth.GasFeeCap,
 		GasTipCap: auth.GasTipCap,
 	}
@@ -88,15 +111,35 @@ func (t *Transactor) EstimateGas(ctx context.Context, dry *types.Transaction, au
 func (t *Transactor) SendTx(txFunc func(*bind.TransactOpts) (*types.Transaction, error)) (*types.Transaction, error) {
 	ctx := context.Background()
 
-retry:
-	auth, err := t.NewAuth(ctx)
+	for {
+		tx, err := t.sendTx(ctx, txFunc)
+		// 自动处理 nonce 冲突
+		if err != nil && nonce.IsNonceError(err) {
+			// 同步链上 nonce
+			if err := t.nonceMgr.ForceSyncNonce(ctx, t.from); err != nil {
+				return nil, err
+			}
+			time.Sleep(200 * time.Millisecond)
+			continue
+		}
+		return tx, err
+	}
+}
+
+// sendTx 预留 nonce 发送一次交易，dry-run、模拟执行失败或节点拒绝交易时归还 nonce，不留下空洞
+func (t *Transactor) sendTx(ctx context.Context, txFunc func(*bind.TransactOpts) (*types.Transaction, error)) (*types.Transaction, error) {
+	auth, res, err := t.NewAuth(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer res.Release(ctx)
 
-	// dry-run
+	// dry-run，只取 to/data，不需要签名
 	tmp := *auth
 	tmp.NoSend = true
+	tmp.Signer = func(_ common.Address, tx *types.Transaction) (*types.Transaction, error) {
+		return tx, nil
+	}
 
 	dryTx, err := txFunc(&tmp)
 	if err != nil {
@@ -105,36 +148,66 @@ retry:
 	}
 
 	// 模拟执行（eth-call）.避免失败扣除gas
-	if err := t.SimulateCall(*dryTx.To(), dryTx.Data(), auth.Value); err != nil {
+	if err := t.SimulateCall(*dryTx.To(), dryTx.Data(), dryTx.Value()); err != nil {
 		return nil, err
 	}
 
-	// gas 估算
+	// gas 估算，按方法预留余量
 	gas, err := t.EstimateGas(ctx, dryTx, auth)
 	if err != nil {
 		return nil, err
 	}
-	auth.GasLimit = gas
+	auth.GasLimit = t.limits.Limit(dryTx.Data(), gas)
 
-	// 正式发送
+	// 余额需要足以支付最坏情况下的费用，否则广播后也无法打包
+	maxPerGas := auth.GasFeeCap
+	if auth.GasPrice != nil {
+		maxPerGas = auth.GasPrice
+	}
+	if err := Preflight(ctx, t.client, t.from, MaxCost(auth.GasLimit, maxPerGas, dryTx.Value())); err != nil {
+		return nil, err
+	}
+
+	// 正式发送。记录签名后的交易，广播结果未知时保留 nonce 而不是归还后重新签名
+	var signed *types.Transaction
+	sign := auth.Signer
+	auth.Signer = func(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+		signedTx, err := sign(addr, tx)
+		signed = signedTx
+		return signedTx, err
+	}
 	tx, err := txFunc(auth)
 	if err != nil {
-		// 自动处理 nonce 冲突
-		if nonce.IsNonceError(err) {
-			// 同步链上 nonce
-			err := t.nonceMgr.ForceSyncNonce(ctx, t.from)
-			if err != nil {
-				return nil, err
+		if signed != nil && chainclient.IsSendUnknown(err) {
+			if err := res.Commit(ctx, signed.Hash()); err != nil {
+				log.Printf("commit nonce %d of tx %s failed: %v", res.Nonce, signed.Hash().Hex(), err)
 			}
-			time.Sleep(200 * time.Millisecond)
-			goto retry
+			return nil, fmt.Errorf("%w: tx %s: %v", ErrSendUnknown, signed.Hash().Hex(), err)
 		}
 		return nil, err
 	}
+	if err := res.Commit(ctx, tx.Hash()); err != nil {
+		log.Printf("commit nonce %d of tx %s failed: %v", res.Nonce, tx.Hash().Hex(), err)
+	}
 
 	return tx, nil
 }
 
+// Transfer 转账 ETH，与合约调用一样经过模拟执行、gas 估算与 nonce 冲突重试
+func (t *Transactor) Transfer(to common.Address, value *big.Int) (*types.Transaction, error) {
+	return t.SendTx(func(auth *bind.TransactOpts) (*types.Transaction, error) {
+		fee := &Fee{Legacy: auth.GasPrice != nil, GasPrice: auth.GasPrice, GasTipCap: auth.GasTipCap, GasFeeCap: auth.GasFeeCap}
+		tx, err := auth.Signer(auth.From, fee.NewTx(t.chainID, auth.Nonce.Uint64(), &to, value, auth.GasLimit, nil))
+		if err != nil || auth.NoSend {
+			return tx, err
+		}
+		if err := t.client.SendTransaction(auth.Context, tx); err != nil {
+			return nil, err
+		}
+		return tx, nil
+	})
+}
+
 func (t *Transactor) SimulateCall(to common.Address, data []byte, value *big.Int) error {
 	msg := ethereum.CallMsg{
 		From: t.from,
@@ -163,7 +236,7 @@ func decodeRevertReason(err error) error {
 	if strings.Contains(errStr, "execution reverted:") {
 		parts := strings.SplitN(errStr, "execution reverted:", 2)
 		if len(parts) == 2 {
-			return fmt.Errorf(strings.TrimSpace(parts[1]))
+			return errors.New(strings.TrimSpace(parts[1]))
 		}
 	}
 
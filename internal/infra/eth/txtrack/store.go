@@ -0,0 +1,161 @@
+package txtrack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/redis/go-redis/v9"
+)
+
+// Store 交易记录存储
+type Store interface {
+	// Create 保存新记录，ID 已存在时返回 ErrTxExists
+	Create(ctx context.Context, tx *Tx) error
+	// Update 更新记录，进入终态时移出待跟踪列表
+	Update(ctx context.Context, tx *Tx) error
+	Get(ctx context.Context, id string) (*Tx, error)
+	// GetByHash 按交易 hash 查找记录
+	GetByHash(ctx context.Context, hash common.Hash) (*Tx, error)
+	// Pending 未进入终态的记录
+	Pending(ctx context.Context) ([]*Tx, error)
+	// ByNonce 同一链、同一发送地址、同一 nonce 的全部记录（原交易及其替换交易）
+	ByNonce(ctx context.Context, chainID uint64, from common.Address, nonce uint64) ([]*Tx, error)
+}
+
+var ErrTxExists = errors.New("tx already tracked")
+
+const (
+	TxKey        = "tx:record"
+	TxHashKey    = "tx:hash"
+	TxPendingKey = "tx:pending"
+	TxNonceKey   = "tx:nonce:"
+)
+
+// nonce 索引只在交易跟踪期间有意义，过期自动清理
+const txNonceTTL = 7 * 24 * time.Hour
+
+type RedisStore struct {
+	Client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client}
+}
+
+func nonceKey(chainID uint64, from common.Address, nonce uint64) string {
+	return fmt.Sprintf("%s%d:%s:%d", TxNonceKey, chainID, from.Hex(), nonce)
+}
+
+func (rs *RedisStore) Create(ctx context.Context, tx *Tx) error {
+	buf, err := json.Marshal(tx)
+	if err != nil {
+		return err
+	}
+	ok, err := rs.Client.HSetNX(ctx, TxKey, tx.ID, buf).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrTxExists
+	}
+
+	nk := nonceKey(tx.ChainID, tx.From, tx.Nonce)
+	pipe := rs.Client.TxPipeline()
+	pipe.HSet(ctx, TxHashKey, tx.Hash.Hex(), tx.ID)
+	pipe.SAdd(ctx, nk, tx.ID)
+	pipe.Expire(ctx, nk, txNonceTTL)
+	if !tx.Status.Final() {
+		pipe.SAdd(ctx, TxPendingKey, tx.ID)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (rs *RedisStore) Update(ctx context.Context, tx *Tx) error {
+	buf, err := json.Marshal(tx)
+	if err != nil {
+		return err
+	}
+
+	pipe := rs.Client.TxPipeline()
+	pipe.HSet(ctx, TxKey, tx.ID, buf)
+	if tx.Status.Final() {
+		pipe.SRem(ctx, TxPendingKey, tx.ID)
+	} else {
+		pipe.SAdd(ctx, TxPendingKey, tx.ID)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (rs *RedisStore) Get(ctx context.Context, id string) (*Tx, error) {
+	raw, err := rs.Client.HGet(ctx, TxKey, id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrTxNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var tx Tx
+	if err := json.Unmarshal(raw, &tx); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+func (rs *RedisStore) GetByHash(ctx context.Context, hash common.Hash) (*Tx, error) {
+	id, err := rs.Client.HGet(ctx, TxHashKey, hash.Hex()).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrTxNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rs.Get(ctx, id)
+}
+
+func (rs *RedisStore) Pending(ctx context.Context) ([]*Tx, error) {
+	ids, err := rs.Client.SMembers(ctx, TxPendingKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	return rs.getAll(ctx, ids)
+}
+
+func (rs *RedisStore) ByNonce(ctx context.Context, chainID uint64, from common.Address, nonce uint64) ([]*Tx, error) {
+	ids, err := rs.Client.SMembers(ctx, nonceKey(chainID, from, nonce)).Result()
+	if err != nil {
+		return nil, err
+	}
+	return rs.getAll(ctx, ids)
+}
+
+func (rs *RedisStore) getAll(ctx context.Context, ids []string) ([]*Tx, error) {
+	if len(ids) == 0 {
+		return []*Tx{}, nil
+	}
+
+	raws, err := rs.Client.HMGet(ctx, TxKey, ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*Tx, 0, len(raws))
+	for _, raw := range raws {
+		str, ok := raw.(string)
+		if !ok {
+			continue
+		}
+		var tx Tx
+		if err := json.Unmarshal([]byte(str), &tx); err != nil {
+			return nil, err
+		}
+		list = append(list, &tx)
+	}
+	return list, nil
+}
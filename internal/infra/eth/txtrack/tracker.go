@@ -0,0 +1,323 @@
+package txtrack
+
+import (
+	"context"
+	"errors"
+	"go-web3/internal/infra/eth"
+	"log"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Request 发起交易的请求信息
+type Request struct {
+	ID          string // 请求 ID（幂等 key），为空时使用交易 hash
+	CallbackURL string // 状态变化时回调，可选
+	Replaces    string // 加速/取消交易替换的记录 ID
+	Pin         string // 发送账户固定 key，相同 pin 的请求使用同一发送账户，可选
+
+	// gas 费，可选
+	Speed        string   // 档位 slow | standard | fast，默认 standard
+	MaxFeePerGas *big.Int // 覆盖计算的 maxFeePerGas（wei）
+	MaxTipPerGas *big.Int // 覆盖计算的 maxPriorityFeePerGas（wei）
+}
+
+// Tracker 交易生命周期跟踪：记录每笔发出的交易，周期性查询回执，
+// 按链的确认区块数把交易标记为 confirmed/failed，并识别被丢弃或被同 nonce 交易替换的情况。
+// 状态变化时通过 Notifier 回调
+type Tracker struct {
+	Store    Store
+	Chains   *eth.Chains
+	Notifier Notifier // 可选
+	Logger   *log.Logger
+
+	Interval    time.Duration // 轮询间隔，默认 3s
+	DropTimeout time.Duration // 节点查不到交易且 nonce 未被使用超过该时间视为丢弃，默认 10m
+
+	notifyWg sync.WaitGroup
+}
+
+func NewTracker(store Store, chains *eth.Chains, notifier Notifier, logger *log.Logger) *Tracker {
+	return &Tracker{
+		Store:    store,
+		Chains:   chains,
+		Notifier: notifier,
+		Logger:   logger,
+	}
+}
+
+// Track 记录已广播的交易。ID 已被其他交易使用时改用交易 hash 作为 ID
+func (t *Tracker) Track(ctx context.Context, chain *eth.Chain, tx *types.Transaction, req Request) (*Tx, error) {
+	id := req.ID
+	if id == "" {
+		id = tx.Hash().Hex()
+	}
+	rec, err := NewTx(id, chain.Name, tx)
+	if err != nil {
+		return nil, err
+	}
+	rec.CallbackURL = req.CallbackURL
+	rec.Replaces = req.Replaces
+
+	err = t.Store.Create(ctx, rec)
+	if errors.Is(err, ErrTxExists) && rec.ID != rec.Hash.Hex() {
+		t.Logger.Printf("tx id %s already used, track %s by hash", rec.ID, rec.Hash.Hex())
+		rec.ID = rec.Hash.Hex()
+		err = t.Store.Create(ctx, rec)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// Get 按记录 ID 或交易 hash 查询
+func (t *Tracker) Get(ctx context.Context, id string) (*Tx, error) {
+	tx, err := t.Store.Get(ctx, id)
+	if errors.Is(err, ErrTxNotFound) && strings.HasPrefix(id, "0x") && len(id) == 66 {
+		return t.Store.GetByHash(ctx, common.HexToHash(id))
+	}
+	return tx, err
+}
+
+// Start 周期性检查未进入终态的交易，阻塞直到 ctx 取消，并等待在途回调完成
+func (t *Tracker) Start(ctx context.Context) {
+	ticker := time.NewTicker(t.interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			t.notifyWg.Wait()
+			t.Logger.Println("tx tracker stopped")
+			return
+		case <-ticker.C:
+			if err := t.pollOnce(ctx); err != nil && ctx.Err() == nil {
+				t.Logger.Printf("poll error: %v", err)
+			}
+		}
+	}
+}
+
+func (t *Tracker) pollOnce(ctx context.Context) error {
+	pending, err := t.Store.Pending(ctx)
+	if err != nil {
+		return err
+	}
+
+	heads := map[uint64]uint64{}
+	for _, rec := range pending {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		chain, err := t.Chains.Get(rec.ChainID)
+		if err != nil {
+			t.Logger.Printf("[%s] %v", rec.ID, err)
+			continue
+		}
+		head, ok := heads[chain.ID]
+		if !ok {
+			if head, err = chain.Client.BlockNumber(ctx); err != nil {
+				t.Logger.Printf("[%s] block number: %v", chain.Name, err)
+				continue
+			}
+			heads[chain.ID] = head
+		}
+
+		if err := t.check(ctx, chain, head, rec); err != nil {
+			t.Logger.Printf("[%s] check tx %s: %v", rec.ID, rec.Hash.Hex(), err)
+		}
+	}
+	return nil
+}
+
+// check 更新单笔交易的状态，有变化时保存，状态变化时回调
+func (t *Tracker) check(ctx context.Context, chain *eth.Chain, head uint64, rec *Tx) error {
+	before := *rec
+	if err := t.refresh(ctx, chain, head, rec); err != nil {
+		return err
+	}
+
+	if rec.Status == before.Status && rec.Confirmations == before.Confirmations &&
+		rec.BlockHash == before.BlockHash && rec.LastSeenAt.Equal(before.LastSeenAt) {
+		return nil
+	}
+	rec.UpdatedAt = time.Now()
+	if err := t.Store.Update(ctx, rec); err != nil {
+		return err
+	}
+
+	if rec.Status != before.Status {
+		t.Logger.Printf("[%s] tx %s %s → %s", rec.ID, rec.Hash.Hex(), before.Status, rec.Status)
+		t.notify(ctx, rec)
+	}
+	return nil
+}
+
+func (t *Tracker) refresh(ctx context.Context, chain *eth.Chain, head uint64, rec *Tx) error {
+	client := chain.Client
+
+	receipt, err := client.TransactionReceipt(ctx, rec.Hash)
+	if err == nil {
+		t.applyReceipt(chain, head, rec, receipt)
+		return nil
+	}
+	if !errors.Is(err, ethereum.NotFound) {
+		return err
+	}
+
+	// 未打包（或已打包的区块被重组）
+	rec.BlockNumber = 0
+	rec.BlockHash = common.Hash{}
+	rec.GasUsed = 0
+	rec.Confirmations = 0
+	rec.Status = StatusPending
+
+	// nonce 已被使用但本交易没有回执：同 nonce 的其他交易已上链
+	nonce, err := client.NonceAt(ctx, rec.From, nil)
+	if err != nil {
+		return err
+	}
+	if nonce > rec.Nonce {
+		// 查询之间本交易可能刚好打包，再确认一次回执
+		if receipt, err := client.TransactionReceipt(ctx, rec.Hash); err == nil {
+			t.applyReceipt(chain, head, rec, receipt)
+			return nil
+		}
+		rec.Status = StatusReplaced
+		rec.ReplacedBy = t.replacement(ctx, chain, rec)
+		return nil
+	}
+
+	_, _, err = client.TransactionByHash(ctx, rec.Hash)
+	switch {
+	case err == nil:
+		rec.LastSeenAt = time.Now()
+	case errors.Is(err, ethereum.NotFound):
+		// 已发出同 nonce 的替换交易时，等待其中一笔上链，不标记为丢弃
+		if time.Since(rec.LastSeenAt) > t.dropTimeout() && !t.hasReplacement(ctx, rec) {
+			rec.Status = StatusDropped
+			rec.Error = "transaction not found in node mempool"
+		}
+	default:
+		return err
+	}
+	return nil
+}
+
+func (t *Tracker) applyReceipt(chain *eth.Chain, head uint64, rec *Tx, receipt *types.Receipt) {
+	number := receipt.BlockNumber.Uint64()
+	rec.BlockNumber = number
+	rec.BlockHash = receipt.BlockHash
+	rec.GasUsed = receipt.GasUsed
+	rec.Confirmations = 0
+	if head >= number {
+		rec.Confirmations = head - number + 1
+	}
+	rec.LastSeenAt = time.Now()
+
+	required := chain.Confirmations
+	if required == 0 {
+		required = 1
+	}
+	switch {
+	case rec.Confirmations < required:
+		rec.Status = StatusMined
+	case receipt.Status == types.ReceiptStatusFailed:
+		rec.Status = StatusFailed
+		rec.Error = "execution reverted"
+	default:
+		rec.Status = StatusConfirmed
+	}
+}
+
+// replacement 在同 nonce 的已跟踪交易中找到上链的那一笔，未跟踪时返回空
+func (t *Tracker) replacement(ctx context.Context, chain *eth.Chain, rec *Tx) string {
+	others, err := t.Store.ByNonce(ctx, rec.ChainID, rec.From, rec.Nonce)
+	if err != nil {
+		return ""
+	}
+	for _, other := range others {
+		if other.ID == rec.ID {
+			continue
+		}
+		if _, err := chain.Client.TransactionReceipt(ctx, other.Hash); err == nil {
+			return other.ID
+		}
+	}
+	return ""
+}
+
+// hasReplacement 是否有未进入终态的同 nonce 交易
+func (t *Tracker) hasReplacement(ctx context.Context, rec *Tx) bool {
+	others, err := t.Store.ByNonce(ctx, rec.ChainID, rec.From, rec.Nonce)
+	if err != nil {
+		return false
+	}
+	for _, other := range others {
+		if other.ID != rec.ID && !other.Status.Final() {
+			return true
+		}
+	}
+	return false
+}
+
+// Latest 同 nonce 交易中最近发出且未进入终态的一笔，没有时返回 ErrTxNotFound
+func (t *Tracker) Latest(ctx context.Context, chainID uint64, from common.Address, nonce uint64) (*Tx, error) {
+	list, err := t.Store.ByNonce(ctx, chainID, from, nonce)
+	if err != nil {
+		return nil, err
+	}
+	var latest *Tx
+	for _, tx := range list {
+		if tx.Status.Final() {
+			continue
+		}
+		if latest == nil || tx.CreatedAt.After(latest.CreatedAt) {
+			latest = tx
+		}
+	}
+	if latest == nil {
+		return nil, ErrTxNotFound
+	}
+	return latest, nil
+}
+
+func (t *Tracker) notify(ctx context.Context, rec *Tx) {
+	if t.Notifier == nil || rec.CallbackURL == "" {
+		return
+	}
+	snapshot := *rec
+
+	t.notifyWg.Add(1)
+	go func() {
+		defer t.notifyWg.Done()
+		// 停止时不中断在途回调
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+		defer cancel()
+		if err := t.Notifier.Notify(ctx, &snapshot); err != nil {
+			t.Logger.Printf("[%s] callback failed: %v", snapshot.ID, err)
+		}
+	}()
+}
+
+func (t *Tracker) interval() time.Duration {
+	if t.Interval > 0 {
+		return t.Interval
+	}
+	return 3 * time.Second
+}
+
+func (t *Tracker) dropTimeout() time.Duration {
+	if t.DropTimeout > 0 {
+		return t.DropTimeout
+	}
+	return 10 * time.Minute
+}
@@ -0,0 +1,332 @@
+package txtrack
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"go-web3/internal/infra/eth"
+	"go-web3/internal/infra/eth/chainclient/chaintest"
+	"io"
+	"log"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/redis/go-redis/v9"
+)
+
+const gwei = 1_000_000_000
+
+// recordNotifier 按顺序记录回调的状态
+type recordNotifier struct {
+	mu       sync.Mutex
+	statuses map[string][]Status
+}
+
+func (n *recordNotifier) Notify(_ context.Context, tx *Tx) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.statuses[tx.ID] = append(n.statuses[tx.ID], tx.Status)
+	return nil
+}
+
+func (n *recordNotifier) get(id string) []Status {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]Status(nil), n.statuses[id]...)
+}
+
+type trackerEnv struct {
+	sim      *chaintest.Simulated
+	chain    *eth.Chain
+	tracker  *Tracker
+	notifier *recordNotifier
+	key      *ecdsa.PrivateKey
+	from     common.Address
+}
+
+// newTrackerEnv 模拟链上一个有余额的发送账户，交易需要 2 个确认
+func newTrackerEnv(t *testing.T) *trackerEnv {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	sim := chaintest.NewSimulated(types.GenesisAlloc{from: {Balance: big.NewInt(1e18)}})
+	t.Cleanup(func() { _ = sim.Close() })
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	chain := eth.NewChain(1337, "sim", sim, nil)
+	chain.Confirmations = 2
+	chains := eth.NewChains()
+	if err := chains.Register(chain); err != nil {
+		t.Fatal(err)
+	}
+	// 出块前节点的交易索引未完成，查不到的交易返回 indexing 错误而不是 NotFound
+	sim.Commit()
+	for {
+		_, err := sim.TransactionReceipt(context.Background(), common.Hash{1})
+		if errors.Is(err, ethereum.NotFound) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	notifier := &recordNotifier{statuses: map[string][]Status{}}
+	tracker := NewTracker(NewRedisStore(rdb), chains, notifier, log.New(io.Discard, "", 0))
+	return &trackerEnv{sim: sim, chain: chain, tracker: tracker, notifier: notifier, key: key, from: from}
+}
+
+// sign 签名转账交易，tip 越高越可以替换同 nonce 的交易
+func (e *trackerEnv) sign(t *testing.T, nonce uint64, tipGwei int64) *types.Transaction {
+	t.Helper()
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	tx, err := types.SignNewTx(e.key, types.LatestSignerForChainID(big.NewInt(1337)), &types.DynamicFeeTx{
+		ChainID:   big.NewInt(1337),
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(tipGwei * gwei),
+		GasFeeCap: big.NewInt(tipGwei * 20 * gwei),
+		Gas:       21000,
+		To:        &to,
+		Value:     big.NewInt(1),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func (e *trackerEnv) send(t *testing.T, tx *types.Transaction) {
+	t.Helper()
+	if err := e.sim.SendTransaction(context.Background(), tx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (e *trackerEnv) track(t *testing.T, tx *types.Transaction, req Request) *Tx {
+	t.Helper()
+	req.CallbackURL = "http://callback.test"
+	rec, err := e.tracker.Track(context.Background(), e.chain, tx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+// poll 检查一次记录并返回检查后的状态，检查出错时失败
+func (e *trackerEnv) poll(t *testing.T, id string) *Tx {
+	t.Helper()
+	ctx := context.Background()
+	rec, err := e.tracker.Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := e.sim.BlockNumber(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.tracker.check(ctx, e.chain, head, rec); err != nil {
+		t.Fatalf("check %s: %v", id, err)
+	}
+	if rec, err = e.tracker.Get(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+func (e *trackerEnv) wantNotified(t *testing.T, id string, want ...Status) {
+	t.Helper()
+	e.tracker.notifyWg.Wait()
+	got := e.notifier.get(id)
+	if len(got) != len(want) {
+		t.Fatalf("callbacks for %s = %v, want %v", id, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("callbacks for %s = %v, want %v", id, got, want)
+		}
+	}
+}
+
+// TestTrackerConfirms 打包后确认数不足为 mined，达到确认数后 confirmed 并移出待跟踪列表
+func TestTrackerConfirms(t *testing.T) {
+	env := newTrackerEnv(t)
+	tx := env.sign(t, 0, 1)
+	env.send(t, tx)
+	rec := env.track(t, tx, Request{ID: "pay-1"})
+
+	if got := env.poll(t, rec.ID); got.Status != StatusPending {
+		t.Fatalf("status = %s before mining, want pending", got.Status)
+	}
+	block := env.sim.Commit()
+	got := env.poll(t, rec.ID)
+	if got.Status != StatusMined || got.Confirmations != 1 || got.BlockHash != block {
+		t.Fatalf("after 1 block = %s, %d confirmations, block %s; want mined in %s",
+			got.Status, got.Confirmations, got.BlockHash.Hex(), block.Hex())
+	}
+	env.sim.Commit()
+	if got := env.poll(t, rec.ID); got.Status != StatusConfirmed || got.Confirmations != 2 {
+		t.Fatalf("after 2 blocks = %s, %d confirmations; want confirmed", got.Status, got.Confirmations)
+	}
+	pending, err := env.tracker.Store.Pending(context.Background())
+	if err != nil || len(pending) != 0 {
+		t.Fatalf("pending = %d, %v; want none", len(pending), err)
+	}
+	env.wantNotified(t, rec.ID, StatusMined, StatusConfirmed)
+}
+
+// TestTrackerDropped 交易从交易池消失且 nonce 未被使用，超过 DropTimeout 后标记为 dropped
+func TestTrackerDropped(t *testing.T) {
+	env := newTrackerEnv(t)
+	tx := env.sign(t, 0, 1)
+	env.send(t, tx)
+	rec := env.track(t, tx, Request{ID: "pay-1"})
+
+	if got := env.poll(t, rec.ID); got.Status != StatusPending {
+		t.Fatalf("status = %s, want pending", got.Status)
+	}
+	env.sim.Backend.Rollback()
+
+	// 未超过 DropTimeout 时继续等待
+	if got := env.poll(t, rec.ID); got.Status != StatusPending {
+		t.Fatalf("status = %s within drop timeout, want pending", got.Status)
+	}
+	env.tracker.DropTimeout = time.Nanosecond
+	got := env.poll(t, rec.ID)
+	if got.Status != StatusDropped || got.Error == "" {
+		t.Fatalf("status = %s (%q), want dropped with an error", got.Status, got.Error)
+	}
+	env.wantNotified(t, rec.ID, StatusDropped)
+}
+
+// TestTrackerWaitsForReplacement 存在未进入终态的同 nonce 交易时不标记为丢弃
+func TestTrackerWaitsForReplacement(t *testing.T) {
+	env := newTrackerEnv(t)
+	env.tracker.DropTimeout = time.Nanosecond
+	// 两笔都未广播，节点上查不到
+	orig := env.track(t, env.sign(t, 0, 1), Request{ID: "pay-1"})
+	env.track(t, env.sign(t, 0, 2), Request{ID: "pay-1-speedup", Replaces: orig.ID})
+
+	if got := env.poll(t, orig.ID); got.Status != StatusPending {
+		t.Fatalf("status = %s with a pending replacement, want pending", got.Status)
+	}
+}
+
+// TestTrackerReplaced 同 nonce 的替换交易上链后，原交易标记为 replaced 并指向替换交易
+func TestTrackerReplaced(t *testing.T) {
+	env := newTrackerEnv(t)
+	orig := env.sign(t, 0, 1)
+	env.send(t, orig)
+	origRec := env.track(t, orig, Request{ID: "pay-1"})
+
+	speedup := env.sign(t, 0, 2)
+	env.send(t, speedup)
+	speedupRec := env.track(t, speedup, Request{ID: "pay-1-speedup", Replaces: origRec.ID})
+	env.sim.Commit()
+
+	got := env.poll(t, origRec.ID)
+	if got.Status != StatusReplaced || got.ReplacedBy != speedupRec.ID {
+		t.Fatalf("original = %s replaced by %q, want replaced by %q", got.Status, got.ReplacedBy, speedupRec.ID)
+	}
+	if got := env.poll(t, speedupRec.ID); got.Status != StatusMined {
+		t.Fatalf("speedup = %s, want mined", got.Status)
+	}
+	env.wantNotified(t, origRec.ID, StatusReplaced)
+}
+
+// TestTrackerReorg 打包区块被重组掉后交易回到 pending，重新打包后再次确认
+func TestTrackerReorg(t *testing.T) {
+	env := newTrackerEnv(t)
+	ctx := context.Background()
+	genesis, err := env.sim.HeaderByNumber(ctx, big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := env.sign(t, 0, 1)
+	env.send(t, tx)
+	rec := env.track(t, tx, Request{ID: "pay-1"})
+
+	block := env.sim.Commit()
+	if got := env.poll(t, rec.ID); got.Status != StatusMined || got.BlockHash != block {
+		t.Fatalf("status = %s in %s, want mined in %s", got.Status, got.BlockHash.Hex(), block.Hex())
+	}
+
+	if err := env.sim.Backend.Fork(genesis.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	got := env.poll(t, rec.ID)
+	if got.Status != StatusPending || got.BlockNumber != 0 || got.BlockHash != (common.Hash{}) || got.Confirmations != 0 {
+		t.Fatalf("after reorg = %s in block %d (%s), %d confirmations; want pending with no block",
+			got.Status, got.BlockNumber, got.BlockHash.Hex(), got.Confirmations)
+	}
+
+	// 被重组掉的交易回到交易池，在新链上重新打包
+	env.sim.Commit()
+	env.sim.Commit()
+	got = env.poll(t, rec.ID)
+	if got.Status != StatusConfirmed || got.BlockHash == block {
+		t.Fatalf("after re-mining = %s in %s, want confirmed in a new block", got.Status, got.BlockHash.Hex())
+	}
+	env.wantNotified(t, rec.ID, StatusMined, StatusPending, StatusConfirmed)
+}
+
+// TestLatest 同 nonce 交易中返回最近发出且未进入终态的一笔
+func TestLatest(t *testing.T) {
+	env := newTrackerEnv(t)
+	ctx := context.Background()
+	store := env.tracker.Store
+
+	base := time.Now()
+	records := []struct {
+		id      string
+		tip     int64
+		status  Status
+		created time.Duration
+	}{
+		{"orig", 1, StatusPending, 0},
+		{"speedup", 2, StatusPending, time.Second},
+		{"cancel", 3, StatusDropped, 2 * time.Second}, // 最新但已是终态
+	}
+	for _, r := range records {
+		rec, err := NewTx(r.id, env.chain.Name, env.sign(t, 0, r.tip))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec.Status = r.status
+		rec.CreatedAt = base.Add(r.created)
+		if err := store.Create(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	latest, err := env.tracker.Latest(ctx, 1337, env.from, 0)
+	if err != nil || latest.ID != "speedup" {
+		t.Fatalf("latest = %v, %v; want speedup", latest, err)
+	}
+
+	// 全部进入终态时没有可替换的交易
+	for _, id := range []string{"orig", "speedup"} {
+		rec, err := store.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec.Status = StatusReplaced
+		if err := store.Update(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := env.tracker.Latest(ctx, 1337, env.from, 0); !errors.Is(err, ErrTxNotFound) {
+		t.Fatalf("latest with only final txs = %v, want ErrTxNotFound", err)
+	}
+	if _, err := env.tracker.Latest(ctx, 1337, env.from, 1); !errors.Is(err, ErrTxNotFound) {
+		t.Fatalf("latest for unused nonce = %v, want ErrTxNotFound", err)
+	}
+}
@@ -0,0 +1,97 @@
+package txtrack
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Status 交易生命周期状态
+type Status string
+
+const (
+	StatusPending   Status = "pending"   // 已广播，未打包
+	StatusMined     Status = "mined"     // 已打包，确认数不足
+	StatusConfirmed Status = "confirmed" // 执行成功且达到确认数
+	StatusFailed    Status = "failed"    // 执行失败（revert）且达到确认数
+	StatusDropped   Status = "dropped"   // 节点交易池中已不存在，且 nonce 未被使用
+	StatusReplaced  Status = "replaced"  // 同一 nonce 的其他交易已上链
+)
+
+// Final 终态，不再跟踪
+func (s Status) Final() bool {
+	switch s {
+	case StatusConfirmed, StatusFailed, StatusDropped, StatusReplaced:
+		return true
+	}
+	return false
+}
+
+var ErrTxNotFound = errors.New("tx not found")
+
+// Tx 发出的交易记录
+type Tx struct {
+	ID          string          `json:"id"` // 请求 ID，默认取幂等 key
+	Chain       string          `json:"chain"`
+	ChainID     uint64          `json:"chainId"`
+	Hash        common.Hash     `json:"hash"`
+	From        common.Address  `json:"from"`
+	To          *common.Address `json:"to,omitempty"`
+	Nonce       uint64          `json:"nonce"`
+	Value       string          `json:"value"` // wei
+	Gas         uint64          `json:"gas"`
+	GasPrice    string          `json:"gasPrice,omitempty"`  // legacy 交易
+	GasTipCap   string          `json:"gasTipCap,omitempty"` // EIP-1559 交易
+	GasFeeCap   string          `json:"gasFeeCap,omitempty"`
+	Data        hexutil.Bytes   `json:"data"`
+	CallbackURL string          `json:"callbackUrl,omitempty"`
+
+	Status        Status      `json:"status"`
+	BlockNumber   uint64      `json:"blockNumber,omitempty"`
+	BlockHash     common.Hash `json:"blockHash,omitempty"`
+	GasUsed       uint64      `json:"gasUsed,omitempty"`
+	Confirmations uint64      `json:"confirmations"`
+	Replaces      string      `json:"replaces,omitempty"`   // 本交易是加速/取消交易时，被替换交易的记录 ID
+	ReplacedBy    string      `json:"replacedBy,omitempty"` // 替换交易的记录 ID，未跟踪时为空
+	Error         string      `json:"error,omitempty"`
+
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"` // 最后一次在节点上查到该交易的时间，用于判断丢弃
+}
+
+// NewTx 由已签名交易生成记录
+func NewTx(id string, chain string, tx *types.Transaction) (*Tx, error) {
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	t := &Tx{
+		ID:         id,
+		Chain:      chain,
+		ChainID:    tx.ChainId().Uint64(),
+		Hash:       tx.Hash(),
+		From:       from,
+		To:         tx.To(),
+		Nonce:      tx.Nonce(),
+		Value:      tx.Value().String(),
+		Gas:        tx.Gas(),
+		Data:       tx.Data(),
+		Status:     StatusPending,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		LastSeenAt: now,
+	}
+	if tx.Type() == types.LegacyTxType || tx.Type() == types.AccessListTxType {
+		t.GasPrice = tx.GasPrice().String()
+	} else {
+		t.GasTipCap = tx.GasTipCap().String()
+		t.GasFeeCap = tx.GasFeeCap().String()
+	}
+	return t, nil
+}
@@ -0,0 +1,88 @@
+package txtrack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Notifier 交易状态变化通知
+type Notifier interface {
+	Notify(ctx context.Context, tx *Tx) error
+}
+
+// WebhookNotifier 把交易记录 POST 到交易的 CallbackURL，非 2xx 响应视为失败并重试
+type WebhookNotifier struct {
+	Client  *http.Client
+	Retries int           // 失败重试次数，默认 3
+	Backoff time.Duration // 首次重试间隔，之后翻倍，默认 1s
+}
+
+func NewWebhookNotifier() *WebhookNotifier {
+	return &WebhookNotifier{
+		Client:  &http.Client{Timeout: 5 * time.Second},
+		Retries: 3,
+		Backoff: time.Second,
+	}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, tx *Tx) error {
+	if tx.CallbackURL == "" {
+		return nil
+	}
+	body, err := json.Marshal(tx)
+	if err != nil {
+		return err
+	}
+
+	backoff := n.Backoff
+	for attempt := 0; ; attempt++ {
+		err = n.post(ctx, tx.CallbackURL, body)
+		if err == nil || attempt >= n.Retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (n *WebhookNotifier) post(ctx context.Context, callbackURL string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("callback %s responded %d", callbackURL, resp.StatusCode)
+	}
+	return nil
+}
+
+// ValidateCallbackURL 回调地址只支持 http/https
+func ValidateCallbackURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("invalid callback url: " + s)
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	mrand "math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrTimeout 等待锁超时
+	ErrTimeout = errors.New("lock wait timeout")
+	// ErrLost 持有期间锁过期或被其他持有者占用，临界区内的操作可能与其他实例并发执行
+	ErrLost = errors.New("lock lost")
+)
+
+// 只有持有者（token 相同）才能释放或续期，避免删除其他实例在锁过期后获得的锁
+var (
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+	renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+// Locker Redis 分布式锁：每次加锁生成随机 token，持有期间定期续期，释放时比较 token 后删除。
+// 等待锁时响应 ctx 取消，并统计竞争情况
+type Locker struct {
+	client *redis.Client
+
+	TTL         time.Duration // 锁过期时间，持有期间每 TTL/3 续期一次，默认 10s
+	Retry       time.Duration // 锁被占用时的最大重试间隔，从 1ms 开始翻倍并加随机抖动，默认 20ms
+	WaitTimeout time.Duration // 最长等待时间，默认 10s，ctx 的 deadline 更早时以 ctx 为准
+
+	acquired  atomic.Uint64
+	contended atomic.Uint64
+	timeouts  atomic.Uint64
+	lost      atomic.Uint64
+	waitNanos atomic.Int64
+	maxWait   atomic.Int64
+}
+
+func NewLocker(client *redis.Client) *Locker {
+	return &Locker{client: client}
+}
+
+// Stats 锁竞争统计，用于健康检查接口
+type Stats struct {
+	Acquired  uint64  `json:"acquired"`  // 成功加锁次数
+	Contended uint64  `json:"contended"` // 加锁时锁已被占用、需要等待的次数
+	Timeouts  uint64  `json:"timeouts"`  // 等待超时或 ctx 取消的次数
+	Lost      uint64  `json:"lost"`      // 持有期间丢失锁的次数
+	AvgWaitMs float64 `json:"avgWaitMs"` // 成功加锁的平均等待时间
+	MaxWaitMs float64 `json:"maxWaitMs"`
+}
+
+func (l *Locker) Stats() Stats {
+	s := Stats{
+		Acquired:  l.acquired.Load(),
+		Contended: l.contended.Load(),
+		Timeouts:  l.timeouts.Load(),
+		Lost:      l.lost.Load(),
+		MaxWaitMs: float64(l.maxWait.Load()) / float64(time.Millisecond),
+	}
+	if s.Acquired > 0 {
+		s.AvgWaitMs = float64(l.waitNanos.Load()) / float64(s.Acquired) / float64(time.Millisecond)
+	}
+	return s
+}
+
+// Lock 已持有的锁
+type Lock struct {
+	key    string
+	token  string
+	locker *Locker
+
+	stop     chan struct{}
+	renewed  chan struct{} // 续期协程退出后关闭
+	lost     chan struct{} // 丢失锁时关闭
+	lostOnce sync.Once
+	released atomic.Bool
+}
+
+// Acquire 加锁，锁被占用时按 Retry 间隔重试，直到获得锁、超过 WaitTimeout 或 ctx 取消
+func (l *Locker) Acquire(ctx context.Context, key string) (*Lock, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, l.waitTimeout())
+	defer cancel()
+
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		ok, err := l.client.SetNX(ctx, key, token, l.ttl()).Result()
+		if err != nil && ctx.Err() == nil {
+			return nil, err
+		}
+		if ok {
+			l.observeWait(time.Since(start))
+			return l.hold(key, token), nil
+		}
+		if attempt == 0 {
+			l.contended.Add(1)
+		}
+
+		// 锁被占用，稍后重试
+		timer := time.NewTimer(l.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			l.timeouts.Add(1)
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("%w: %s after %s", ErrTimeout, key, time.Since(start).Round(time.Millisecond))
+			}
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (l *Locker) hold(key, token string) *Lock {
+	lk := &Lock{
+		key:     key,
+		token:   token,
+		locker:  l,
+		stop:    make(chan struct{}),
+		renewed: make(chan struct{}),
+		lost:    make(chan struct{}),
+	}
+	go lk.renew()
+	return lk
+}
+
+// renew 持有期间定期续期，续期失败视为丢失锁
+func (lk *Lock) renew() {
+	defer close(lk.renewed)
+	l := lk.locker
+	ticker := time.NewTicker(l.ttl() / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-lk.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), l.ttl()/3)
+			n, err := renewScript.Run(ctx, l.client, []string{lk.key}, lk.token, l.ttl().Milliseconds()).Int()
+			cancel()
+			if err != nil {
+				// 网络错误时下次再试，锁在 TTL 内仍然有效
+				log.Printf("renew lock %s: %v", lk.key, err)
+				continue
+			}
+			if n == 0 {
+				lk.markLost()
+				return
+			}
+		}
+	}
+}
+
+func (lk *Lock) markLost() {
+	lk.lostOnce.Do(func() {
+		lk.locker.lost.Add(1)
+		log.Printf("lock %s lost", lk.key)
+		close(lk.lost)
+	})
+}
+
+// Lost 丢失锁时关闭，临界区中的长操作可以据此中止
+func (lk *Lock) Lost() <-chan struct{} {
+	return lk.lost
+}
+
+// Release 停止续期并释放锁。锁已丢失时返回 ErrLost，重复调用返回 nil
+func (lk *Lock) Release(ctx context.Context) error {
+	if !lk.released.CompareAndSwap(false, true) {
+		return nil
+	}
+	close(lk.stop)
+	<-lk.renewed
+
+	select {
+	case <-lk.lost:
+		return ErrLost
+	default:
+	}
+	n, err := releaseScript.Run(ctx, lk.locker.client, []string{lk.key}, lk.token).Int()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		lk.markLost()
+		return ErrLost
+	}
+	return nil
+}
+
+func (l *Locker) observeWait(d time.Duration) {
+	l.acquired.Add(1)
+	l.waitNanos.Add(int64(d))
+	for {
+		max := l.maxWait.Load()
+		if int64(d) <= max || l.maxWait.CompareAndSwap(max, int64(d)) {
+			return
+		}
+	}
+}
+
+// backoff 第 attempt 次重试前的等待时间，随机抖动避免等待者同时重试
+func (l *Locker) backoff(attempt int) time.Duration {
+	d := l.retry()
+	if attempt < 10 && time.Millisecond<<attempt < d {
+		d = time.Millisecond << attempt
+	}
+	return d/2 + mrand.N(d)
+}
+
+func newToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func (l *Locker) ttl() time.Duration {
+	if l.TTL > 0 {
+		return l.TTL
+	}
+	return 10 * time.Second
+}
+
+func (l *Locker) retry() time.Duration {
+	if l.Retry > 0 {
+		return l.Retry
+	}
+	return 20 * time.Millisecond
+}
+
+func (l *Locker) waitTimeout() time.Duration {
+	if l.WaitTimeout > 0 {
+		return l.WaitTimeout
+	}
+	return 10 * time.Second
+}
@@ -0,0 +1,180 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+const testKey = "nonce:lock:1:0x0000000000000000000000000000000000000001"
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	return mr, rdb
+}
+
+// TestMutualExclusion 多个 goroutine 竞争同一地址的锁，临界区内同时只有一个持有者
+func TestMutualExclusion(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	locker := NewLocker(rdb)
+	locker.Retry = 2 * time.Millisecond
+
+	const workers = 32
+	var (
+		inside  atomic.Int32
+		overlap atomic.Bool
+		counter int // 只在持有锁时读写
+		wg      sync.WaitGroup
+		start   = make(chan struct{})
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			lk, err := locker.Acquire(context.Background(), testKey)
+			if err != nil {
+				t.Errorf("acquire: %v", err)
+				return
+			}
+			if inside.Add(1) != 1 {
+				overlap.Store(true)
+			}
+			n := counter
+			time.Sleep(time.Millisecond)
+			counter = n + 1
+			inside.Add(-1)
+			if err := lk.Release(context.Background()); err != nil {
+				t.Errorf("release: %v", err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if overlap.Load() {
+		t.Fatal("two holders were inside the critical section at the same time")
+	}
+	if counter != workers {
+		t.Fatalf("counter = %d, want %d", counter, workers)
+	}
+	stats := locker.Stats()
+	if stats.Acquired != workers || stats.Timeouts != 0 || stats.Lost != 0 {
+		t.Fatalf("stats = %+v, want %d acquired, no timeouts or lost locks", stats, workers)
+	}
+	if stats.Contended == 0 || stats.Contended > workers-1 {
+		t.Fatalf("contended = %d, want between 1 and %d", stats.Contended, workers-1)
+	}
+	if stats.MaxWaitMs <= 0 || stats.AvgWaitMs > stats.MaxWaitMs {
+		t.Fatalf("wait stats = avg %.3fms max %.3fms", stats.AvgWaitMs, stats.MaxWaitMs)
+	}
+}
+
+// TestReleaseAfterExpiry 锁过期后被其他实例获得，原持有者释放时返回 ErrLost 且不删除新持有者的锁
+func TestReleaseAfterExpiry(t *testing.T) {
+	mr, rdb := newTestRedis(t)
+	ctx := context.Background()
+
+	// TTL 足够长，测试期间不会续期，由 miniredis 快进模拟过期
+	first := NewLocker(rdb)
+	first.TTL = time.Minute
+	second := NewLocker(rdb)
+	second.TTL = time.Minute
+
+	lk1, err := first.Acquire(ctx, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr.FastForward(2 * time.Minute)
+
+	lk2, err := second.Acquire(ctx, testKey)
+	if err != nil {
+		t.Fatalf("acquire after expiry: %v", err)
+	}
+	if err := lk1.Release(ctx); !errors.Is(err, ErrLost) {
+		t.Fatalf("release of expired lock = %v, want ErrLost", err)
+	}
+	if got, _ := rdb.Get(ctx, testKey).Result(); got != lk2.token {
+		t.Fatalf("lock value = %q, want second holder's token %q", got, lk2.token)
+	}
+	if err := lk2.Release(ctx); err != nil {
+		t.Fatalf("release by current holder: %v", err)
+	}
+	if mr.Exists(testKey) {
+		t.Fatal("lock still held after release")
+	}
+
+	if s := first.Stats(); s.Acquired != 1 || s.Lost != 1 {
+		t.Fatalf("first locker stats = %+v, want 1 acquired and 1 lost", s)
+	}
+	if s := second.Stats(); s.Acquired != 1 || s.Lost != 0 {
+		t.Fatalf("second locker stats = %+v, want 1 acquired and none lost", s)
+	}
+}
+
+// TestLostOnRenew 续期时发现锁已被占用，关闭 Lost 并计数
+func TestLostOnRenew(t *testing.T) {
+	mr, rdb := newTestRedis(t)
+	locker := NewLocker(rdb)
+	locker.TTL = 150 * time.Millisecond
+
+	lk, err := locker.Acquire(context.Background(), testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mr.Set(testKey, "other-holder"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-lk.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("lock loss not detected by renewal")
+	}
+	if err := lk.Release(context.Background()); !errors.Is(err, ErrLost) {
+		t.Fatalf("release = %v, want ErrLost", err)
+	}
+	if got, _ := mr.Get(testKey); got != "other-holder" {
+		t.Fatalf("lock value = %q, other holder's lock was deleted", got)
+	}
+	if s := locker.Stats(); s.Lost != 1 {
+		t.Fatalf("lost = %d, want 1", s.Lost)
+	}
+}
+
+// TestWaitTimeout 等待超时返回 ErrTimeout，计入竞争与超时次数
+func TestWaitTimeout(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	holder := NewLocker(rdb)
+	lk, err := holder.Acquire(context.Background(), testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lk.Release(context.Background())
+
+	waiter := NewLocker(rdb)
+	waiter.WaitTimeout = 50 * time.Millisecond
+	if _, err := waiter.Acquire(context.Background(), testKey); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("acquire = %v, want ErrTimeout", err)
+	}
+
+	// ctx 取消同样计入超时，但返回 ctx 的错误
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := waiter.Acquire(ctx, testKey); !errors.Is(err, context.Canceled) {
+		t.Fatalf("acquire with cancelled ctx = %v, want context.Canceled", err)
+	}
+
+	if s := waiter.Stats(); s.Acquired != 0 || s.Contended != 2 || s.Timeouts != 2 {
+		t.Fatalf("waiter stats = %+v, want 0 acquired, 2 contended, 2 timeouts", s)
+	}
+}
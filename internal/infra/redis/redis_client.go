@@ -9,15 +9,9 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
-var (
-	Rdb *redis.Client
-	Ctx = context.Background()
-)
-
-// Init 初始化 Redis 客户端
-func InitRedis() {
-	cfg := config.Get().RedisConfig()
-	Rdb = redis.NewClient(&redis.Options{
+// NewClient 创建 Redis 客户端并检查连接
+func NewClient(ctx context.Context, cfg config.RedisConfig) (*redis.Client, error) {
+	rdb := redis.NewClient(&redis.Options{
 		Addr:         cfg.Addr,
 		Password:     cfg.Password,
 		DB:           cfg.DB,
@@ -29,9 +23,10 @@ func InitRedis() {
 		WriteTimeout: 3 * time.Second,
 	})
 
-	if err := Rdb.Ping(Ctx).Err(); err != nil {
-		log.Fatalf("Redis 连接失败: %v", err)
-	} else {
-		log.Println("Redis 连接成功")
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		_ = rdb.Close()
+		return nil, err
 	}
+	log.Println("Redis 连接成功")
+	return rdb, nil
 }
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"go-web3/internal/constants"
+	"go-web3/internal/infra/eth"
+	"go-web3/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const chainKey = "chain"
+
+/*
+Chain 链选择中间件
+--------------------------------------
+请求通过 query 参数 chain 或请求头 X-Chain 指定链（chain ID 或链名，如 11155111、sepolia），
+未指定时使用默认链。链未接入时直接返回参数错误
+*/
+func Chain(chains *eth.Chains) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Query("chain")
+		if name == "" {
+			name = c.Request.Header.Get("X-Chain")
+		}
+
+		chain, err := chains.Resolve(name)
+		if err != nil {
+			utils.FailMsg(c, constants.ParamError, err.Error())
+			c.Abort()
+			return
+		}
+
+		c.Set(chainKey, chain)
+		c.Next()
+	}
+}
+
+// CurrentChain 获取 Chain 中间件解析出的链
+func CurrentChain(c *gin.Context) *eth.Chain {
+	return c.MustGet(chainKey).(*eth.Chain)
+}
@@ -5,12 +5,12 @@ import (
 	"context"
 	"encoding/json"
 	"go-web3/internal/constants"
-	"go-web3/internal/infra/redis"
 	"go-web3/internal/utils"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 )
 
 /*
@@ -56,7 +56,7 @@ Idempotency 幂等中间件
  2. 第一次请求时执行 handler，并将完整响应缓存到 Redis
  3. 重复请求（相同的幂等 key）直接返回缓存的响应
 */
-func Idempotency() gin.HandlerFunc {
+func Idempotency(rdb *redis.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := context.Background()
 		key := c.Request.Header.Get("X-Idempotency-Key")
@@ -69,7 +69,7 @@ func Idempotency() gin.HandlerFunc {
 
 		redisKey := "idem:" + key
 		// 重复请求，已经有执行结果，则返回上次请求的结果
-		if raw, err := redis.Rdb.Get(ctx, redisKey).Bytes(); err == nil {
+		if raw, err := rdb.Get(ctx, redisKey).Bytes(); err == nil {
 			var cached struct {
 				Status int               `json:"status"` // HTTP 状态码
 				Header map[string]string `json:"header"` // Header 信息
@@ -121,7 +121,7 @@ func Idempotency() gin.HandlerFunc {
 		// 序列化缓存
 		buf, _ := json.Marshal(resp)
 
-		redis.Rdb.Set(ctx, redisKey, buf, 10*time.Minute)
+		rdb.Set(ctx, redisKey, buf, 10*time.Minute)
 
 	}
 }
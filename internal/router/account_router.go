@@ -8,15 +8,15 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func registerAccountRoutes(router *gin.RouterGroup) {
+func registerAccountRoutes(router *gin.RouterGroup, d Deps) {
 	// 获取账户地址余额信息
 	router.GET("/balance/:address", handlers.GetBalance)
 
 	// 转账
-	router.POST("/trans", middleware.Idempotency(), handlers.Trans)
+	router.POST("/trans", middleware.Idempotency(d.Redis), d.Handlers.Trans)
 
 	// 查询交易收据
-	router.GET("/trans/receipt/:txHash", handlers.GetTxReceipt)
+	router.GET("/trans/receipt/:txHash", d.Handlers.GetTxReceipt)
 
 	//查询指定区块号的区块信息
 	router.GET("/block/:number", eth_block.GetBlockInfo)
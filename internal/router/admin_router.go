@@ -0,0 +1,12 @@
+package router
+
+import (
+	"go-web3/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func registerAdminRoutes(router *gin.RouterGroup, h *handlers.Handlers) {
+	// 发送账户池状态
+	router.GET("/senders", h.ListSenders)
+}
@@ -6,9 +6,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func registerContractRoutes(router *gin.RouterGroup) {
+func registerContractRoutes(router *gin.RouterGroup, h *handlers.Handlers) {
 	// 结算拍卖
-	router.GET("/settle/:auctionId", handlers.SettleAuction)
+	router.GET("/settle/:auctionId", h.SettleAuction)
 	// 取消拍卖
-	router.GET("/cancel/:auctionId", handlers.CancelAuction)
+	router.GET("/cancel/:auctionId", h.CancelAuction)
 }
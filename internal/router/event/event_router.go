@@ -3,45 +3,103 @@ package router_event
 import (
 	"context"
 	"go-web3/contracts/constants"
-	"go-web3/contracts/nftauction"
 	"go-web3/internal/handlers/eth-block"
 	"go-web3/internal/infra/eth"
 	"go-web3/internal/infra/eth/event"
-	"go-web3/internal/infra/redis"
+	"go-web3/internal/infra/eth/registry"
+	"go-web3/internal/services/deadletter"
+	"go-web3/internal/services/eventadmin"
 	"log"
 	"os"
-	"strings"
 
-	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/redis/go-redis/v9"
 )
 
-func SetupRouter() *event.Router {
-	logger := log.New(os.Stdout, "[eth-event-listener] ", log.LstdFlags)
-	eventRouter := event.NewRouter(eth.EthWssClient, logger)
-	parsedABI, _ := abi.JSON(strings.NewReader(nftauction.NftauctionMetaData.ABI))
-	event.RegisterABI("NftAuctionV1", parsedABI, constants.ADDRESS_NFT_AUCTION)
-	eventRouter.Use(event.Recover(), event.Logger())
-	eventRouter.Event("NftAuctionV1", "AuctionCreated").
-		Use(eth_block.ListenerAuctionCreated)
+// Deps 事件管道依赖，由 App 注入
+type Deps struct {
+	Redis             *redis.Client
+	Contracts         *registry.Registry // 合约清单
+	Events            *event.Registry    // 事件 ABI 与路由表，各链共享
+	DeadLetters       event.DeadLetterStore
+	DeadLetterService *deadletter.Service
+	EventAdminService *eventadmin.Service
+}
+
+func SetupRouter(d Deps, chain *eth.Chain) *event.Router {
+	logger := log.New(os.Stdout, "[eth-event-listener:"+chain.Name+"] ", log.LstdFlags)
+	eventRouter := event.NewRouter(chain.Name, chain.WssClient, d.Events, logger)
+	// 同一拍卖（合约 + auctionId）的事件按顺序处理
+	eventRouter.Workers = 8
+	eventRouter.QueueSize = 256
+	eventRouter.Partition = event.PartitionByContractTopic
+	// Recover 放在 Retry 内层，panic 也会被重试并最终进入死信队列
+	eventRouter.Use(event.Logger(), event.Retry(event.DefaultRetryPolicy(), d.DeadLetters), event.Recover())
+	d.DeadLetterService.AddRouter(eventRouter)
+	// 只在部署了拍卖合约的链上监听
+	if _, err := d.Contracts.Get(chain.Name, constants.CONTRACT_NFT_AUCTION); err == nil {
+		eventRouter.Event(constants.CONTRACT_NFT_AUCTION, "AuctionCreated").
+			Use(eth_block.ListenerAuctionCreated)
+	}
 	return eventRouter
 }
 
-func SetupScanner() *event.Scanner {
-	logger := log.New(os.Stdout, "[eth-event-scan] ", log.LstdFlags)
-	blockStore := event.NewRedisBlockStore(redis.Rdb, 9787489)
-	store := event.NewRedisDedupeStore(context.Background(), redis.Rdb)
+func SetupScanner(d Deps, chain *eth.Chain) *event.Scanner {
+	logger := log.New(os.Stdout, "[eth-event-scan:"+chain.Name+"] ", log.LstdFlags)
+	blockStore := event.NewRedisBlockStore(d.Redis, chain.StartBlock)
+	store := event.NewRedisDedupeStore(context.Background(), d.Redis)
 	scanner := &event.Scanner{
-		Client:        eth.EthClient,
+		Client:        chain.Client,
 		BlockStore:    blockStore,
 		DedupeStore:   store,
-		Chain:         "sepolia",
-		Contracts:     []string{"NftAuctionV1"},
-		ReorgDepth:    6,
-		Confirmations: 6,
+		HashStore:     blockStore,
+		Registry:      d.Events,
+		Chain:         chain.Name,
+		ReorgDepth:    chain.ReorgDepth,
+		Confirmations: chain.Confirmations,
 		Logger:        logger,
 	}
-	parsedABI, _ := abi.JSON(strings.NewReader(nftauction.NftauctionMetaData.ABI))
-	event.RegisterABI("NftAuctionV1", parsedABI, constants.ADDRESS_NFT_AUCTION)
+	// 清单中的合约全部扫描，首次扫描从部署区块开始
+	for _, c := range d.Contracts.All(chain.Name) {
+		if err := scanner.AddContract(context.Background(), c.Name, c.DeployBlock); err != nil {
+			logger.Fatalf("add contract %s to scanner: %v", c.Name, err)
+		}
+	}
 	logger.Println("Starting block scanner...")
 	return scanner
 }
+
+// RegisterContracts 把各链的合约清单与子合约模板注册到事件 ABI 注册表，由 App 在创建事件管道前调用一次
+func RegisterContracts(contracts *registry.Registry, events *event.Registry, chains *eth.Chains) {
+	for _, chain := range chains.All() {
+		for _, c := range contracts.All(chain.Name) {
+			events.RegisterContract(&event.ABIInfo{
+				Chain:        chain.Name,
+				ContractName: c.Name,
+				ABI:          c.ABI,
+				Address:      c.Address,
+				StartBlock:   c.DeployBlock,
+			})
+		}
+	}
+	for _, t := range contracts.Templates() {
+		events.RegisterTemplate(t.Name, t.ABI)
+	}
+}
+
+// SetupPipeline 实时订阅 + 区块补扫，共享去重与 checkpoint；合约与路由可在运行时通过 /event 接口增删
+func SetupPipeline(d Deps, chain *eth.Chain) *event.Pipeline {
+	pipeline := event.NewPipeline(SetupRouter(d, chain), SetupScanner(d, chain))
+	manager := event.NewManager(pipeline.Router, pipeline.Scanner)
+	manager.RegisterHandler("auctionCreated", event.EventHandlerFunc(eth_block.ListenerAuctionCreated))
+	d.EventAdminService.AddManager(manager)
+	return pipeline
+}
+
+// SetupPipelines 为每条接入的链创建事件管道
+func SetupPipelines(d Deps, chains *eth.Chains) []*event.Pipeline {
+	var pipelines []*event.Pipeline
+	for _, chain := range chains.All() {
+		pipelines = append(pipelines, SetupPipeline(d, chain))
+	}
+	return pipelines
+}
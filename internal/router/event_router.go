@@ -0,0 +1,31 @@
+package router
+
+import (
+	"go-web3/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func registerEventRoutes(router *gin.RouterGroup, h *handlers.Handlers) {
+	// 死信事件列表
+	router.GET("/deadletter", h.ListDeadLetters)
+	// 死信事件详情
+	router.GET("/deadletter/:id", h.GetDeadLetter)
+	// 重放死信事件
+	router.POST("/deadletter/:id/replay", h.ReplayDeadLetter)
+	// 丢弃死信事件
+	router.DELETE("/deadletter/:id", h.DiscardDeadLetter)
+
+	// 监听合约列表
+	router.GET("/contracts", h.ListContracts)
+	// 运行时注册监听合约
+	router.POST("/contracts", h.AddContract)
+	// 移除监听合约
+	router.DELETE("/contracts/:name", h.RemoveContract)
+	// 事件路由列表
+	router.GET("/routes", h.ListEventRoutes)
+	// 运行时注册事件路由
+	router.POST("/routes", h.AddEventRoute)
+	// 移除事件路由
+	router.DELETE("/routes/:id", h.RemoveEventRoute)
+}
@@ -1,9 +1,11 @@
 package router
 
 import (
+	"go-web3/internal/config"
 	"go-web3/internal/constants"
+	"go-web3/internal/handlers"
 	"go-web3/internal/infra/eth"
-	"go-web3/internal/infra/redis"
+	ethtrans "go-web3/internal/infra/eth/trans"
 	"go-web3/internal/middleware"
 	"go-web3/internal/utils"
 	"log"
@@ -11,9 +13,19 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 )
 
-func SetupRouter() *gin.Engine {
+// Deps 路由依赖，由 App 注入
+type Deps struct {
+	Config   config.Config
+	Redis    *redis.Client
+	Senders  *ethtrans.SenderPool
+	Chains   *eth.Chains
+	Handlers *handlers.Handlers
+}
+
+func SetupRouter(d Deps) *gin.Engine {
 	r := gin.New()
 	r.Use(gin.Logger())
 	r.Use(func(c *gin.Context) {
@@ -32,29 +44,77 @@ func SetupRouter() *gin.Engine {
 	})
 
 	r.GET("/health/redis", func(c *gin.Context) {
-		err := redis.Rdb.Set(redis.Ctx, "health", "health", 10*time.Minute).Err()
+		ctx := c.Request.Context()
+		err := d.Redis.Set(ctx, "health", "health", 10*time.Minute).Err()
 		if err != nil {
 			panic(err)
 		}
-		result, err := redis.Rdb.Get(redis.Ctx, "health").Result()
+		result, err := d.Redis.Get(ctx, "health").Result()
 		if err != nil {
 			return
 		}
 		utils.OkData(c, result)
 	})
 
+	// 各链 RPC 节点健康状态
+	r.GET("/health/rpc", func(c *gin.Context) {
+		result := gin.H{}
+		for _, chain := range d.Chains.All() {
+			result[chain.Name] = gin.H{
+				"chainId":   chain.ID,
+				"endpoints": chain.EndpointStats(),
+			}
+		}
+		utils.OkData(c, result)
+	})
+
+	// 各链 nonce 分配锁的竞争统计
+	r.GET("/health/nonce", func(c *gin.Context) {
+		result := gin.H{}
+		for _, chain := range d.Chains.All() {
+			if chain.NonceMgr == nil {
+				continue
+			}
+			result[chain.Name] = gin.H{
+				"chainId": chain.ID,
+				"lock":    chain.NonceMgr.LockStats(),
+			}
+		}
+		utils.OkData(c, result)
+	})
+
+	// 发送账户，不返回任何密钥信息
 	r.GET("/health/eth", func(c *gin.Context) {
-		key, _ := eth.GetPrivateKeyTemp()
-		utils.OkData(c, key)
+		utils.OkData(c, gin.H{
+			"addresses": d.Senders.Addresses(),
+			"signer":    d.Config.EthConfig().Signer.Type,
+		})
 	})
 
+	// 以下模块都通过 chain 参数选择链，未指定时使用默认链
 	// 账户模块
-	accountGroup := r.Group("/account")
-	registerAccountRoutes(accountGroup)
+	accountGroup := r.Group("/account", middleware.Chain(d.Chains))
+	registerAccountRoutes(accountGroup, d)
 
 	// 合约交互
-	contractGroup := r.Group("/contract/nft/auction", middleware.Idempotency())
-	registerContractRoutes(contractGroup)
+	contractGroup := r.Group("/contract/nft/auction", middleware.Chain(d.Chains), middleware.Idempotency(d.Redis))
+	registerContractRoutes(contractGroup, d.Handlers)
+
+	// gas 费
+	gasGroup := r.Group("/gas", middleware.Chain(d.Chains))
+	gasGroup.GET("/fees", d.Handlers.GasFees)
+
+	// 交易状态
+	txGroup := r.Group("/tx")
+	registerTxRoutes(txGroup, d)
+
+	// 链上事件管理
+	eventGroup := r.Group("/event", middleware.Chain(d.Chains))
+	registerEventRoutes(eventGroup, d.Handlers)
+
+	// 运维管理
+	adminGroup := r.Group("/admin", middleware.Chain(d.Chains))
+	registerAdminRoutes(adminGroup, d.Handlers)
 
 	return r
 }
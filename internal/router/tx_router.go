@@ -0,0 +1,21 @@
+package router
+
+import (
+	"go-web3/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func registerTxRoutes(router *gin.RouterGroup, d Deps) {
+	h := d.Handlers
+
+	// 查询交易状态（请求 ID 或交易 hash）
+	router.GET("/:id", h.GetTx)
+
+	// 替换签名账户在指定 nonce 上未打包的交易，通过 chain 参数选择链
+	nonceGroup := router.Group("/nonce/:nonce", middleware.Chain(d.Chains), middleware.Idempotency(d.Redis))
+	// 加速：提高 gas 费重发
+	nonceGroup.POST("/speedup", h.SpeedUpTx)
+	// 取消：0 ETH 转给自己
+	nonceGroup.POST("/cancel", h.CancelTx)
+}
@@ -3,7 +3,6 @@ package account
 import (
 	"context"
 	"errors"
-	"go-web3/internal/config"
 	"go-web3/internal/infra/eth"
 	"math"
 	"math/big"
@@ -18,7 +17,7 @@ type Balance struct {
 	NetworkName string `json:"network"`
 }
 
-func GetEthBalance(address string) (*Balance, error) {
+func GetEthBalance(chain *eth.Chain, address string) (*Balance, error) {
 	// 校验地址格式
 	if !common.IsHexAddress(address) {
 		return nil, errors.New("invalid wallet address")
@@ -26,7 +25,7 @@ func GetEthBalance(address string) (*Balance, error) {
 
 	// 查询余额（单位：Wei）
 	account := common.HexToAddress(address)
-	balanceWei, err := eth.EthClient.BalanceAt(context.Background(), account, nil)
+	balanceWei, err := chain.Client.BalanceAt(context.Background(), account, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -40,6 +39,6 @@ func GetEthBalance(address string) (*Balance, error) {
 		Address:     address,
 		BalanceWei:  balanceWei.String(),
 		BalanceETH:  ethStr,
-		NetworkName: config.Get().EthConfig().NetworkName,
+		NetworkName: chain.Name,
 	}, nil
 }
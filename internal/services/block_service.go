@@ -17,10 +17,10 @@ type BlockInfo struct {
 	GasUsed      uint64 `json:"gasUsed"`
 }
 
-func GetBlockInfo(blockNumber uint64) (*BlockInfo, error) {
+func GetBlockInfo(chain *eth.Chain, blockNumber uint64) (*BlockInfo, error) {
 	ctx := context.Background()
 	num := new(big.Int).SetUint64(blockNumber)
-	block, err := eth.EthClient.BlockByNumber(ctx, num)
+	block, err := chain.Client.BlockByNumber(ctx, num)
 
 	if err != nil {
 		return nil, errors.New("failed to get block: " + err.Error())
@@ -0,0 +1,91 @@
+package deadletter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-web3/internal/infra/eth/event"
+	"sync"
+)
+
+// Service 死信查询、重放与丢弃。死信存储各链共享
+type Service struct {
+	store    event.DeadLetterStore
+	registry *event.Registry
+
+	mu      sync.RWMutex
+	routers map[string]*event.Router // 每条链用于重放的事件路由，以链名为 key
+}
+
+type Page struct {
+	Total int64               `json:"total"`
+	List  []*event.DeadLetter `json:"list"`
+}
+
+func NewService(store event.DeadLetterStore, registry *event.Registry) *Service {
+	return &Service{
+		store:    store,
+		registry: registry,
+		routers:  map[string]*event.Router{},
+	}
+}
+
+// AddRouter 注入链上用于重放的事件路由，每条链调用一次
+func (s *Service) AddRouter(r *event.Router) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routers[r.Chain] = r
+}
+
+func (s *Service) router(chain string) (*event.Router, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.routers[chain]
+	return r, ok
+}
+
+func (s *Service) List(offset, limit int64) (*Page, error) {
+	list, total, err := s.store.List(context.Background(), offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &Page{Total: total, List: list}, nil
+}
+
+func (s *Service) Get(id string) (*event.DeadLetter, error) {
+	return s.store.Get(context.Background(), id)
+}
+
+// Replay 重放死信事件，成功后从死信队列删除；再次失败时死信记录的尝试次数会累加
+func (s *Service) Replay(id string) error {
+	ctx := context.Background()
+
+	dl, err := s.store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	rt := s.registry.FindRoute(dl.Route)
+	if rt == nil {
+		return fmt.Errorf("no route %s for %s.%s", dl.Route, dl.Contract, dl.Event)
+	}
+	router, ok := s.router(rt.Chain)
+	if !ok {
+		return errors.New("event router not initialized for chain " + rt.Chain)
+	}
+
+	if err := router.Replay(ctx, dl); err != nil {
+		return err
+	}
+	return s.store.Delete(ctx, id)
+}
+
+// Discard 丢弃死信事件
+func (s *Service) Discard(id string) error {
+	ctx := context.Background()
+
+	if _, err := s.store.Get(ctx, id); err != nil {
+		return err
+	}
+	return s.store.Delete(ctx, id)
+}
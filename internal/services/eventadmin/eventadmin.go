@@ -0,0 +1,169 @@
+package eventadmin
+
+import (
+	"context"
+	"errors"
+	"go-web3/internal/infra/eth/event"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Service 运行时合约与路由管理
+type Service struct {
+	registry *event.Registry
+
+	mu       sync.RWMutex
+	managers map[string]*event.Manager // 每条链一个管理器，以链名为 key
+}
+
+// Contract 已注册合约
+type Contract struct {
+	Name       string `json:"name"`
+	Address    string `json:"address"`
+	Template   string `json:"template,omitempty"`
+	StartBlock uint64 `json:"startBlock"`
+}
+
+// Route 已注册路由
+type Route struct {
+	ID       string   `json:"id"`
+	Contract string   `json:"contract,omitempty"`
+	Template string   `json:"template,omitempty"`
+	Events   []string `json:"events,omitempty"`
+}
+
+type AddContractReq struct {
+	Name       string `json:"name" binding:"required"`
+	Address    string `json:"address" binding:"required"`
+	ABI        string `json:"abi"`      // ABI JSON，与 template 二选一
+	Template   string `json:"template"` // 已注册的 ABI 模板
+	StartBlock uint64 `json:"startBlock"`
+}
+
+type AddRouteReq struct {
+	Contract string   `json:"contract"`
+	Template string   `json:"template"`
+	Events   []string `json:"events"`
+	Handler  string   `json:"handler" binding:"required"` // 具名处理器
+}
+
+func NewService(registry *event.Registry) *Service {
+	return &Service{
+		registry: registry,
+		managers: map[string]*event.Manager{},
+	}
+}
+
+// AddManager 注入链的运行时合约与路由管理器
+func (s *Service) AddManager(m *event.Manager) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.managers[m.Chain] = m
+}
+
+func (s *Service) getManager(chain string) (*event.Manager, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m, ok := s.managers[chain]
+	if !ok {
+		return nil, errors.New("event manager not initialized for chain " + chain)
+	}
+	return m, nil
+}
+
+func (s *Service) ListContracts(chain string) []Contract {
+	list := make([]Contract, 0)
+	for _, info := range s.registry.AllABIs(chain) {
+		list = append(list, Contract{
+			Name:       info.ContractName,
+			Address:    info.Address.Hex(),
+			Template:   info.Template,
+			StartBlock: info.StartBlock,
+		})
+	}
+	return list
+}
+
+// AddContract 运行时注册合约，立即开始订阅，并从 startBlock 开始补扫
+func (s *Service) AddContract(chain string, req AddContractReq) error {
+	manager, err := s.getManager(chain)
+	if err != nil {
+		return err
+	}
+	if !common.IsHexAddress(req.Address) {
+		return errors.New("invalid contract address")
+	}
+
+	spec := event.ContractSpec{
+		Name:       req.Name,
+		Address:    common.HexToAddress(req.Address),
+		Template:   req.Template,
+		StartBlock: req.StartBlock,
+	}
+	if req.Template == "" {
+		if req.ABI == "" {
+			return errors.New("abi or template is required")
+		}
+		parsed, err := abi.JSON(strings.NewReader(req.ABI))
+		if err != nil {
+			return err
+		}
+		spec.ABI = &parsed
+	}
+	return manager.AddContract(context.Background(), spec)
+}
+
+// RemoveContract 移除合约及只绑定该合约的路由
+func (s *Service) RemoveContract(chain string, name string) error {
+	manager, err := s.getManager(chain)
+	if err != nil {
+		return err
+	}
+	return manager.RemoveContract(name)
+}
+
+func (s *Service) ListRoutes(chain string) []Route {
+	list := make([]Route, 0)
+	for _, rt := range s.registry.AllRoutes() {
+		if rt.Chain != chain {
+			continue
+		}
+		list = append(list, Route{
+			ID:       rt.ID,
+			Contract: rt.Contract,
+			Template: rt.Template,
+			Events:   rt.Events,
+		})
+	}
+	return list
+}
+
+// AddRoute 运行时注册路由，处理器按名称引用
+func (s *Service) AddRoute(chain string, req AddRouteReq) (string, error) {
+	manager, err := s.getManager(chain)
+	if err != nil {
+		return "", err
+	}
+	rt, err := manager.AddRoute(event.RouteSpec{
+		Contract: req.Contract,
+		Template: req.Template,
+		Events:   req.Events,
+		Handler:  req.Handler,
+	})
+	if err != nil {
+		return "", err
+	}
+	return rt.ID, nil
+}
+
+func (s *Service) RemoveRoute(chain string, id string) error {
+	manager, err := s.getManager(chain)
+	if err != nil {
+		return err
+	}
+	return manager.RemoveRoute(id)
+}
@@ -2,103 +2,100 @@ package services
 
 import (
 	"context"
-	"crypto/ecdsa"
-	"errors"
 	"fmt"
 	"go-web3/contracts/constants"
 	"go-web3/contracts/nftauction"
-	"go-web3/internal/config"
 	"go-web3/internal/infra/eth"
+	"go-web3/internal/infra/eth/registry"
 	"go-web3/internal/infra/eth/trans"
-	"go-web3/internal/infra/redis"
+	"go-web3/internal/infra/eth/txtrack"
+	"log"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 )
 
-// SettleAuction 拍卖结算
-func SettleAuction(auctionId *big.Int) error {
-	ctx := context.Background()
-	// 加载私钥
-	cfg := config.Get().EthConfig()
-	privateKeyHex := cfg.Private
-	privateKey, err := crypto.HexToECDSA(privateKeyHex)
-	if err != nil {
-		return errors.New("invalid private key")
-	}
-
-	// 创建授权对象 auth（用于发送交易）
-	chainID, err := eth.EthClient.ChainID(ctx)
-	if err != nil {
-		return err
-	}
+// AuctionService 拍卖合约交互，合约地址从合约清单读取，发出的交易交给 Tracker 跟踪。
+// 同一拍卖的交易默认固定使用同一发送账户
+type AuctionService struct {
+	contracts *registry.Registry
+	senders   *trans.SenderPool
+	tracker   *txtrack.Tracker
+	gas       trans.GasStrategy
+	limits    *trans.GasLimitPolicy
+}
 
-	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, chainID)
-	if err != nil {
-		return err
+func NewAuctionService(contracts *registry.Registry, senders *trans.SenderPool, tracker *txtrack.Tracker,
+	gas trans.GasStrategy, limits *trans.GasLimitPolicy) *AuctionService {
+	return &AuctionService{
+		contracts: contracts,
+		senders:   senders,
+		tracker:   tracker,
+		gas:       gas,
+		limits:    limits,
 	}
+}
 
-	auth.GasLimit = 0
+// SettleAuction 拍卖结算
+func (s *AuctionService) SettleAuction(chain *eth.Chain, auctionId *big.Int, req txtrack.Request) (*trans.SendResult, error) {
+	return s.send(chain, auctionId, req, func(auction *nftauction.NftauctionTransactor, auth *bind.TransactOpts) (*types.Transaction, error) {
+		return auction.SettleAuction(auth, auctionId)
+	})
+}
 
-	// 设置 nonce
-	pubKey := privateKey.Public().(*ecdsa.PublicKey)
-	fromAddr := crypto.PubkeyToAddress(*pubKey)
-	nonce, err := eth.NonceMgr.GetNextNonce(ctx, fromAddr)
-	if err != nil {
-		return err
-	}
+func (s *AuctionService) CancelAuction(chain *eth.Chain, auctionId *big.Int, req txtrack.Request) (*trans.SendResult, error) {
+	return s.send(chain, auctionId, req, func(auction *nftauction.NftauctionTransactor, auth *bind.TransactOpts) (*types.Transaction, error) {
+		return auction.CancelAuction(auth, auctionId)
+	})
+}
 
-	auth.Nonce = big.NewInt(int64(nonce))
-	auth.Value = big.NewInt(0)
+// send 通过交易器调用拍卖合约：模拟执行、gas limit 预留余量、余额检查后广播
+func (s *AuctionService) send(chain *eth.Chain, auctionId *big.Int, req txtrack.Request,
+	call func(*nftauction.NftauctionTransactor, *bind.TransactOpts) (*types.Transaction, error)) (result *trans.SendResult, err error) {
+	factory := trans.NewEthFactory(chain, s.senders)
+	factory.Gas = s.gas
+	factory.GasLimits = s.limits
 
-	gasPrice, err := eth.EthClient.SuggestGasPrice(ctx)
+	ts, lease, err := factory.AcquireTransactor(context.Background(), auctionPin(auctionId, req))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	auth.GasPrice = gasPrice
+	defer func() { lease.Release(err) }()
+	ts.SetGas(nil, trans.GasOptionsOf(chain, req))
 
 	// 绑定已经部署的合约(代理地址)
-	address := common.HexToAddress(constants.ADDRESS_NFT_AUCTION)
-	instance, err := nftauction.NewNftauctionTransactor(address, eth.EthClient)
+	address, err := s.contracts.Address(chain.Name, constants.CONTRACT_NFT_AUCTION)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	// 调用合约结算函数
-	tx, err := instance.SettleAuction(auth, auctionId)
+	auction, err := nftauction.NewNftauctionTransactor(address, chain.Client)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	fmt.Println("tx sent:", tx.Hash().Hex())
-
-	return nil
-
-}
-
-func CancelAuction(auctionId *big.Int) error {
-	factory := trans.NewEthFactory(eth.EthClient, redis.Rdb)
-
-	ts := factory.NewTransactor(config.Get().EthConfig().Private)
-
-	auction, _ := nftauction.NewNftauctionTransactor(
-		common.HexToAddress(constants.ADDRESS_NFT_AUCTION),
-		eth.EthClient,
-	)
-
 	tx, err := ts.SendTx(func(auth *bind.TransactOpts) (*types.Transaction, error) {
-		return auction.CancelAuction(auth, auctionId)
+		return call(auction, auth)
 	})
-
 	if err != nil {
-		return fmt.Errorf("send tx failed: %w", err)
+		return nil, fmt.Errorf("send tx failed: %w", err)
 	}
 
-	fmt.Println("tx:", tx.Hash().Hex())
+	s.track(context.Background(), chain, tx, req)
+	return trans.NewSendResult(tx), nil
+}
 
-	return err
+// auctionPin 未指定 pin 时按拍卖 ID 固定发送账户
+func auctionPin(auctionId *big.Int, req txtrack.Request) string {
+	if req.Pin != "" {
+		return req.Pin
+	}
+	return "nftauction:" + auctionId.String()
+}
 
+// track 交易已广播，跟踪记录失败不影响返回
+func (s *AuctionService) track(ctx context.Context, chain *eth.Chain, tx *types.Transaction, req txtrack.Request) {
+	if _, err := s.tracker.Track(ctx, chain, tx, req); err != nil {
+		log.Printf("track tx %s failed: %v", tx.Hash().Hex(), err)
+	}
 }
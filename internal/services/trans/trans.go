@@ -2,19 +2,81 @@ package trans
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"errors"
-	"go-web3/internal/config"
+	"fmt"
 	"go-web3/internal/infra/eth"
+	"go-web3/internal/infra/eth/chainclient"
 	"go-web3/internal/infra/eth/nonce"
+	ethtrans "go-web3/internal/infra/eth/trans"
+	"go-web3/internal/infra/eth/txtrack"
 	"go-web3/internal/utils"
+	"log"
 	"math/big"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 )
 
+// Service 交易查询与转账，发送账户从账户池中选择，发出的交易交给 Tracker 跟踪
+type Service struct {
+	senders  *ethtrans.SenderPool
+	tracker  *txtrack.Tracker
+	replacer *ethtrans.Replacer
+	gas      ethtrans.GasStrategy
+	limits   *ethtrans.GasLimitPolicy
+}
+
+func NewService(senders *ethtrans.SenderPool, tracker *txtrack.Tracker, replacer *ethtrans.Replacer,
+	gas ethtrans.GasStrategy, limits *ethtrans.GasLimitPolicy) *Service {
+	return &Service{
+		senders:  senders,
+		tracker:  tracker,
+		replacer: replacer,
+		gas:      gas,
+		limits:   limits,
+	}
+}
+
+// GasFees 链上各档位当前的 gas 费
+func (s *Service) GasFees(chain *eth.Chain) (map[ethtrans.Speed]*ethtrans.Fee, error) {
+	ctx := context.Background()
+	fees := map[ethtrans.Speed]*ethtrans.Fee{}
+	for _, speed := range ethtrans.Speeds {
+		fee, err := s.gas.Suggest(ctx, chain.Client, ethtrans.GasOptions{Speed: speed, FeeCap: chain.MaxFeeCap})
+		if err != nil {
+			return nil, err
+		}
+		fees[speed] = fee
+	}
+	return fees, nil
+}
+
+// GetTx 按请求 ID 或交易 hash 查询交易状态
+func (s *Service) GetTx(id string) (*txtrack.Tx, error) {
+	return s.tracker.Get(context.Background(), id)
+}
+
+// SpeedUp 提高 gas 费重发发送账户在该 nonce 上未打包的交易
+func (s *Service) SpeedUp(chain *eth.Chain, from common.Address, nonce uint64, req txtrack.Request) (*txtrack.Tx, error) {
+	return s.replacer.SpeedUp(context.Background(), chain, from, nonce, req)
+}
+
+// Cancel 用 0 ETH 转给自己的交易取消发送账户在该 nonce 上未打包的交易
+func (s *Service) Cancel(chain *eth.Chain, from common.Address, nonce uint64, req txtrack.Request) (*txtrack.Tx, error) {
+	return s.replacer.Cancel(context.Background(), chain, from, nonce, req)
+}
+
+// PrimarySender 默认发送账户，替换交易未指定账户时使用
+func (s *Service) PrimarySender() common.Address {
+	return s.senders.Primary().Address()
+}
+
+// SenderStates 账户池中各发送账户在链上的状态
+func (s *Service) SenderStates(chain *eth.Chain) ([]ethtrans.SenderState, error) {
+	return s.senders.States(context.Background(), chain)
+}
+
 type TxReceiptResp struct {
 	TxHash          string       `json:"txHash"`
 	BlockHash       string       `json:"blockHash"`
@@ -27,17 +89,17 @@ type TxReceiptResp struct {
 	Logs            []*types.Log `json:"logs"`            // 合约 emit 的所有事件
 }
 
-func GetTxReceipt(txHash string) (any, error) {
+func (s *Service) GetTxReceipt(chain *eth.Chain, txHash string) (any, error) {
 	ctx := context.Background()
 	hash := common.HexToHash(txHash)
 
-	receipt, err := eth.EthClient.TransactionReceipt(ctx, hash)
+	receipt, err := chain.Client.TransactionReceipt(ctx, hash)
 	if err != nil {
 		return nil, err
 	}
 
 	// 获取 from / to
-	tx, _, err := eth.EthClient.TransactionByHash(ctx, hash)
+	tx, _, err := chain.Client.TransactionByHash(ctx, hash)
 	if err != nil {
 		return nil, err
 	}
@@ -71,84 +133,90 @@ func GetTxReceipt(txHash string) (any, error) {
 
 }
 
-func Trans(to string, amountEth string) (string, error) {
+// Trans 转账，返回交易 hash、gas limit 与最坏情况下的费用。余额不足时返回 *ethtrans.InsufficientFundsError
+func (s *Service) Trans(chain *eth.Chain, to string, amountEth string, req txtrack.Request) (result *ethtrans.SendResult, err error) {
 	ctx := context.Background()
-	// 加载私钥
-	cfg := config.Get().EthConfig()
-	privateKeyHex := cfg.Private
-	privateKey, err := crypto.HexToECDSA(privateKeyHex)
-	if err != nil {
-		return "", errors.New("invalid private key")
-	}
-	publicKey := privateKey.Public().(*ecdsa.PublicKey) // 转换成具体的公钥结构体对象
-	from := crypto.PubkeyToAddress(*publicKey)
-
-	// nonce
-	_nonce, err := eth.NonceMgr.GetNextNonce(ctx, from)
-	if err != nil {
-		return "", err
-	}
 
 	// 金额转换 ETH → Wei（避免 big.Float）
 	amountWei, ok := new(big.Int).SetString(utils.ParseEthToWei(amountEth), 10)
 	if !ok {
-		return "", errors.New("invalid amount")
+		return nil, errors.New("invalid amount")
 	}
 
-	// EIP-1559 推荐 gas 参数
-	tipCap, err := eth.EthClient.SuggestGasTipCap(ctx) // maxPriorityFeePerGas
+	// 选择发送账户，发送结果计入账户健康状态
+	lease, err := s.senders.Acquire(ctx, chain, req.Pin)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	defer func() { lease.Release(err) }()
+	from := lease.Signer.Address()
 
-	// 获取最新区块 → baseFee
-	header, err := eth.EthClient.HeaderByNumber(ctx, nil)
+	// gas 费：按请求的档位与覆盖值计算，不超过链的上限；不支持 EIP-1559 的链使用 gasPrice
+	fee, err := s.gas.Suggest(ctx, chain.Client, ethtrans.GasOptionsOf(chain, req))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	baseFee := header.BaseFee
 
-	// maxFeePerGas = baseFee + tipCap
-	maxFeePerGas := new(big.Int).Add(baseFee, tipCap)
+	// GasLimit：估算值按 transfer 规则预留余量，转给合约时可能超过 21000
+	toAddress := common.HexToAddress(to)
+	estimated, err := chain.Client.EstimateGas(ctx, ethereum.CallMsg{From: from, To: &toAddress, Value: amountWei})
+	if err != nil {
+		return nil, err
+	}
+	gasLimit := s.limits.Limit(nil, estimated)
 
-	// GasLimit：普通 ETH 转账 21000
-	gasLimit := uint64(21000)
+	// 余额需要足以支付最坏情况下的费用
+	if err := ethtrans.Preflight(ctx, chain.Client, from, ethtrans.MaxCost(gasLimit, fee.MaxPerGas(), amountWei)); err != nil {
+		return nil, err
+	}
 
 	// 构造交易信息
-	toAddress := common.HexToAddress(to)
-	chainID, err := eth.EthClient.ChainID(ctx)
+	chainID, err := chain.Client.ChainID(ctx)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	txData := &types.DynamicFeeTx{
-		ChainID:   chainID,
-		Nonce:     _nonce,
-		GasTipCap: tipCap,
-		GasFeeCap: maxFeePerGas,
-		Gas:       gasLimit,
-		To:        &toAddress,
-		Value:     amountWei,
-		Data:      nil,
+
+	// 预留 nonce，广播失败时归还
+	res, err := chain.NonceMgr.Reserve(ctx, from)
+	if err != nil {
+		return nil, err
 	}
+	defer res.Release(ctx)
 
-	tx := types.NewTx(txData)
+	tx := fee.NewTx(chainID, res.Nonce, &toAddress, amountWei, gasLimit, nil)
 
 	// 自动匹配最新标准签名规则
-	signTx, err := types.SignTx(tx, types.LatestSignerForChainID(chainID), privateKey)
+	signTx, err := lease.Signer.SignTx(ctx, tx, chainID)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// 广播交易
-	err = eth.EthClient.SendTransaction(ctx, signTx)
+	err = chain.Client.SendTransaction(ctx, signTx)
 	if err != nil {
+		// 广播结果未知时交易可能已经上链，nonce 保留给该交易
+		if chainclient.IsSendUnknown(err) {
+			if err := res.Commit(ctx, signTx.Hash()); err != nil {
+				log.Printf("commit nonce %d of tx %s failed: %v", res.Nonce, signTx.Hash().Hex(), err)
+			}
+			return nil, fmt.Errorf("%w: tx %s: %v", ethtrans.ErrSendUnknown, signTx.Hash().Hex(), err)
+		}
 		// 判断 nonce 是否与链上数据不一致。不一致强制同步链上 nonce
 		if nonce.IsNonceError(err) {
-			_ = eth.NonceMgr.ForceSyncNonce(ctx, from)
+			_ = res.Release(ctx)
+			_ = chain.NonceMgr.ForceSyncNonce(ctx, from)
 		}
-		return "", err
+		return nil, err
+	}
+	if err := res.Commit(ctx, signTx.Hash()); err != nil {
+		log.Printf("commit nonce %d of tx %s failed: %v", res.Nonce, signTx.Hash().Hex(), err)
+	}
+
+	// 交易已广播，跟踪记录失败不影响返回
+	if _, err := s.tracker.Track(ctx, chain, signTx, req); err != nil {
+		log.Printf("track tx %s failed: %v", signTx.Hash().Hex(), err)
 	}
 
-	return signTx.Hash().Hex(), nil
+	return ethtrans.NewSendResult(signTx), nil
 
 }
@@ -47,3 +47,12 @@ func FailMsg(c *gin.Context, errCode string, msg string) {
 		Timestamp: time.Now().UnixMilli(),
 	})
 }
+
+func FailData(c *gin.Context, errCode string, msg string, data any) {
+	c.JSON(http.StatusBadRequest, Response{
+		Code:      errCode,
+		Msg:       msg,
+		Data:      data,
+		Timestamp: time.Now().UnixMilli(),
+	})
+}